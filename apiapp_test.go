@@ -0,0 +1,96 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+	"testing"
+)
+
+// createFixtureApi creates the api application appname from the SQLite fixture in a temporary
+// GOPATH and returns its path.
+func createFixtureApi(t *testing.T, appname string, args ...string) string {
+	gopath := t.TempDir()
+	dbFile := path.Join(gopath, "shop.db")
+	createSqliteFixture(t, dbFile).Close()
+	wd, _ := os.Getwd()
+	src := path.Join(gopath, "src")
+	os.MkdirAll(src, 0755)
+	if err := os.Chdir(src); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	defer os.Setenv("GOPATH", os.Getenv("GOPATH"))
+	os.Setenv("GOPATH", gopath)
+	defer func() { driver, conn, authMode = "", "", "" }()
+
+	createapi(cmdApiapp, append([]string{appname, "-driver=sqlite", "-conn=" + dbFile}, args...))
+	return path.Join(src, appname)
+}
+
+func TestCreateApiDriver(t *testing.T) {
+	app := createFixtureApi(t, "shop")
+	main, err := ioutil.ReadFile(path.Join(app, "main.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{`_ "github.com/mattn/go-sqlite3"`, `orm.RegisterDataBase("default", "sqlite3", beego.AppConfig.String("sqlconn"))`} {
+		if !strings.Contains(string(main), expected) {
+			t.Errorf("main.go is missing %s:\n%s", expected, main)
+		}
+	}
+	conf, err := ioutil.ReadFile(path.Join(app, "conf", "app.conf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(conf), `sqlconn = "`+path.Join(path.Dir(path.Dir(app)), "shop.db")+`"`) {
+		t.Errorf("app.conf doesn't hold the connection string:\n%s", conf)
+	}
+}
+
+func TestCreateApiGofmt(t *testing.T) {
+	for _, auth := range []string{"", "jwt", "apikey"} {
+		app := createFixtureApi(t, "shop", "-auth="+auth)
+		out, err := exec.Command("gofmt", "-l", app).CombinedOutput()
+		if err != nil {
+			t.Fatalf("gofmt: %s\n%s", err, out)
+		}
+		if len(out) > 0 {
+			t.Errorf("auth %q: files are not gofmt-clean:\n%s", auth, out)
+		}
+	}
+}
+
+func TestCreateApiPasswordEnv(t *testing.T) {
+	dir := t.TempDir()
+	for _, driver := range []string{"mysql", "postgres"} {
+		conf := renderTemplate("api/app.conf.tpl", map[string]interface{}{"Appname": "shop", "Auth": "", "Secret": "", "Conn": "app@tcp(db.local:3306)/shop", "PasswordEnv": "SHOP_DB_PASSWORD"})
+		if !strings.Contains(conf, `sqlpasswordenv = "SHOP_DB_PASSWORD"`) {
+			t.Errorf("app.conf doesn't name the password variable:\n%s", conf)
+		}
+		main := renderTemplate("api/main.go.tpl", map[string]interface{}{"Appname": "shop", "Auth": "", "AuthHeader": "", "DriverName": driver, "DriverPkg": driverPkg(driver), "PasswordEnv": "SHOP_DB_PASSWORD"})
+		if !strings.Contains(main, `os.Getenv(beego.AppConfig.String("sqlpasswordenv"))`) {
+			t.Errorf("%s: main.go doesn't read the password:\n%s", driver, main)
+		}
+		file := path.Join(dir, driver+".go")
+		ioutil.WriteFile(file, []byte(main), 0644)
+		if out, _ := exec.Command("gofmt", "-l", file).CombinedOutput(); len(out) > 0 {
+			t.Errorf("%s: main.go is not gofmt-clean:\n%s", driver, main)
+		}
+	}
+}
+
+func TestCreateApiErrorProblem(t *testing.T) {
+	problem := renderTemplate("api/problem.go.tpl", map[string]interface{}{"Appname": "shop"})
+	// the errors of the database stay in the log
+	for _, expected := range []string{`beego.Error("request failed:", msg)`, `NewProblem(http.StatusInternalServerError, "internal server error")`, `NewProblem(http.StatusConflict, constraint.detail)`} {
+		if !strings.Contains(problem, expected) {
+			t.Errorf("problem.go is missing %s:\n%s", expected, problem)
+		}
+	}
+	if strings.Contains(problem, "ClearErrorPrefix") {
+		t.Errorf("problem.go returns the database errors:\n%s", problem)
+	}
+}
@@ -0,0 +1,313 @@
+// Copyright 2013 bee authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	path "path/filepath"
+	"strings"
+)
+
+// authSchemes are the authentication schemes of -auth, with the header carrying the credentials
+var authSchemes = map[string]string{
+	"jwt":    "Authorization",
+	"apikey": "X-API-Key",
+}
+
+// authTables are the tables of the users and tokens created by bee api -auth, they have their own
+// models and are left out of appcode
+var authTables = map[string]bool{
+	"auth_users":  true,
+	"auth_tokens": true,
+}
+
+// withoutAuthTables removes the users and tokens tables from a list of table names
+func withoutAuthTables(names []string) (tables []string) {
+	for _, name := range names {
+		if !authTables[name] {
+			tables = append(tables, name)
+		}
+	}
+	return
+}
+
+// ownerColumnNames are the foreign keys holding the owner of a row when they reference a table of
+// users other than auth_users
+var ownerColumnNames = map[string]bool{
+	"owner_id":   true,
+	"user_id":    true,
+	"created_by": true,
+}
+
+// isUsersTable reports whether a table holds users by its name: user, users, or ending in _user or _users
+func isUsersTable(name string) bool {
+	name = strings.ToLower(name)
+	return name == "user" || name == "users" || strings.HasSuffix(name, "_user") || strings.HasSuffix(name, "_users")
+}
+
+// useAuthUsers relates the foreign keys referencing auth_users to the AuthUser model of bee api -auth,
+// and keeps the key type of the owner columns referencing another table of users
+func useAuthUsers(tables []*Table) {
+	for _, tb := range tables {
+		for _, col := range tb.Columns {
+			if !col.Tag.RelFk {
+				continue
+			}
+			fk := tb.Fk[col.Tag.Column]
+			if fk.RefTable == "auth_users" {
+				col.Type = "*AuthUser"
+			} else if ownerColumnNames[col.Tag.Column] && isUsersTable(fk.RefTable) {
+				col.RefType = refColumnType(tables, fk)
+			}
+		}
+	}
+}
+
+// ownerColumn returns the foreign key of the user owning the rows of the table, nil if it has none:
+// a foreign key to auth_users, or else an owner_id, user_id or created_by foreign key to a table of
+// users with an integer key
+func (tb *Table) ownerColumn() *Column {
+	for _, col := range tb.Columns {
+		if col.Tag.RelFk && col.Type == "*AuthUser" {
+			return col
+		}
+	}
+	if authMode == "" {
+		return nil
+	}
+	for _, col := range tb.Columns {
+		if col.Tag.RelFk && ownerColumnNames[col.Tag.Column] && isUsersTable(tb.Fk[col.Tag.Column].RefTable) &&
+			(strings.HasPrefix(col.RefType, "int") || strings.HasPrefix(col.RefType, "uint")) {
+			return col
+		}
+	}
+	return nil
+}
+
+// ownerKey returns the model of the users referenced by the owner column of a table, and the
+// name and type of its key
+func (tb *Table) ownerKey(owner *Column) (model, key, keyType string) {
+	if owner.Type == "*AuthUser" {
+		return "AuthUser", "Id", "int"
+	}
+	return strings.TrimPrefix(owner.Type, "*"), camelCase(tb.Fk[owner.Tag.Column].RefColumn), owner.RefType
+}
+
+// addAuditTemplateData adds the assignments of the created_by and updated_by columns of a table
+// to the data of its templates. They are set from the user of the request, so only when the API
+// authenticates its users.
+func addAuditTemplateData(data map[string]interface{}, tb *Table) {
+	data["CreatedBy"], data["CreatedByColumn"] = "", ""
+	data["UpdatedBy"], data["UpdatedByColumn"] = "", ""
+	if authMode == "" || tb.ViewQuery != "" {
+		return
+	}
+	for _, col := range tb.Columns {
+		key, ok := map[string]string{"created_by": "CreatedBy", "updated_by": "UpdatedBy"}[col.Tag.Column]
+		if !ok {
+			continue
+		}
+		code, ok := auditCode(col)
+		if owner := tb.ownerColumn(); owner == col && col.Type != "*AuthUser" {
+			code, ok = "\tt.SetRowOwner(p.UserId)\n", true
+		}
+		if !ok {
+			ColorLog("[WARN] %s.%s: can't hold the user of a request as %s, it is not set\n", tb.Name, col.Tag.Column, col.Type)
+			continue
+		}
+		data[key], data[key+"Column"] = code, col.Tag.Column
+	}
+}
+
+// auditCode returns the assignment of the user of a request, p, to an audit column: its id, or
+// its username to a text column
+func auditCode(col *Column) (string, bool) {
+	field := "t." + col.Name
+	switch col.Type {
+	case "*AuthUser":
+		return fmt.Sprintf("\t%s = &AuthUser{Id: p.UserId}\n", field), true
+	case "string":
+		return fmt.Sprintf("\t%s = p.Username\n", field), true
+	case "sql.NullString":
+		return fmt.Sprintf("\t%s = sql.NullString{String: p.Username, Valid: true}\n", field), true
+	case "sql.NullInt64":
+		return fmt.Sprintf("\t%s = sql.NullInt64{Int64: int64(p.UserId), Valid: true}\n", field), true
+	}
+	typ := strings.TrimPrefix(col.Type, "*")
+	value := "p.Username"
+	if strings.HasPrefix(typ, "int") || strings.HasPrefix(typ, "uint") {
+		value = typ + "(p.UserId)"
+	} else if typ != "string" {
+		return "", false
+	}
+	if typ == col.Type {
+		return fmt.Sprintf("\t%s = %s\n", field, value), true
+	}
+	v := keyVarName(col)
+	return fmt.Sprintf("\t%s := %s\n\t%s = &%s\n", v, value, field, v), true
+}
+
+// Policies are the actions granted to each role on each resource, e.g.
+// {"user": {"posts": ["read", "create", "update:own", "delete:own"]}}. Resources are the route
+// namespaces, * stands for every resource or action, and the :own actions are only granted on
+// the rows owned by the user.
+type Policies map[string]map[string][]string
+
+// writePolicyFile adds the default policies of the generated routes to conf/rbac.json: admin may
+// do anything, user may read, and also write the rows it owns in the tables with an owner. The
+// policies of the file are kept.
+func writePolicyFile(tables []*Table, procedures []*Procedure, currpath string, selectedTables map[string]bool) {
+	fpath := path.Join(currpath, "conf", "rbac.json")
+	policies := Policies{}
+	if b, err := ioutil.ReadFile(fpath); err == nil {
+		if err := json.Unmarshal(b, &policies); err != nil {
+			ColorLog("[ERRO] Could not parse %s: %s\n", fpath, err)
+			os.Exit(2)
+		}
+	}
+	if policies["admin"] == nil {
+		policies["admin"] = map[string][]string{"*": {"*"}}
+	}
+	if policies["user"] == nil {
+		policies["user"] = map[string][]string{}
+	}
+	user := policies["user"]
+	add := func(resource string, actions ...string) {
+		if _, ok := user[resource]; !ok {
+			user[resource] = actions
+		}
+	}
+	for _, tb := range tables {
+		if selectedTables != nil && !selectedTables[tb.Name] {
+			continue
+		}
+		switch {
+		case tb.ViewQuery != "":
+			add(routeName(tb.Name), "read")
+		case tb.ownerColumn() != nil && tb.Pk != "" && !tb.hasCompositeKey():
+			add(routeName(tb.Name), "read", "create", "update:own", "delete:own")
+		case tb.Pk != "" || tb.hasCompositeKey():
+			add(routeName(tb.Name), "read")
+		}
+	}
+	add(routeName("reports"), "read")
+	if procRoutes && len(procedures) > 0 {
+		add("procedures", "read")
+	}
+
+	b, err := json.MarshalIndent(policies, "", "  ")
+	if err != nil {
+		ColorLog("[ERRO] Could not encode the policies: %s\n", err)
+		os.Exit(2)
+	}
+	os.MkdirAll(path.Dir(fpath), 0755)
+	if err := ioutil.WriteFile(fpath, append(b, '\n'), 0644); err != nil {
+		ColorLog("[ERRO] Could not write %s: %s\n", fpath, err)
+		os.Exit(2)
+	}
+	ColorLog("[INFO] policies => %s\n", fpath)
+}
+
+// checkAuthMode exits when -auth isn't a known scheme
+func checkAuthMode() {
+	if _, ok := authSchemes[authMode.String()]; authMode != "" && !ok {
+		ColorLog("[ERRO] Unknown auth '%s', use jwt or apikey\n", authMode)
+		os.Exit(2)
+	}
+}
+
+// authAnnotation returns the comment lines documenting the authentication of a route, if any
+func authAnnotation() string {
+	if authMode == "" {
+		return ""
+	}
+	return fmt.Sprintf("// @Auth %s\n// @Failure 401 missing or invalid credentials\n// @Failure 403 not allowed by conf/rbac.json\n", authMode)
+}
+
+// authDefinition returns the comment line of router.go defining the scheme of -auth for the docs
+func authDefinition() string {
+	if authMode == "" {
+		return ""
+	}
+	return fmt.Sprintf("// @Auth %s header %s\n", authMode, authSchemes[authMode.String()])
+}
+
+// writeAuthFiles writes the users and tokens models and migration, the auth and authorization
+// filters and the login and refresh endpoints of an API application
+func writeAuthFiles(apppath, driver string, data map[string]interface{}) {
+	os.Mkdir(path.Join(apppath, "filters"), 0755)
+	os.Mkdir(path.Join(apppath, "models"), 0755)
+	for _, f := range []struct{ dir, file, tpl string }{
+		{"helpers", "auth.go", "api/auth_helpers.go.tpl"},
+		{"models", "auth.go", "api/auth_model.go.tpl"},
+		{"filters", "auth.go", "api/auth_filter.go.tpl"},
+		{"filters", "rbac.go", "api/rbac_filter.go.tpl"},
+		{"controllers", "auth.go", "api/auth_controller.go.tpl"},
+	} {
+		fpath := path.Join(apppath, f.dir, f.file)
+		fmt.Printf("create file %s: %s\n", f.file, fpath)
+		writetofile(fpath, renderTemplate(f.tpl, data))
+	}
+	up, down := authMigrationSQL(driver)
+	generateMigration("create_auth_tables", migrationSqlCalls(up), migrationSqlCalls(down), apppath, driver)
+}
+
+// authMigrationSQL returns the statements creating and dropping the users and tokens tables
+func authMigrationSQL(driver string) (up, down []string) {
+	switch driver {
+	case "postgres":
+		up = []string{
+			`CREATE TABLE "auth_users" ("id" serial PRIMARY KEY, "username" varchar(128) NOT NULL UNIQUE, ` +
+				`"password_hash" varchar(255) NOT NULL, "role" varchar(64) NOT NULL DEFAULT 'user', "created" timestamp NOT NULL)`,
+			`CREATE TABLE "auth_tokens" ("id" serial PRIMARY KEY, "user_id" integer NOT NULL REFERENCES "auth_users" ("id") ON DELETE CASCADE, ` +
+				`"kind" varchar(16) NOT NULL, "token_hash" char(64) NOT NULL UNIQUE, "expires" timestamp NOT NULL, "created" timestamp NOT NULL)`,
+		}
+		down = []string{`DROP TABLE "auth_tokens"`, `DROP TABLE "auth_users"`}
+	case "sqlite", "sqlite3":
+		up = []string{
+			`CREATE TABLE "auth_users" ("id" integer PRIMARY KEY AUTOINCREMENT, "username" varchar(128) NOT NULL UNIQUE, ` +
+				`"password_hash" varchar(255) NOT NULL, "role" varchar(64) NOT NULL DEFAULT 'user', "created" datetime NOT NULL)`,
+			`CREATE TABLE "auth_tokens" ("id" integer PRIMARY KEY AUTOINCREMENT, "user_id" integer NOT NULL REFERENCES "auth_users" ("id") ON DELETE CASCADE, ` +
+				`"kind" varchar(16) NOT NULL, "token_hash" char(64) NOT NULL UNIQUE, "expires" datetime NOT NULL, "created" datetime NOT NULL)`,
+		}
+		down = []string{`DROP TABLE "auth_tokens"`, `DROP TABLE "auth_users"`}
+	default:
+		up = []string{
+			"CREATE TABLE `auth_users` (`id` int(11) NOT NULL AUTO_INCREMENT, `username` varchar(128) NOT NULL, " +
+				"`password_hash` varchar(255) NOT NULL, `role` varchar(64) NOT NULL DEFAULT 'user', `created` datetime NOT NULL, " +
+				"PRIMARY KEY (`id`), UNIQUE KEY `username` (`username`))",
+			"CREATE TABLE `auth_tokens` (`id` int(11) NOT NULL AUTO_INCREMENT, `user_id` int(11) NOT NULL, `kind` varchar(16) NOT NULL, " +
+				"`token_hash` char(64) NOT NULL, `expires` datetime NOT NULL, `created` datetime NOT NULL, PRIMARY KEY (`id`), " +
+				"UNIQUE KEY `token_hash` (`token_hash`), CONSTRAINT `auth_tokens_user_id` FOREIGN KEY (`user_id`) REFERENCES `auth_users` (`id`) ON DELETE CASCADE)",
+		}
+		down = []string{"DROP TABLE `auth_tokens`", "DROP TABLE `auth_users`"}
+	}
+	return
+}
+
+// newSecret returns a random key signing the tokens of an application
+func newSecret() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		ColorLog("[ERRO] Could not generate the token secret: %s\n", err)
+		os.Exit(2)
+	}
+	return hex.EncodeToString(b)
+}
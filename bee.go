@@ -84,6 +84,7 @@ var commands = []*Command{
 	cmdGenerate,
 	//cmdRundocs,
 	cmdMigrate,
+	cmdDb,
 }
 
 func main() {
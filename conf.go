@@ -66,9 +66,13 @@ var conf struct {
 		IngExt []string `json:"ignore_ext"`
 	}
 	Database struct {
-		Driver string
-		Conn   string
+		Driver      string
+		Conn        string
+		Default     string                     // connection used when neither -db nor -conn is given
+		Connections map[string]*dbProfile      // named connections selected with -db
+		Types       map[string]*sqlTypeMapping // Go types of SQL types, checked before the built-in ones
 	}
+	Appcode appcodeNaming // naming conventions of bee generate appcode
 }
 
 // loadConfig loads customized configuration.
@@ -0,0 +1,597 @@
+// Copyright 2013 bee authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+var cmdDb = &Command{
+	UsageLine: "db [Command]",
+	Short:     "load seed data and schema snapshots into the database",
+	Long: `
+bee db seed [-env=dev] [-database=test] [-driver=mysql] [-conn="root:@tcp(127.0.0.1:3306)/test"]
+    apply all seed files in database/seeds that were not applied yet, in file name order
+    -env:    the environment seeds are applied for (default: dev)
+    -database: database name (default: test)
+    -driver: [mysql | postgres | sqlite] (default: mysql)
+    -conn:   the connection string used by the driver, the default is root:@tcp(127.0.0.1:3306)/test
+
+Seed files are created with 'bee generate seed'. A seed file is either a JSON or
+YAML file listing the rows of a table, or a Go file whose function loads the data
+through the ORM. A seed restricted to some environments with 'envs' is skipped
+in the others. Applied seeds are recorded in the 'seeds' table, so running
+'bee db seed' again only applies new seeds.
+
+bee db dump [-file=database/schema.sql] [-driver=mysql] [-conn="root:@tcp(127.0.0.1:3306)/test"]
+    write the current schema of the database to a SQL file, followed by the content
+    of the migrations table, so the file can be committed next to the migrations
+
+bee db load [-file=database/schema.sql] [-driver=mysql] [-conn="root:@tcp(127.0.0.1:3306)/test"]
+    create the schema of an empty database from a file written by 'bee db dump',
+    instead of replaying every migration
+
+The dump is canonical: tables, indexes and constraints are written in name order
+and migrations without their timestamps, so dumping a database migrated from
+scratch and diffing it with the committed schema.sql detects schema drift.
+
+All commands accept -db=name to use a database connection defined in bee.json
+instead of -conn.
+`,
+}
+
+var dDriver docValue
+var dConn docValue
+var dDatabase docValue
+var dEnv docValue
+var dFile docValue
+var dDb docValue
+
+func init() {
+	cmdDb.Run = runDb
+	cmdDb.Flag.Var(&dDriver, "driver", "database driver: mysql, postgres, sqlite, etc.")
+	cmdDb.Flag.Var(&dConn, "conn", "connection string used by the driver to connect to a database instance")
+	cmdDb.Flag.Var(&dDatabase, "database", "specify the database want to use.")
+	cmdDb.Flag.Var(&dDb, "db", "name of a database connection defined in bee.json")
+	cmdDb.Flag.Var(&dEnv, "env", "environment the seeds are applied for.")
+	cmdDb.Flag.Var(&dFile, "file", "schema file written by dump and read by load.")
+}
+
+// runDb is the entry point of the db command
+func runDb(cmd *Command, args []string) int {
+	crupath, _ := os.Getwd()
+	if len(args) < 1 {
+		ColorLog("[ERRO] Command is missing\n")
+		ColorLog("[HINT] Usage: bee db seed|dump|load\n")
+		os.Exit(2)
+	}
+	err := loadConfig()
+	if err != nil {
+		ColorLog("[ERRO] Fail to parse bee.json[ %s ]\n", err)
+	}
+	cmd.Flag.Parse(args[1:])
+
+	driver, connStr := resolveDbConn(dDb.String(), dDriver.String(), dConn.String(), dDatabase.String(), "test")
+	dDriver, dConn = docValue(driver), docValue(connStr)
+	if dEnv == "" {
+		dEnv = "dev"
+	}
+	if dFile == "" {
+		dFile = docValue(path.Join("database", "schema.sql"))
+	}
+	ColorLog("[INFO] Using '%s' as 'driver'\n", dDriver)
+	ColorLog("[INFO] Using '%s' as 'conn'\n", dConn)
+
+	switch args[0] {
+	case "seed":
+		ColorLog("[INFO] Using '%s' as 'env'\n", dEnv)
+		seed(crupath, dDriver.String(), dConn.String(), dEnv.String())
+		ColorLog("[SUCC] Seeding successful!\n")
+	case "dump":
+		dumpSchema(dDriver.String(), dConn.String(), dFile.String())
+		ColorLog("[SUCC] Schema written to %s\n", dFile)
+	case "load":
+		loadSchema(dDriver.String(), dConn.String(), dFile.String())
+		ColorLog("[SUCC] Schema loaded from %s\n", dFile)
+	default:
+		ColorLog("[ERRO] Unknown command: %s\n", args[0])
+		os.Exit(2)
+	}
+	return 0
+}
+
+// seedFile is the content of a JSON or YAML seed file
+type seedFile struct {
+	Envs  []string                 `json:"envs" yaml:"envs"`
+	Table string                   `json:"table" yaml:"table"`
+	Rows  []map[string]interface{} `json:"rows" yaml:"rows"`
+}
+
+// seed applies the seed files of database/seeds that are not recorded in the seeds table yet.
+// Data files are loaded by bee itself, Go seeds are built into a binary like migrations are.
+func seed(crupath, driver, connStr, env string) {
+	dir := path.Join(crupath, "database", S_PATH)
+	binary := "s"
+	source := binary + ".go"
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		ColorLog("[ERRO] Could not read seed directory: %s\n", err)
+		os.Exit(2)
+	}
+	db, err := sql.Open(driver, connStr)
+	if err != nil {
+		ColorLog("[ERRO] Could not connect to %s: %s\n", driver, connStr)
+		os.Exit(2)
+	}
+	defer db.Close()
+	if _, err := db.Exec(SEEDS_DDL); err != nil {
+		ColorLog("[ERRO] Could not create seeds table: %s\n", err)
+		os.Exit(2)
+	}
+	applied := getAppliedSeeds(db, driver, env)
+
+	// ReadDir sorts by file name, which starts with the creation time of the seed
+	built := false
+	for _, fi := range files {
+		ext := path.Ext(fi.Name())
+		name := strings.TrimSuffix(fi.Name(), ext)
+		if fi.IsDir() || fi.Name() == source || applied[name] {
+			continue
+		}
+		switch ext {
+		case ".json", ".yml", ".yaml":
+			applySeedFile(db, driver, path.Join(dir, fi.Name()), name, env)
+		case ".go":
+			if !built {
+				writeSeedSourceFile(dir, source, driver, connStr)
+				buildSeedBinary(dir, binary)
+				built = true
+			}
+			ColorLog("[INFO] Seeding %s\n", name)
+			runSeedBinary(dir, binary, env, name)
+		}
+	}
+	if built {
+		removeTempFile(dir, source)
+		removeTempFile(dir, binary)
+	}
+}
+
+// getAppliedSeeds returns the names of the seeds applied for env
+func getAppliedSeeds(db *sql.DB, driver, env string) map[string]bool {
+	applied := make(map[string]bool)
+	rows, err := db.Query("SELECT name FROM seeds WHERE env = "+placeholder(driver, 1), env)
+	if err != nil {
+		ColorLog("[ERRO] Could not retrieve seeds: %s\n", err)
+		os.Exit(2)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			ColorLog("[ERRO] Could not read seeds in database: %s\n", err)
+			os.Exit(2)
+		}
+		applied[name] = true
+	}
+	return applied
+}
+
+// applySeedFile inserts the rows of a JSON or YAML seed file and records the seed,
+// all in one transaction.
+func applySeedFile(db *sql.DB, driver, fpath, name, env string) {
+	data, err := ioutil.ReadFile(fpath)
+	if err != nil {
+		ColorLog("[ERRO] Could not read seed file: %s\n", err)
+		os.Exit(2)
+	}
+	var sf seedFile
+	if path.Ext(fpath) == ".json" {
+		err = json.Unmarshal(data, &sf)
+	} else {
+		err = yaml.Unmarshal(data, &sf)
+	}
+	if err != nil {
+		ColorLog("[ERRO] Could not parse seed file %s: %s\n", fpath, err)
+		os.Exit(2)
+	}
+	if len(sf.Envs) > 0 && !containsString(sf.Envs, env) {
+		ColorLog("[INFO] Skipping %s, it is not for '%s'\n", name, env)
+		return
+	}
+	if sf.Table == "" {
+		ColorLog("[ERRO] Seed file %s has no table\n", fpath)
+		os.Exit(2)
+	}
+	ColorLog("[INFO] Seeding %s: %d rows into %s\n", name, len(sf.Rows), sf.Table)
+	tx, err := db.Begin()
+	if err != nil {
+		ColorLog("[ERRO] Could not start transaction: %s\n", err)
+		os.Exit(2)
+	}
+	d := sqlDialect{driver}
+	for _, row := range sf.Rows {
+		var columns []string
+		for column := range row {
+			columns = append(columns, column)
+		}
+		sort.Strings(columns)
+		quoted := make([]string, len(columns))
+		marks := make([]string, len(columns))
+		values := make([]interface{}, len(columns))
+		for i, column := range columns {
+			quoted[i] = d.quote(column)
+			marks[i] = placeholder(driver, i+1)
+			values[i] = row[column]
+		}
+		stmt := "INSERT INTO " + d.quote(sf.Table) + " (" + strings.Join(quoted, ", ") + ") VALUES (" + strings.Join(marks, ", ") + ")"
+		if _, err := tx.Exec(stmt, values...); err != nil {
+			tx.Rollback()
+			ColorLog("[ERRO] Could not seed %s: %s\n", name, err)
+			os.Exit(2)
+		}
+	}
+	if _, err := tx.Exec("INSERT INTO seeds (name, env) VALUES ("+placeholder(driver, 1)+", "+placeholder(driver, 2)+")", name, env); err != nil {
+		tx.Rollback()
+		ColorLog("[ERRO] Could not record seed %s: %s\n", name, err)
+		os.Exit(2)
+	}
+	if err := tx.Commit(); err != nil {
+		ColorLog("[ERRO] Could not commit seed %s: %s\n", name, err)
+		os.Exit(2)
+	}
+}
+
+// placeholder returns the n-th bind parameter of a statement for the driver
+func placeholder(driver string, n int) string {
+	if driver == "postgres" {
+		return "$" + strconv.Itoa(n)
+	}
+	return "?"
+}
+
+// writeSeedSourceFile creates the main file of the Go seeds binary based on SEED_MAIN_TPL
+func writeSeedSourceFile(dir, source, driver, connStr string) {
+	changeDir(dir)
+	if f, err := os.OpenFile(source, os.O_CREATE|os.O_EXCL|os.O_RDWR, 0666); err != nil {
+		ColorLog("[ERRO] Could not create file: %s\n", err)
+		os.Exit(2)
+	} else {
+		content := strings.Replace(SEED_MAIN_TPL, "{{DBDriver}}", driver, -1)
+		content = strings.Replace(content, "{{DriverPkg}}", driverPkg(driver), -1)
+		content = strings.Replace(content, "{{ConnStr}}", connStr, -1)
+		if _, err := f.WriteString(content); err != nil {
+			ColorLog("[ERRO] Could not write to file: %s\n", err)
+			os.Exit(2)
+		}
+		f.Close()
+	}
+}
+
+// buildSeedBinary go-builds the Go seeds in database/seeds
+func buildSeedBinary(dir, binary string) {
+	changeDir(dir)
+	cmd := exec.Command("go", "build", "-o", binary)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		ColorLog("[ERRO] Could not build seed binary: %s\n", err)
+		formatShellErrOutput(string(out))
+		removeTempFile(dir, binary)
+		removeTempFile(dir, binary+".go")
+		os.Exit(2)
+	}
+}
+
+// runSeedBinary runs a single Go seed for env
+func runSeedBinary(dir, binary, env, name string) {
+	changeDir(dir)
+	cmd := exec.Command("./"+binary, env, name)
+	out, err := cmd.CombinedOutput()
+	formatShellOutput(string(out))
+	if err != nil {
+		ColorLog("[ERRO] Could not run seed %s: %s\n", name, err)
+		removeTempFile(dir, binary)
+		removeTempFile(dir, binary+".go")
+		os.Exit(2)
+	}
+}
+
+// dumpSchema writes the schema of the database and the content of its migrations table to file
+func dumpSchema(driver, connStr, file string) {
+	trans, ok := dbDriver[driver]
+	if !ok {
+		ColorLog("[ERRO] Dumping %s database is not supported yet.\n", driver)
+		os.Exit(2)
+	}
+	db, err := sql.Open(driver, connStr)
+	if err != nil {
+		ColorLog("[ERRO] Could not connect to %s: %s\n", driver, connStr)
+		os.Exit(2)
+	}
+	defer db.Close()
+	names := trans.GetTableNames(db)
+	sort.Strings(names)
+	tables := getTableObjects(names, db, trans)
+	hasMigrations := tableExists(db, "migrations")
+	if hasMigrations {
+		tables = append(tables, getTableObjects([]string{"migrations"}, db, trans)...)
+	}
+
+	d := sqlDialect{driver}
+	var stmts []string
+	for _, tb := range tables {
+		stmts = append(stmts, d.dumpTable(tb))
+	}
+	for _, tb := range tables {
+		indexes := tb.Indexes
+		sort.Sort(indexesByName(indexes))
+		for _, idx := range indexes {
+			stmts = append(stmts, d.dumpIndex(tb.Name, idx))
+		}
+	}
+	for _, tb := range tables {
+		var fks []*ForeignKey
+		for _, fk := range tb.Fk {
+			fks = append(fks, fk)
+		}
+		sort.Sort(foreignKeysByConstraint(fks))
+		for _, fk := range fks {
+			stmts = append(stmts, d.addForeignKey(tb.Name, fk))
+		}
+	}
+	if hasMigrations {
+		stmts = append(stmts, dumpMigrations(db, d)...)
+	}
+
+	dir := path.Dir(file)
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		ColorLog("[ERRO] Could not create directory %s: %s\n", dir, err)
+		os.Exit(2)
+	}
+	content := fmt.Sprintf("-- Schema of the %s database, written by 'bee db dump'. Do not edit.\n\n", driver)
+	for _, stmt := range stmts {
+		content += stmt + ";\n"
+	}
+	if err := ioutil.WriteFile(file, []byte(content), 0666); err != nil {
+		ColorLog("[ERRO] Could not write schema file: %s\n", err)
+		os.Exit(2)
+	}
+}
+
+// dumpMigrations returns the INSERT statements recreating the rows of the migrations table.
+// The ids and timestamps are left out so that dumps of different databases can be compared.
+func dumpMigrations(db *sql.DB, d sqlDialect) (stmts []string) {
+	rows, err := db.Query("SELECT name, statements, rollback_statements, status FROM migrations ORDER BY id_migration")
+	if err != nil {
+		ColorLog("[ERRO] Could not retrieve migrations: %s\n", err)
+		os.Exit(2)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name, statements, rollbackStatements, status sql.NullString
+		if err := rows.Scan(&name, &statements, &rollbackStatements, &status); err != nil {
+			ColorLog("[ERRO] Could not read migrations in database: %s\n", err)
+			os.Exit(2)
+		}
+		values := []string{d.nullLiteral(name), d.nullLiteral(statements), d.nullLiteral(rollbackStatements), d.nullLiteral(status)}
+		stmts = append(stmts, "INSERT INTO "+d.quote("migrations")+" (name, statements, rollback_statements, status) VALUES ("+strings.Join(values, ", ")+")")
+	}
+	return
+}
+
+// loadSchema creates the schema written by dumpSchema in an empty database
+func loadSchema(driver, connStr, file string) {
+	trans, ok := dbDriver[driver]
+	if !ok {
+		ColorLog("[ERRO] Loading schema into %s database is not supported yet.\n", driver)
+		os.Exit(2)
+	}
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		ColorLog("[ERRO] Could not read schema file: %s\n", err)
+		os.Exit(2)
+	}
+	db, err := sql.Open(driver, connStr)
+	if err != nil {
+		ColorLog("[ERRO] Could not connect to %s: %s\n", driver, connStr)
+		os.Exit(2)
+	}
+	defer db.Close()
+	if len(trans.GetTableNames(db)) > 0 || tableExists(db, "migrations") {
+		ColorLog("[ERRO] The database is not empty\n")
+		ColorLog("[HINT] A schema can only be loaded into a new database\n")
+		os.Exit(2)
+	}
+
+	stmts := splitSqlStatements(string(data), driver)
+	ColorLog("[INFO] Loading %d statements\n", len(stmts))
+	if !isTransactionalDDL(driver) {
+		for _, stmt := range stmts {
+			if _, err := db.Exec(stmt); err != nil {
+				ColorLog("[ERRO] Could not execute %s: %s\n", stmt, err)
+				ColorLog("[HINT] The schema was partially loaded, drop the database before trying again\n")
+				os.Exit(2)
+			}
+		}
+		return
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		ColorLog("[ERRO] Could not start transaction: %s\n", err)
+		os.Exit(2)
+	}
+	for _, stmt := range stmts {
+		if _, err := tx.Exec(stmt); err != nil {
+			tx.Rollback()
+			ColorLog("[ERRO] Could not execute %s: %s\n", stmt, err)
+			os.Exit(2)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		ColorLog("[ERRO] Could not commit schema: %s\n", err)
+		os.Exit(2)
+	}
+}
+
+// tableExists checks whether a table can be queried
+func tableExists(db *sql.DB, table string) bool {
+	rows, err := db.Query("SELECT 1 FROM " + table + " WHERE 1 = 0")
+	if err != nil {
+		return false
+	}
+	rows.Close()
+	return true
+}
+
+// splitSqlStatements splits a SQL script on the semicolons outside of string literals
+// and quoted identifiers, dropping comment lines.
+func splitSqlStatements(script, driver string) (stmts []string) {
+	var stmt []rune
+	var quote rune
+	escape := false
+	comment := false
+	for _, c := range script {
+		switch {
+		case comment:
+			if c == '\n' {
+				comment = false
+			}
+			continue
+		case escape:
+			escape = false
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			} else if c == '\\' && driver == "mysql" && quote == '\'' {
+				escape = true
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case c == '-' && len(stmt) > 0 && stmt[len(stmt)-1] == '-':
+			stmt = stmt[:len(stmt)-1]
+			comment = true
+			continue
+		case c == ';':
+			if s := strings.TrimSpace(string(stmt)); s != "" {
+				stmts = append(stmts, s)
+			}
+			stmt = stmt[:0]
+			continue
+		}
+		stmt = append(stmt, c)
+	}
+	if s := strings.TrimSpace(string(stmt)); s != "" {
+		stmts = append(stmts, s)
+	}
+	return
+}
+
+type indexesByName []*Index
+
+func (s indexesByName) Len() int           { return len(s) }
+func (s indexesByName) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s indexesByName) Less(i, j int) bool { return s[i].Name < s[j].Name }
+
+type foreignKeysByConstraint []*ForeignKey
+
+func (s foreignKeysByConstraint) Len() int           { return len(s) }
+func (s foreignKeysByConstraint) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s foreignKeysByConstraint) Less(i, j int) bool { return s[i].Constraint < s[j].Constraint }
+
+const (
+	SEEDS_DDL = `
+CREATE TABLE IF NOT EXISTS seeds (
+	name varchar(255) NOT NULL,
+	env varchar(64) NOT NULL,
+	created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (name, env)
+)
+`
+	SEED_MAIN_TPL = `package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/aamsur/beego/orm"
+
+	{{DriverPkg}}
+)
+
+// seed is a Go seed registered by its file
+type seed struct {
+	envs []string
+	run  func(o orm.Ormer) error
+}
+
+var seeds = make(map[string]seed)
+
+func registerSeed(name string, envs []string, run func(o orm.Ormer) error) {
+	seeds[name] = seed{envs, run}
+}
+
+func init() {
+	orm.RegisterDataBase("default", "{{DBDriver}}", "{{ConnStr}}")
+}
+
+func main() {
+	env, name := os.Args[1], os.Args[2]
+	s, ok := seeds[name]
+	if !ok {
+		fmt.Println("seed is not registered:", name)
+		os.Exit(2)
+	}
+	if len(s.envs) > 0 {
+		found := false
+		for _, e := range s.envs {
+			found = found || e == env
+		}
+		if !found {
+			fmt.Printf("skipping %s, it is not for '%s'\n", name, env)
+			return
+		}
+	}
+	o := orm.NewOrm()
+	if err := o.Begin(); err != nil {
+		fmt.Println(err)
+		os.Exit(2)
+	}
+	if err := s.run(o); err != nil {
+		o.Rollback()
+		fmt.Println(err)
+		os.Exit(2)
+	}
+	if _, err := o.Raw("INSERT INTO seeds (name, env) VALUES (?, ?)", name, env).Exec(); err != nil {
+		o.Rollback()
+		fmt.Println(err)
+		os.Exit(2)
+	}
+	if err := o.Commit(); err != nil {
+		fmt.Println(err)
+		os.Exit(2)
+	}
+}
+`
+)
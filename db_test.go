@@ -0,0 +1,19 @@
+package main
+
+import (
+	"database/sql"
+	"reflect"
+	"testing"
+)
+
+func TestSplitSqlStatements(t *testing.T) {
+	d := sqlDialect{"mysql"}
+	insert := "INSERT INTO `migrations` (name, statements) VALUES (" +
+		d.literal("init") + ", " + d.nullLiteral(sql.NullString{String: "CREATE TABLE `a` (`b` varchar(8) DEFAULT 'x;\\'');\n", Valid: true}) + ")"
+	script := "-- header; not a statement\n\nCREATE TABLE `a` (\n\t`b` varchar(8)\n);\n" + insert + ";\n"
+	stmts := splitSqlStatements(script, "mysql")
+	expected := []string{"CREATE TABLE `a` (\n\t`b` varchar(8)\n)", insert}
+	if !reflect.DeepEqual(stmts, expected) {
+		t.Errorf("unexpected statements:\n%q\nexpected:\n%q", stmts, expected)
+	}
+}
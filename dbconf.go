@@ -0,0 +1,238 @@
+// Copyright 2013 bee authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// dbProfile is a named database connection of bee.json
+type dbProfile struct {
+	Dialect     string            // mysql, postgres or sqlite3, used as the driver name
+	Host        string            // default: 127.0.0.1
+	Port        int               // default: the port of the dialect
+	User        string            // default: root for mysql, postgres for postgres
+	Password    string            // prefer password_env, bee.json is usually committed
+	PasswordEnv string            `json:"password_env"` // environment variable holding the password
+	Database    string            // database name, or file for sqlite3
+	Params      map[string]string // driver parameters, e.g. charset or sslmode
+}
+
+// resolveDbConn returns the driver and connection string selected by the -db, -driver, -conn and
+// -database flags. A named connection of bee.json is used when -db is given or when bee.json has a
+// default connection and -conn is not given. Otherwise the connection string comes from -conn,
+// bee.json or the local server of the driver, opening defaultDb.
+func resolveDbConn(name, driver, conn, database, defaultDb string) (string, string) {
+	name = dbProfileName(name, conn)
+	if name != "" {
+		p, ok := conf.Database.Connections[name]
+		if !ok {
+			ColorLog("[ERRO] Database connection '%s' is not defined in bee.json\n", name)
+			ColorLog("[HINT] Add it under database.connections\n")
+			os.Exit(2)
+		}
+		if driver == "" {
+			driver = p.Dialect
+		}
+		if driver == "" {
+			driver = "mysql"
+		}
+		driver = sqlDriverName(driver)
+		db := p.Database
+		if database != "" {
+			db = database
+		}
+		ColorLog("[INFO] Using database connection '%s'\n", name)
+		return driver, p.dsn(driver, db)
+	}
+
+	if driver == "" {
+		driver = conf.Database.Driver
+		if driver == "" {
+			driver = "mysql"
+		}
+	}
+	driver = sqlDriverName(driver)
+	if conn == "" {
+		conn = conf.Database.Conn
+	}
+	if conn == "" {
+		conn = (&dbProfile{}).dsn(driver, defaultDb)
+	}
+	if database != "" {
+		// -database opens another database through the same connection
+		conn = withDatabase(driver, conn, database)
+	}
+	return driver, conn
+}
+
+// withDatabase replaces the database of the connection string conn, keeping its host,
+// credentials and parameters.
+func withDatabase(driver, conn, database string) string {
+	switch driver {
+	case "postgres":
+		if u, err := url.Parse(conn); err == nil && (u.Scheme == "postgres" || u.Scheme == "postgresql") {
+			u.Path = "/" + database
+			return u.String()
+		}
+		// key=value form: host=localhost dbname=shop sslmode=disable
+		var fields []string
+		for _, f := range strings.Fields(conn) {
+			if !strings.HasPrefix(f, "dbname=") {
+				fields = append(fields, f)
+			}
+		}
+		return strings.Join(append(fields, "dbname="+database), " ")
+	case "sqlite3":
+		if i := strings.Index(conn, "?"); i >= 0 {
+			return database + conn[i:]
+		}
+		return database
+	default:
+		// user:password@tcp(host:port)/dbname?param=value, split on the last slash like the driver does
+		i := strings.LastIndex(conn, "/")
+		if i < 0 {
+			return conn + "/" + database
+		}
+		params := ""
+		if j := strings.Index(conn[i:], "?"); j >= 0 {
+			params = conn[i+j:]
+		}
+		return conn[:i+1] + database + params
+	}
+}
+
+// withoutPassword removes the password from the connection string conn
+func withoutPassword(driver, conn string) string {
+	switch driver {
+	case "postgres":
+		if u, err := url.Parse(conn); err == nil && u.User != nil && (u.Scheme == "postgres" || u.Scheme == "postgresql") {
+			u.User = url.User(u.User.Username())
+			return u.String()
+		}
+		var fields []string
+		for _, f := range strings.Fields(conn) {
+			if !strings.HasPrefix(f, "password=") {
+				fields = append(fields, f)
+			}
+		}
+		return strings.Join(fields, " ")
+	case "sqlite3":
+		return conn
+	default:
+		// user:password@tcp(host:port)/dbname, the address follows the last @ before the database
+		i := strings.LastIndex(conn[:strings.LastIndex(conn, "/")+1], "@")
+		if i < 0 {
+			return conn
+		}
+		if j := strings.Index(conn[:i], ":"); j >= 0 {
+			return conn[:j] + conn[i:]
+		}
+		return conn
+	}
+}
+
+// sqlDriverName returns the name database/sql knows a driver by
+func sqlDriverName(driver string) string {
+	switch driver {
+	case "sqlite":
+		return "sqlite3"
+	case "postgresql":
+		return "postgres"
+	}
+	return driver
+}
+
+// dbProfileName returns the name of the bee.json connection selected by the -db and -conn flags
+func dbProfileName(name, conn string) string {
+	if name == "" && conn == "" {
+		return conf.Database.Default
+	}
+	return name
+}
+
+// password returns the password of the connection, read from password_env when it is set
+func (p *dbProfile) password() string {
+	if p.PasswordEnv != "" {
+		return os.Getenv(p.PasswordEnv)
+	}
+	return p.Password
+}
+
+// dsn builds the connection string of the profile for the driver
+func (p *dbProfile) dsn(driver, database string) string {
+	host, port, user := p.Host, p.Port, p.User
+	if host == "" {
+		host = "127.0.0.1"
+	}
+	switch driver {
+	case "postgres":
+		if port == 0 {
+			port = 5432
+		}
+		if user == "" {
+			user = "postgres"
+		}
+		u := url.URL{
+			Scheme: "postgres",
+			Host:   host + ":" + strconv.Itoa(port),
+			Path:   "/" + database,
+		}
+		if pass := p.password(); pass != "" {
+			u.User = url.UserPassword(user, pass)
+		} else {
+			u.User = url.User(user)
+		}
+		u.RawQuery = p.query()
+		return u.String()
+	case "sqlite", "sqlite3":
+		dsn := database
+		if q := p.query(); q != "" {
+			dsn += "?" + q
+		}
+		return dsn
+	default:
+		// go-sql-driver/mysql: user:password@tcp(host:port)/dbname?param=value
+		if port == 0 {
+			port = 3306
+		}
+		if user == "" {
+			user = "root"
+		}
+		dsn := user + ":" + p.password() + "@tcp(" + host + ":" + strconv.Itoa(port) + ")/" + database
+		if q := p.query(); q != "" {
+			dsn += "?" + q
+		}
+		return dsn
+	}
+}
+
+// query encodes the parameters of the profile sorted by name
+func (p *dbProfile) query() string {
+	var keys []string
+	for k := range p.Params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var params []string
+	for _, k := range keys {
+		params = append(params, url.QueryEscape(k)+"="+url.QueryEscape(p.Params[k]))
+	}
+	return strings.Join(params, "&")
+}
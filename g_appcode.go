@@ -17,15 +17,19 @@ package main
 import (
 	"database/sql"
 	"fmt"
+	"go/token"
 	"os"
 	"os/exec"
 	"path"
 	"path/filepath"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 
 	_ "github.com/go-sql-driver/mysql"
 	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
 )
 
 const (
@@ -37,8 +41,11 @@ const (
 // DbTransformer has method to reverse engineer a database schema to restful api code
 type DbTransformer interface {
 	GetTableNames(conn *sql.DB) []string
+	GetViewNames(conn *sql.DB) []string
+	GetProcedures(conn *sql.DB) []*Procedure
 	GetConstraints(conn *sql.DB, table *Table, blackList map[string]bool)
 	GetColumns(conn *sql.DB, table *Table, blackList map[string]bool)
+	GetIndexes(conn *sql.DB, table *Table)
 	GetGoDataType(sqlType string) string
 }
 
@@ -50,10 +57,15 @@ type MysqlDB struct {
 type PostgresDB struct {
 }
 
+// SqliteDB is the SQLite version of DbTransformer
+type SqliteDB struct {
+}
+
 // dbDriver maps a DBMS name to its version of DbTransformer
 var dbDriver = map[string]DbTransformer{
 	"mysql":    &MysqlDB{},
 	"postgres": &PostgresDB{},
+	"sqlite3":  &SqliteDB{},
 }
 
 type MvcPath struct {
@@ -77,7 +89,9 @@ var typeMappingMysql = map[string]string{
 	"mediumint unsigned": "uint32",
 	"bigint unsigned":    "uint64",
 	"bit":                "uint64",
-	"bool":               "bool",   // boolean
+	"bool":               "bool", // boolean
+	"boolean":            "bool",
+	"year":               "int16",  // year
 	"enum":               "string", // enum
 	"set":                "string", // set
 	"varchar":            "string", // string & text
@@ -97,14 +111,53 @@ var typeMappingMysql = map[string]string{
 	"float":              "float32", // float & decimal
 	"double":             "float64",
 	"decimal":            "float64",
+	"numeric":            "float64",
+	"real":               "float64",
 	"binary":             "string", // binary
 	"varbinary":          "string",
+	"json":               "string", // json
+	"geometry":           "string", // spatial, in the internal binary format
+	"point":              "string",
+	"linestring":         "string",
+	"polygon":            "string",
+	"multipoint":         "string",
+	"multilinestring":    "string",
+	"multipolygon":       "string",
+	"geometrycollection": "string",
+}
+
+// typeMappingSqlite maps the common SQLite column types to Go data types
+var typeMappingSqlite = map[string]string{
+	"integer":   "int",
+	"int":       "int",
+	"tinyint":   "int8",
+	"smallint":  "int16",
+	"mediumint": "int32",
+	"bigint":    "int64",
+	"boolean":   "bool",
+	"bool":      "bool",
+	"varchar":   "string",
+	"char":      "string",
+	"text":      "string",
+	"clob":      "string",
+	"blob":      "string",
+	"date":      "time.Time",
+	"datetime":  "time.Time",
+	"timestamp": "time.Time",
+	"time":      "time.Time",
+	"real":      "float64",
+	"float":     "float64",
+	"double":    "float64",
+	"decimal":   "float64",
+	"numeric":   "float64",
 }
 
 // typeMappingPostgres maps SQL data type to corresponding Go data type
 var typeMappingPostgres = map[string]string{
 	"serial":                      "int", // serial
 	"big serial":                  "int64",
+	"smallserial":                 "int16",
+	"bigserial":                   "int64",
 	"smallint":                    "int16", // int
 	"integer":                     "int",
 	"bigint":                      "int64",
@@ -118,6 +171,11 @@ var typeMappingPostgres = map[string]string{
 	"time":                        "time.Time",
 	"timestamp":                   "time.Time",
 	"timestamp without time zone": "time.Time",
+	"timestamp with time zone":    "time.Time",
+	"timestamptz":                 "time.Time",
+	"time without time zone":      "time.Time",
+	"time with time zone":         "time.Time",
+	"timetz":                      "time.Time",
 	"interval":                    "string",  // time interval, string for now
 	"real":                        "float32", // float & decimal
 	"double precision":            "float64",
@@ -130,31 +188,107 @@ var typeMappingPostgres = map[string]string{
 	"USER-DEFINED":                "string",  // user defined
 	"uuid":                        "string",  // uuid
 	"json":                        "string",  // json
+	"jsonb":                       "string",
+	"xml":                         "string", // xml
+	"inet":                        "string", // network addresses
+	"cidr":                        "string",
+	"macaddr":                     "string",
+	"macaddr8":                    "string",
+	"bit":                         "string", // bit strings
+	"bit varying":                 "string",
+	"oid":                         "uint32", // object identifier
+	"point":                       "string", // geometry
+	"line":                        "string",
+	"lseg":                        "string",
+	"box":                         "string",
+	"path":                        "string",
+	"polygon":                     "string",
+	"circle":                      "string",
+	"tsquery":                     "string", // fulltext
+}
+
+// sqlTypeMapping is an entry of the type map of bee.json, e.g.
+// "types": {"citext": {"type": "string", "orm": "type(text)"}}
+type sqlTypeMapping struct {
+	Type string // Go type
+	Orm  string // ORM tag options added to the columns of this type
+}
+
+// warnedTypes are the unknown SQL types already reported
+var warnedTypes = make(map[string]bool)
+
+// goDataType maps an SQL type to a Go type, with the type map of bee.json first and
+// then the type map of the driver. Unknown types fall back to string.
+func goDataType(typeMapping map[string]string, sqlType string) string {
+	if t, ok := conf.Database.Types[sqlType]; ok {
+		return t.Type
+	}
+	if t, ok := typeMapping[sqlType]; ok {
+		return t
+	}
+	if !warnedTypes[sqlType] {
+		ColorLog("[WARN] data type (%s) not found, using string\n", sqlType)
+		ColorLog("[HINT] Map it to a Go type in the database types of bee.json\n")
+		warnedTypes[sqlType] = true
+	}
+	return "string"
+}
+
+// customOrmOptions returns the ORM tag options of an SQL type in the type map of bee.json
+func customOrmOptions(sqlType string) string {
+	if t, ok := conf.Database.Types[sqlType]; ok {
+		return t.Orm
+	}
+	return ""
 }
 
 // Table represent a table in a database
 type Table struct {
 	Name          string
 	Pk            string
+	PkColumns     []string // all columns of the primary key, in key order
 	Uk            []string
 	Fk            map[string]*ForeignKey
+	Indexes       []*Index
 	Columns       []*Column
+	Relations     []*Column // many-to-many and reverse relation fields, they have no column
 	ImportTimePkg bool
+	ImportSqlPkg  bool
+	ViewQuery     string // raw query reading the table when it is a database view, empty otherwise
+	PartialModel  bool   // a model embedding structs of other packages, whose columns are unknown
+}
+
+// Procedure represents a stored procedure, or a function of PostgreSQL
+type Procedure struct {
+	Name   string
+	Query  string    // raw query calling the procedure, with a ? for each parameter
+	Params []*Column // input parameters in order, their Tag.Column is the parameter name
 }
 
 // Column reprsents a column for a table
 type Column struct {
-	Name string
-	Type string
-	Tag  *OrmTag
+	Name       string
+	Type       string
+	SqlType    string // column type as declared in the database, e.g. varchar(255)
+	SqlDefault string // column default as declared in the database
+	RefType    string // Go type of the referenced column, for foreign keys of a join table key
+	Tag        *OrmTag
 }
 
 // ForeignKey represents a foreign key column for a table
 type ForeignKey struct {
-	Name      string
-	RefSchema string
-	RefTable  string
-	RefColumn string
+	Name       string
+	Constraint string
+	RefSchema  string
+	RefTable   string
+	RefColumn  string
+}
+
+// Index represents a unique constraint or a non unique index of a table
+type Index struct {
+	Name    string
+	Unique  bool
+	Columns []string
 }
 
 // OrmTag contains Beego ORM tag information for a column
@@ -177,14 +311,21 @@ type OrmTag struct {
 	RelFk       bool
 	ReverseMany bool
 	RelM2M      bool
+	RelThrough  string   // model of the join table of a many-to-many relation
+	JsonName    string   // json name of a relation field, which has no column
+	Custom      string   // options of the column type in the type map of bee.json
+	Valid       []string // validation functions of the column, e.g. MaxSize(255)
 }
 
 // String returns the source code string for the Table struct
 func (tb *Table) String() string {
-	rv := fmt.Sprintf("type %s struct {\n", camelCase(tb.Name))
+	rv := fmt.Sprintf("type %s struct {\n", modelName(tb.Name))
 	for _, v := range tb.Columns {
 		rv += v.String() + "\n"
 	}
+	for _, v := range tb.Relations {
+		rv += v.String() + "\n"
+	}
 	rv += "}\n"
 	return rv
 }
@@ -237,6 +378,9 @@ func (tag *OrmTag) String() string {
 	if tag.RelM2M {
 		ormOptions = append(ormOptions, "rel(m2m)")
 	}
+	if tag.RelThrough != "" {
+		ormOptions = append(ormOptions, fmt.Sprintf("rel_through(%s)", tag.RelThrough))
+	}
 	if tag.Pk {
 		ormOptions = append(ormOptions, "pk")
 	}
@@ -246,14 +390,25 @@ func (tag *OrmTag) String() string {
 	if tag.Default != "" {
 		ormOptions = append(ormOptions, fmt.Sprintf("default(%s)", tag.Default))
 	}
+	if tag.Custom != "" {
+		ormOptions = append(ormOptions, tag.Custom)
+	}
 
 	if len(ormOptions) == 0 {
 		return ""
 	}
-	return fmt.Sprintf("`orm:\"%s\" json:\"%s\"`", strings.Join(ormOptions, ";"), tag.Column)
+	jsonName := tag.Column
+	if jsonName == "" {
+		// relations are only filled in when joined
+		jsonName = tag.JsonName + ",omitempty"
+	}
+	if len(tag.Valid) > 0 {
+		return fmt.Sprintf("`orm:\"%s\" json:\"%s\" valid:%s`", strings.Join(ormOptions, ";"), jsonName, strconv.Quote(strings.Join(tag.Valid, ";")))
+	}
+	return fmt.Sprintf("`orm:\"%s\" json:\"%s\"`", strings.Join(ormOptions, ";"), jsonName)
 }
 
-func generateAppcode(driver, connStr, level, tables, currpath string) {
+func generateAppcode(driver, connStr, level, tables, nullable, currpath string) {
 	var mode byte
 	switch level {
 	case "1":
@@ -269,6 +424,13 @@ func generateAppcode(driver, connStr, level, tables, currpath string) {
 		ColorLog("[HINT] Level must be either 1, 2 or 3\n")
 		os.Exit(2)
 	}
+	switch nullable {
+	case "pointer", "sql", "zero":
+	default:
+		ColorLog("[ERRO] Invalid 'nullable' option: %s\n", nullable)
+		ColorLog("[HINT] Nullable must be either pointer, sql or zero\n")
+		os.Exit(2)
+	}
 	var selectedTables map[string]bool
 	if tables != "" {
 		selectedTables = make(map[string]bool)
@@ -279,20 +441,18 @@ func generateAppcode(driver, connStr, level, tables, currpath string) {
 	switch driver {
 	case "mysql":
 	case "postgres":
-	case "sqlite":
-		ColorLog("[ERRO] Generating app code from SQLite database is not supported yet.\n")
-		os.Exit(2)
+	case "sqlite3":
 	default:
 		ColorLog("[ERRO] Unknown database driver: %s\n", driver)
 		ColorLog("[HINT] Driver must be one of mysql, postgres or sqlite\n")
 		os.Exit(2)
 	}
-	gen(driver, connStr, mode, selectedTables, currpath)
+	gen(driver, connStr, mode, selectedTables, nullable, currpath)
 }
 
 // Generate takes table, column and foreign key information from database connection
 // and generate corresponding golang source files
-func gen(dbms, connStr string, mode byte, selectedTableNames map[string]bool, currpath string) {
+func gen(dbms, connStr string, mode byte, selectedTableNames map[string]bool, nullable, currpath string) {
 	db, err := sql.Open(dbms, connStr)
 	if err != nil {
 		ColorLog("[ERRO] Could not connect to %s database: %s, %s\n", dbms, connStr, err)
@@ -302,23 +462,41 @@ func gen(dbms, connStr string, mode byte, selectedTableNames map[string]bool, cu
 	if trans, ok := dbDriver[dbms]; ok {
 		ColorLog("[INFO] Analyzing database tables...\n")
 		tableNames := trans.GetTableNames(db)
+		if authMode != "" {
+			tableNames = withoutAuthTables(tableNames)
+		}
 		tables := getTableObjects(tableNames, db, trans)
+		if authMode != "" {
+			useAuthUsers(tables)
+		}
+		views := getTableObjects(trans.GetViewNames(db), db, trans)
+		for _, view := range views {
+			view.ViewQuery = "SELECT * FROM " + sqlDialect{dbms}.quote(view.Name)
+		}
+		tables = append(tables, views...)
+		procedures := trans.GetProcedures(db)
+		setNullableTypes(tables, nullable)
+		setValidation(tables)
 		mvcPath := new(MvcPath)
 		mvcPath.ModelPath = path.Join(currpath, "models")
 		mvcPath.ControllerPath = path.Join(currpath, "controllers")
 		mvcPath.RouterPath = path.Join(currpath, "routers")
 		createPaths(mode, mvcPath)
 		pkgPath := getPackagePath(currpath)
-		writeSourceFiles(pkgPath, tables, mode, mvcPath, selectedTableNames)
+		addRelations(tables, pkgPath)
+		writeSourceFiles(pkgPath, tables, procedures, mode, mvcPath, selectedTableNames)
+		if authMode != "" && (O_CONTROLLER&mode) == O_CONTROLLER {
+			writePolicyFile(tables, selectProcedures(procedures, selectedTableNames), currpath, selectedTableNames)
+		}
 	} else {
 		ColorLog("[ERRO] Generating app code from %s database is not supported yet.\n", dbms)
 		os.Exit(2)
 	}
 }
 
-// getTables gets a list table names in current database
+// getTables gets a list table names in current database, views are left out
 func (*MysqlDB) GetTableNames(db *sql.DB) (tables []string) {
-	rows, err := db.Query("SHOW TABLES")
+	rows, err := db.Query("SHOW FULL TABLES WHERE Table_type = 'BASE TABLE'")
 	if err != nil {
 		ColorLog("[ERRO] Could not show tables\n")
 		ColorLog("[HINT] Check your connection string\n")
@@ -326,18 +504,102 @@ func (*MysqlDB) GetTableNames(db *sql.DB) (tables []string) {
 	}
 	defer rows.Close()
 	for rows.Next() {
-		var name string
-		if err := rows.Scan(&name); err != nil {
+		var name, tableType string
+		if err := rows.Scan(&name, &tableType); err != nil {
 			ColorLog("[ERRO] Could not show tables\n")
 			os.Exit(2)
 		}
-		if name != "migrations" {
+		if !isBeeTable(name) {
 			tables = append(tables, name)
 		}
 	}
 	return
 }
 
+// GetViewNames for MySQL
+func (*MysqlDB) GetViewNames(db *sql.DB) []string {
+	return queryNames(db, `
+		SELECT table_name FROM information_schema.views
+		WHERE table_schema = database() ORDER BY table_name`)
+}
+
+// GetProcedures for MySQL. Procedures with OUT parameters can't be called with a single query
+// and are left out.
+func (mysqlDB *MysqlDB) GetProcedures(db *sql.DB) (procedures []*Procedure) {
+	names := queryNames(db, `
+		SELECT routine_name FROM information_schema.routines
+		WHERE routine_schema = database() AND routine_type = 'PROCEDURE'
+		ORDER BY routine_name`)
+	for _, name := range names {
+		rows, err := db.Query(`
+			SELECT parameter_mode, parameter_name, data_type, dtd_identifier FROM information_schema.parameters
+			WHERE specific_schema = database() AND specific_name = ? AND routine_type = 'PROCEDURE'
+			ORDER BY ordinal_position`, name)
+		if err != nil {
+			ColorLog("[ERRO] Could not query the parameters of procedure %s: %s\n", name, err)
+			os.Exit(2)
+		}
+		proc := &Procedure{Name: name}
+		for rows.Next() {
+			var mode, param, dataType, sqlType string
+			if err := rows.Scan(&mode, &param, &dataType, &sqlType); err != nil {
+				ColorLog("[ERRO] Could not query the parameters of procedure %s: %s\n", name, err)
+				os.Exit(2)
+			}
+			if mode != "IN" {
+				ColorLog("[WARN] procedure %s has %s parameter %s, skip it\n", name, mode, param)
+				proc = nil
+				break
+			}
+			goType := mysqlDB.GetGoDataType(dataType)
+			if strings.Contains(sqlType, "unsigned") && strings.HasPrefix(goType, "int") {
+				goType = "u" + goType
+			}
+			proc.Params = append(proc.Params, procedureParam(param, goType, sqlType))
+		}
+		rows.Close()
+		if proc != nil {
+			proc.Query = "CALL " + sqlDialect{"mysql"}.quote(name) + "(" + placeholders(len(proc.Params)) + ")"
+			procedures = append(procedures, proc)
+		}
+	}
+	return
+}
+
+// queryNames returns the names listed by a query
+func queryNames(db *sql.DB, query string) (names []string) {
+	rows, err := db.Query(query)
+	if err != nil {
+		ColorLog("[ERRO] Could not query %s: %s\n", strings.TrimSpace(query), err)
+		os.Exit(2)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			ColorLog("[ERRO] Could not query %s: %s\n", strings.TrimSpace(query), err)
+			os.Exit(2)
+		}
+		names = append(names, name)
+	}
+	return
+}
+
+// procedureParam returns the column describing a parameter of a stored procedure
+func procedureParam(name, goType, sqlType string) *Column {
+	return &Column{Name: camelCase(name), Type: goType, SqlType: sqlType, Tag: &OrmTag{Column: name}}
+}
+
+// placeholders returns n comma separated ? placeholders
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+// isBeeTable reports whether a table is bookkeeping of bee itself rather than application data
+func isBeeTable(name string) bool {
+	return name == "migrations" || name == MIGRATION_LOCK_TABLE || name == "seeds"
+}
+
 // getTableObjects process each table name
 func getTableObjects(tableNames []string, db *sql.DB, dbTransformer DbTransformer) (tables []*Table) {
 	// if a table has a composite pk or doesn't have pk, we can't use it yet
@@ -356,6 +618,18 @@ func getTableObjects(tableNames []string, db *sql.DB, dbTransformer DbTransforme
 	// process columns, ignoring blacklisted tables
 	for _, tb := range tables {
 		dbTransformer.GetColumns(db, tb, blackList)
+		dbTransformer.GetIndexes(db, tb)
+		// types mapped in bee.json may be time types too
+		for _, col := range tb.Columns {
+			if col.Type == "time.Time" {
+				tb.ImportTimePkg = true
+			}
+		}
+	}
+	for _, tb := range tables {
+		if tb.hasCompositeKey() {
+			tb.setCompositeKey(tables)
+		}
 	}
 	return
 }
@@ -365,28 +639,31 @@ func getTableObjects(tableNames []string, db *sql.DB, dbTransformer DbTransforme
 func (*MysqlDB) GetConstraints(db *sql.DB, table *Table, blackList map[string]bool) {
 	rows, err := db.Query(
 		`SELECT 
-			c.constraint_type, u.column_name, u.referenced_table_schema, u.referenced_table_name, referenced_column_name, u.ordinal_position
+			c.constraint_type, c.constraint_name, u.column_name, u.referenced_table_schema, u.referenced_table_name, referenced_column_name, u.ordinal_position
 		FROM
 			information_schema.table_constraints c 
 		INNER JOIN
 			information_schema.key_column_usage u ON c.constraint_name = u.constraint_name 
 		WHERE
-			c.table_schema = database() AND c.table_name = ? AND u.table_schema = database() AND u.table_name = ?`,
+			c.table_schema = database() AND c.table_name = ? AND u.table_schema = database() AND u.table_name = ?
+		ORDER BY
+			c.constraint_name, u.ordinal_position`,
 		table.Name, table.Name) //  u.position_in_unique_constraint,
 	if err != nil {
 		ColorLog("[ERRO] Could not query INFORMATION_SCHEMA for PK/UK/FK information\n")
 		os.Exit(2)
 	}
 	for rows.Next() {
-		var constraintTypeBytes, columnNameBytes, refTableSchemaBytes, refTableNameBytes, refColumnNameBytes, refOrdinalPosBytes []byte
-		if err := rows.Scan(&constraintTypeBytes, &columnNameBytes, &refTableSchemaBytes, &refTableNameBytes, &refColumnNameBytes, &refOrdinalPosBytes); err != nil {
+		var constraintTypeBytes, constraintNameBytes, columnNameBytes, refTableSchemaBytes, refTableNameBytes, refColumnNameBytes, refOrdinalPosBytes []byte
+		if err := rows.Scan(&constraintTypeBytes, &constraintNameBytes, &columnNameBytes, &refTableSchemaBytes, &refTableNameBytes, &refColumnNameBytes, &refOrdinalPosBytes); err != nil {
 			ColorLog("[ERRO] Could not read INFORMATION_SCHEMA for PK/UK/FK information\n")
 			os.Exit(2)
 		}
-		constraintType, columnName, refTableSchema, refTableName, refColumnName, refOrdinalPos :=
-			string(constraintTypeBytes), string(columnNameBytes), string(refTableSchemaBytes),
+		constraintType, constraintName, columnName, refTableSchema, refTableName, refColumnName, refOrdinalPos :=
+			string(constraintTypeBytes), string(constraintNameBytes), string(columnNameBytes), string(refTableSchemaBytes),
 			string(refTableNameBytes), string(refColumnNameBytes), string(refOrdinalPosBytes)
 		if constraintType == "PRIMARY KEY" {
+			table.addPkColumn(columnName)
 			if refOrdinalPos == "1" {
 				table.Pk = columnName
 			} else {
@@ -397,9 +674,11 @@ func (*MysqlDB) GetConstraints(db *sql.DB, table *Table, blackList map[string]bo
 			}
 		} else if constraintType == "UNIQUE" {
 			table.Uk = append(table.Uk, columnName)
+			table.addIndexColumn(constraintName, true, columnName)
 		} else if constraintType == "FOREIGN KEY" {
 			fk := new(ForeignKey)
 			fk.Name = columnName
+			fk.Constraint = constraintName
 			fk.RefSchema = refTableSchema
 			fk.RefTable = refTableName
 			fk.RefColumn = refColumnName
@@ -418,7 +697,9 @@ func (mysqlDB *MysqlDB) GetColumns(db *sql.DB, table *Table, blackList map[strin
 		FROM
 			information_schema.columns 
 		WHERE
-			table_schema = database() AND table_name = ?`,
+			table_schema = database() AND table_name = ?
+		ORDER BY
+			ordinal_position`,
 		table.Name)
 	defer colDefRows.Close()
 	for colDefRows.Next() {
@@ -434,6 +715,8 @@ func (mysqlDB *MysqlDB) GetColumns(db *sql.DB, table *Table, blackList map[strin
 		col := new(Column)
 		col.Name = camelCase(colName)
 		col.Type = mysqlDB.GetGoDataType(dataType)
+		col.SqlType = columnType
+		col.SqlDefault = columnDefault
 		// Tag info
 		tag := new(OrmTag)
 		tag.Column = colName
@@ -456,7 +739,7 @@ func (mysqlDB *MysqlDB) GetColumns(db *sql.DB, table *Table, blackList map[strin
 				tag.RelFk = true
 				refStructName := fkCol.RefTable
 				col.Name = camelCase(colName)
-				col.Type = "*" + camelCase(refStructName)
+				col.Type = "*" + modelName(refStructName)
 
 				if isNullable == "YES" {
 					tag.Null = true
@@ -475,9 +758,11 @@ func (mysqlDB *MysqlDB) GetColumns(db *sql.DB, table *Table, blackList map[strin
 				if isSQLSignedIntType(dataType) {
 					sign := extractIntSignness(columnType)
 					if sign == "unsigned" && extra != "auto_increment" {
-						col.Type = mysqlDB.GetGoDataType(dataType + " " + sign)
+						dataType += " " + sign
+						col.Type = mysqlDB.GetGoDataType(dataType)
 					}
 				}
+				tag.Custom = customOrmOptions(dataType)
 				if isSQLStringType(dataType) {
 					tag.Size = extractColSize(columnType)
 				}
@@ -511,20 +796,41 @@ func (mysqlDB *MysqlDB) GetColumns(db *sql.DB, table *Table, blackList map[strin
 
 // getGoDataType maps an SQL data type to Golang data type
 func (*MysqlDB) GetGoDataType(sqlType string) (goType string) {
-	if v, ok := typeMappingMysql[sqlType]; ok {
-		return v
-	} else {
-		ColorLog("[ERRO] data type (%s) not found!\n", sqlType)
+	return goDataType(typeMappingMysql, sqlType)
+}
+
+// GetIndexes retrieves the non unique indexes of a table from information_schema
+func (*MysqlDB) GetIndexes(db *sql.DB, table *Table) {
+	rows, err := db.Query(
+		`SELECT
+			index_name, column_name
+		FROM
+			information_schema.statistics
+		WHERE
+			table_schema = database() AND table_name = ? AND non_unique = 1
+		ORDER BY
+			index_name, seq_in_index`,
+		table.Name)
+	if err != nil {
+		ColorLog("[ERRO] Could not query INFORMATION_SCHEMA for index information: %s\n", err)
 		os.Exit(2)
 	}
-	return goType
+	defer rows.Close()
+	for rows.Next() {
+		var indexName, columnName string
+		if err := rows.Scan(&indexName, &columnName); err != nil {
+			ColorLog("[ERRO] Could not read INFORMATION_SCHEMA for index information\n")
+			os.Exit(2)
+		}
+		table.addIndexColumn(indexName, false, columnName)
+	}
 }
 
 // GetTableNames for PostgreSQL
 func (*PostgresDB) GetTableNames(db *sql.DB) (tables []string) {
 	rows, err := db.Query(`
 		SELECT table_name FROM information_schema.tables
-		WHERE table_catalog = current_database() and table_schema = 'public'`)
+		WHERE table_catalog = current_database() and table_schema = 'public' and table_type = 'BASE TABLE'`)
 	if err != nil {
 		ColorLog("[ERRO] Could not show tables: %s\n", err)
 		ColorLog("[HINT] Check your connection string\n")
@@ -537,16 +843,106 @@ func (*PostgresDB) GetTableNames(db *sql.DB) (tables []string) {
 			ColorLog("[ERRO] Could not show tables\n")
 			os.Exit(2)
 		}
-		tables = append(tables, name)
+		if !isBeeTable(name) {
+			tables = append(tables, name)
+		}
+	}
+	return
+}
+
+// GetViewNames for PostgreSQL
+func (*PostgresDB) GetViewNames(db *sql.DB) []string {
+	return queryNames(db, `
+		SELECT table_name FROM information_schema.views
+		WHERE table_catalog = current_database() AND table_schema = 'public' ORDER BY table_name`)
+}
+
+// GetProcedures for PostgreSQL. Functions are called with SELECT * FROM, which returns the rows
+// of set returning functions and a single row otherwise. Trigger functions, overloads and
+// procedures with OUT parameters are left out.
+func (postgresDB *PostgresDB) GetProcedures(db *sql.DB) (procedures []*Procedure) {
+	rows, err := db.Query(`
+		SELECT specific_name, routine_name, routine_type FROM information_schema.routines
+		WHERE routine_schema = 'public' AND routine_type IN ('FUNCTION', 'PROCEDURE')
+			AND COALESCE(data_type, '') <> 'trigger'
+		ORDER BY routine_name, specific_name`)
+	if err != nil {
+		ColorLog("[ERRO] Could not query the stored procedures: %s\n", err)
+		os.Exit(2)
+	}
+	type routine struct{ specificName, name, routineType string }
+	var routines []routine
+	for rows.Next() {
+		var r routine
+		if err := rows.Scan(&r.specificName, &r.name, &r.routineType); err != nil {
+			ColorLog("[ERRO] Could not query the stored procedures: %s\n", err)
+			os.Exit(2)
+		}
+		routines = append(routines, r)
+	}
+	rows.Close()
+
+	for i, r := range routines {
+		if i > 0 && routines[i-1].name == r.name {
+			ColorLog("[WARN] %s is overloaded, only its first version is generated\n", r.name)
+			continue
+		}
+		proc := postgresDB.getProcedure(db, r.specificName, r.name, r.routineType)
+		if proc != nil {
+			procedures = append(procedures, proc)
+		}
 	}
 	return
 }
 
+// getProcedure reads the parameters of a PostgreSQL routine
+func (postgresDB *PostgresDB) getProcedure(db *sql.DB, specificName, name, routineType string) *Procedure {
+	rows, err := db.Query(`
+		SELECT parameter_mode, COALESCE(parameter_name, ''), data_type, udt_name FROM information_schema.parameters
+		WHERE specific_schema = 'public' AND specific_name = $1
+		ORDER BY ordinal_position`, specificName)
+	if err != nil {
+		ColorLog("[ERRO] Could not query the parameters of %s: %s\n", name, err)
+		os.Exit(2)
+	}
+	defer rows.Close()
+	proc := &Procedure{Name: name}
+	for rows.Next() {
+		var mode, param, dataType, udtName string
+		if err := rows.Scan(&mode, &param, &dataType, &udtName); err != nil {
+			ColorLog("[ERRO] Could not query the parameters of %s: %s\n", name, err)
+			os.Exit(2)
+		}
+		if mode == "OUT" {
+			// OUT parameters of functions are columns of their result
+			if routineType == "PROCEDURE" {
+				ColorLog("[WARN] procedure %s has OUT parameter %s, skip it\n", name, param)
+				return nil
+			}
+			continue
+		}
+		if param == "" {
+			param = fmt.Sprintf("arg%d", len(proc.Params)+1)
+		}
+		if _, ok := conf.Database.Types[udtName]; ok && (dataType == "USER-DEFINED" || dataType == "ARRAY") {
+			dataType = udtName
+		}
+		proc.Params = append(proc.Params, procedureParam(param, postgresDB.GetGoDataType(dataType), dataType))
+	}
+	call := "SELECT * FROM "
+	if routineType == "PROCEDURE" {
+		call = "CALL "
+	}
+	proc.Query = call + sqlDialect{"postgres"}.quote(name) + "(" + placeholders(len(proc.Params)) + ")"
+	return proc
+}
+
 // GetConstraints for PostgreSQL
 func (*PostgresDB) GetConstraints(db *sql.DB, table *Table, blackList map[string]bool) {
 	rows, err := db.Query(
 		`SELECT 
 			c.constraint_type,
+			c.constraint_name,
 			u.column_name,
 			cu.table_catalog AS referenced_table_catalog,
 			cu.table_name AS referenced_table_name,
@@ -560,22 +956,25 @@ func (*PostgresDB) GetConstraints(db *sql.DB, table *Table, blackList map[string
 			information_schema.constraint_column_usage cu ON cu.constraint_name =  c.constraint_name
 		WHERE
 			c.table_catalog = current_database() AND c.table_schema = 'public' AND c.table_name = $1 
-			AND u.table_catalog = current_database() AND u.table_schema = 'public' AND u.table_name = $2`,
+			AND u.table_catalog = current_database() AND u.table_schema = 'public' AND u.table_name = $2
+		ORDER BY
+			c.constraint_name, u.ordinal_position`,
 		table.Name, table.Name) //  u.position_in_unique_constraint,
 	if err != nil {
 		ColorLog("[ERRO] Could not query INFORMATION_SCHEMA for PK/UK/FK information: %s\n", err)
 		os.Exit(2)
 	}
 	for rows.Next() {
-		var constraintTypeBytes, columnNameBytes, refTableSchemaBytes, refTableNameBytes, refColumnNameBytes, refOrdinalPosBytes []byte
-		if err := rows.Scan(&constraintTypeBytes, &columnNameBytes, &refTableSchemaBytes, &refTableNameBytes, &refColumnNameBytes, &refOrdinalPosBytes); err != nil {
+		var constraintTypeBytes, constraintNameBytes, columnNameBytes, refTableSchemaBytes, refTableNameBytes, refColumnNameBytes, refOrdinalPosBytes []byte
+		if err := rows.Scan(&constraintTypeBytes, &constraintNameBytes, &columnNameBytes, &refTableSchemaBytes, &refTableNameBytes, &refColumnNameBytes, &refOrdinalPosBytes); err != nil {
 			ColorLog("[ERRO] Could not read INFORMATION_SCHEMA for PK/UK/FK information\n")
 			os.Exit(2)
 		}
-		constraintType, columnName, refTableSchema, refTableName, refColumnName, refOrdinalPos :=
-			string(constraintTypeBytes), string(columnNameBytes), string(refTableSchemaBytes),
+		constraintType, constraintName, columnName, refTableSchema, refTableName, refColumnName, refOrdinalPos :=
+			string(constraintTypeBytes), string(constraintNameBytes), string(columnNameBytes), string(refTableSchemaBytes),
 			string(refTableNameBytes), string(refColumnNameBytes), string(refOrdinalPosBytes)
 		if constraintType == "PRIMARY KEY" {
+			table.addPkColumn(columnName)
 			if refOrdinalPos == "1" {
 				table.Pk = columnName
 			} else {
@@ -586,9 +985,11 @@ func (*PostgresDB) GetConstraints(db *sql.DB, table *Table, blackList map[string
 			}
 		} else if constraintType == "UNIQUE" {
 			table.Uk = append(table.Uk, columnName)
+			table.addIndexColumn(constraintName, true, columnName)
 		} else if constraintType == "FOREIGN KEY" {
 			fk := new(ForeignKey)
 			fk.Name = columnName
+			fk.Constraint = constraintName
 			fk.RefSchema = refTableSchema
 			fk.RefTable = refTableName
 			fk.RefColumn = refColumnName
@@ -607,38 +1008,49 @@ func (postgresDB *PostgresDB) GetColumns(db *sql.DB, table *Table, blackList map
 			data_type ||
 			CASE
 				WHEN data_type = 'character' THEN '('||character_maximum_length||')'
+				WHEN data_type = 'character varying' AND character_maximum_length IS NOT NULL THEN '('||character_maximum_length||')'
 				WHEN data_type = 'numeric' THEN '(' || numeric_precision || ',' || numeric_scale ||')'
 				ELSE ''
 			END AS column_type,
 			is_nullable,
 			column_default,
-			'' AS extra
+			'' AS extra,
+			udt_name
 		FROM
 			information_schema.columns 
 		WHERE
-			table_catalog = current_database() AND table_schema = 'public' AND table_name = $1`,
+			table_catalog = current_database() AND table_schema = 'public' AND table_name = $1
+		ORDER BY
+			ordinal_position`,
 		table.Name)
 	defer colDefRows.Close()
 	for colDefRows.Next() {
 		// datatype as bytes so that SQL <null> values can be retrieved
-		var colNameBytes, dataTypeBytes, columnTypeBytes, isNullableBytes, columnDefaultBytes, extraBytes []byte
-		if err := colDefRows.Scan(&colNameBytes, &dataTypeBytes, &columnTypeBytes, &isNullableBytes, &columnDefaultBytes, &extraBytes); err != nil {
+		var colNameBytes, dataTypeBytes, columnTypeBytes, isNullableBytes, columnDefaultBytes, extraBytes, udtNameBytes []byte
+		if err := colDefRows.Scan(&colNameBytes, &dataTypeBytes, &columnTypeBytes, &isNullableBytes, &columnDefaultBytes, &extraBytes, &udtNameBytes); err != nil {
 			ColorLog("[ERRO] Could not query INFORMATION_SCHEMA for column information\n")
 			os.Exit(2)
 		}
 		colName, dataType, columnType, isNullable, columnDefault, extra :=
 			string(colNameBytes), string(dataTypeBytes), string(columnTypeBytes), string(isNullableBytes), string(columnDefaultBytes), string(extraBytes)
+		// enums, domains and arrays are mapped by their own name in bee.json, e.g. citext or _int4
+		if _, ok := conf.Database.Types[string(udtNameBytes)]; ok && (dataType == "USER-DEFINED" || dataType == "ARRAY") {
+			dataType = string(udtNameBytes)
+		}
 		// create a column
 		col := new(Column)
 		col.Name = camelCase(colName)
 		col.Type = postgresDB.GetGoDataType(dataType)
+		col.SqlType = columnType
+		col.SqlDefault = columnDefault
 		// Tag info
 		tag := new(OrmTag)
 		tag.Column = colName
 		if table.Pk == colName {
 			col.Name = "Id"
 			col.Type = "int"
-			if extra == "auto_increment" {
+			// serial columns take their default from a sequence
+			if extra == "auto_increment" || strings.HasPrefix(columnDefault, "nextval(") {
 				tag.Auto = true
 			} else {
 				tag.Pk = true
@@ -654,7 +1066,11 @@ func (postgresDB *PostgresDB) GetColumns(db *sql.DB, table *Table, blackList map
 				tag.RelFk = true
 				refStructName := fkCol.RefTable
 				col.Name = camelCase(colName)
-				col.Type = "*" + camelCase(refStructName)
+				col.Type = "*" + modelName(refStructName)
+
+				if isNullable == "YES" {
+					tag.Null = true
+				}
 			} else {
 				// if the name of column is Id, and it's not primary key
 				if colName == "id" {
@@ -686,6 +1102,7 @@ func (postgresDB *PostgresDB) GetColumns(db *sql.DB, table *Table, blackList map
 				if isSQLStrangeType(dataType) {
 					tag.Type = dataType
 				}
+				tag.Custom = customOrmOptions(dataType)
 			}
 		}
 		col.Tag = tag
@@ -693,159 +1110,807 @@ func (postgresDB *PostgresDB) GetColumns(db *sql.DB, table *Table, blackList map
 	}
 }
 func (*PostgresDB) GetGoDataType(sqlType string) (goType string) {
-	if v, ok := typeMappingPostgres[sqlType]; ok {
-		return v
-	} else {
-		ColorLog("[ERRO] data type (%s) not found!\n", sqlType)
-		os.Exit(2)
-	}
-	return goType
+	return goDataType(typeMappingPostgres, sqlType)
 }
 
-// deleteAndRecreatePaths removes several directories completely
-func createPaths(mode byte, paths *MvcPath) {
-	if (mode & O_MODEL) == O_MODEL {
-		os.Mkdir(paths.ModelPath, 0777)
-	}
-	if (mode & O_CONTROLLER) == O_CONTROLLER {
-		os.Mkdir(paths.ControllerPath, 0777)
+// GetIndexes for PostgreSQL
+func (*PostgresDB) GetIndexes(db *sql.DB, table *Table) {
+	rows, err := db.Query(
+		`SELECT
+			i.relname AS index_name,
+			a.attname AS column_name
+		FROM
+			pg_index x
+		INNER JOIN
+			pg_class t ON t.oid = x.indrelid
+		INNER JOIN
+			pg_class i ON i.oid = x.indexrelid
+		INNER JOIN
+			pg_namespace n ON n.oid = t.relnamespace
+		INNER JOIN
+			pg_attribute a ON a.attrelid = t.oid AND a.attnum = ANY(x.indkey)
+		WHERE
+			n.nspname = 'public' AND t.relname = $1 AND NOT x.indisunique AND NOT x.indisprimary
+		ORDER BY
+			i.relname, array_position(x.indkey::int2[], a.attnum)`,
+		table.Name)
+	if err != nil {
+		ColorLog("[ERRO] Could not query pg_index for index information: %s\n", err)
+		os.Exit(2)
 	}
-	if (mode & O_ROUTER) == O_ROUTER {
-		os.Mkdir(paths.RouterPath, 0777)
+	defer rows.Close()
+	for rows.Next() {
+		var indexName, columnName string
+		if err := rows.Scan(&indexName, &columnName); err != nil {
+			ColorLog("[ERRO] Could not read pg_index for index information\n")
+			os.Exit(2)
+		}
+		table.addIndexColumn(indexName, false, columnName)
 	}
 }
 
-// writeSourceFiles generates source files for model/controller/router
-// It will wipe the following directories and recreate them:./models, ./controllers, ./routers
-// Newly geneated files will be inside these folders.
-func writeSourceFiles(pkgPath string, tables []*Table, mode byte, paths *MvcPath, selectedTables map[string]bool) {
-	if (O_MODEL & mode) == O_MODEL {
-		ColorLog("[INFO] Creating model files...\n")
-		writeModelFiles(tables, paths.ModelPath, selectedTables, pkgPath)
-	}
-	if (O_CONTROLLER & mode) == O_CONTROLLER {
-		ColorLog("[INFO] Creating controller files...\n")
-		writeControllerFiles(tables, paths.ControllerPath, selectedTables, pkgPath)
+// GetTableNames for SQLite
+func (*SqliteDB) GetTableNames(db *sql.DB) (tables []string) {
+	rows, err := db.Query("SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%' ORDER BY name")
+	if err != nil {
+		ColorLog("[ERRO] Could not show tables: %s\n", err)
+		ColorLog("[HINT] Check your connection string\n")
+		os.Exit(2)
 	}
-	if (O_ROUTER & mode) == O_ROUTER {
-		ColorLog("[INFO] Creating router files...\n")
-		writeRouterFile(tables, paths.RouterPath, selectedTables, pkgPath)
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			ColorLog("[ERRO] Could not show tables\n")
+			os.Exit(2)
+		}
+		if !isBeeTable(name) {
+			tables = append(tables, name)
+		}
 	}
+	return
 }
 
-// writeModelFiles generates model files
-func writeModelFiles(tables []*Table, mPath string, selectedTables map[string]bool, pkgPath string) {
-	for _, tb := range tables {
-		// if selectedTables map is not nil and this table is not selected, ignore it
-		if selectedTables != nil {
-			if _, selected := selectedTables[tb.Name]; !selected {
-				continue
-			}
-		}
-		filename := getFileName(tb.Name)
-		fpath := path.Join(mPath, filename+".go")
-		var f *os.File
-		var err error
-		if isExist(fpath) {
-			ColorLog("[WARN] %v is exist, do you want to overwrite it? Yes or No?\n", fpath)
-			if askForConfirmation() {
-				f, err = os.OpenFile(fpath, os.O_RDWR|os.O_TRUNC, 0666)
-				if err != nil {
-					ColorLog("[WARN] %v\n", err)
-					continue
-				}
-			} else {
-				ColorLog("[WARN] skip create file\n")
-				continue
-			}
-		} else {
-			f, err = os.OpenFile(fpath, os.O_CREATE|os.O_RDWR, 0666)
-			if err != nil {
-				ColorLog("[WARN] %v\n", err)
-				continue
-			}
-		}
-		template := ""
-		if tb.Pk == "" {
-			template = STRUCT_MODEL_TPL
-		} else {
-			template = MODEL_TPL
-		}
-		fileStr := strings.Replace(template, "{{modelStruct}}", tb.String(), 1)
-		fileStr = strings.Replace(fileStr, "{{modelName}}", camelCase(tb.Name), -1)
-		fileStr = strings.Replace(fileStr, "{{tableName}}", tb.Name, -1)
-		// if table contains time field, import time.Time package
-		timePkg := ""
-		importTimePkg := ""
+// GetViewNames for SQLite
+func (*SqliteDB) GetViewNames(db *sql.DB) []string {
+	return queryNames(db, "SELECT name FROM sqlite_master WHERE type = 'view' ORDER BY name")
+}
 
-		if tb.ImportTimePkg {
-			timePkg = "\"time\"\n"
-			importTimePkg = "import \"time\"\n"
-		}
+// GetProcedures for SQLite, which has no stored procedures
+func (*SqliteDB) GetProcedures(db *sql.DB) []*Procedure {
+	return nil
+}
 
-		fileStr = strings.Replace(fileStr, "{{pkgPath}}", pkgPath, -1)
-		fileStr = strings.Replace(fileStr, "{{timePkg}}", timePkg, -1)
-		fileStr = strings.Replace(fileStr, "{{importTimePkg}}", importTimePkg, -1)
+// sqliteColumn is a row of PRAGMA table_info
+type sqliteColumn struct {
+	name       string
+	sqlType    string
+	notNull    bool
+	defaultVal string
+	pk         int // position in the primary key, 0 if the column is not part of it
+}
 
-		if _, err := f.WriteString(fileStr); err != nil {
-			ColorLog("[ERRO] Could not write model file to %s\n", fpath)
+// sqliteTableInfo reads the columns of a table with PRAGMA table_info
+func sqliteTableInfo(db *sql.DB, table string) (columns []sqliteColumn) {
+	rows, err := db.Query("PRAGMA table_info(" + quoteSqliteName(table) + ")")
+	if err != nil {
+		ColorLog("[ERRO] Could not query table_info of %s: %s\n", table, err)
+		os.Exit(2)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var cid, notNull, pk int
+		var name, sqlType string
+		var defaultVal sql.NullString
+		if err := rows.Scan(&cid, &name, &sqlType, &notNull, &defaultVal, &pk); err != nil {
+			ColorLog("[ERRO] Could not read table_info of %s: %s\n", table, err)
 			os.Exit(2)
 		}
-		f.Close()
-		ColorLog("[INFO] model => %s\n", fpath)
-		formatSourceCode(fpath)
+		columns = append(columns, sqliteColumn{name, sqlType, notNull == 1, defaultVal.String, pk})
 	}
+	return
 }
 
-// writeControllerFiles generates controller files
-func writeControllerFiles(tables []*Table, cPath string, selectedTables map[string]bool, pkgPath string) {
-	for _, tb := range tables {
-		// if selectedTables map is not nil and this table is not selected, ignore it
-		if selectedTables != nil {
-			if _, selected := selectedTables[tb.Name]; !selected {
-				continue
+// sqliteIndexes reads the indexes of a table with PRAGMA index_list and PRAGMA index_info.
+// Indexes backing the primary key are left out.
+func sqliteIndexes(db *sql.DB, table string) (indexes []*Index) {
+	rows, err := db.Query("PRAGMA index_list(" + quoteSqliteName(table) + ")")
+	if err != nil {
+		ColorLog("[ERRO] Could not query index_list of %s: %s\n", table, err)
+		os.Exit(2)
+	}
+	for rows.Next() {
+		var seq, unique, partial int
+		var name, origin string
+		if err := rows.Scan(&seq, &name, &unique, &origin, &partial); err != nil {
+			ColorLog("[ERRO] Could not read index_list of %s: %s\n", table, err)
+			os.Exit(2)
+		}
+		if origin != "pk" {
+			indexes = append(indexes, &Index{Name: name, Unique: unique == 1})
+		}
+	}
+	rows.Close()
+	sort.Sort(indexesByName(indexes))
+	for _, idx := range indexes {
+		rows, err := db.Query("PRAGMA index_info(" + quoteSqliteName(idx.Name) + ")")
+		if err != nil {
+			ColorLog("[ERRO] Could not query index_info of %s: %s\n", idx.Name, err)
+			os.Exit(2)
+		}
+		for rows.Next() {
+			var seqno, cid int
+			var column string
+			if err := rows.Scan(&seqno, &cid, &column); err != nil {
+				ColorLog("[ERRO] Could not read index_info of %s: %s\n", idx.Name, err)
+				os.Exit(2)
 			}
+			idx.Columns = append(idx.Columns, column)
 		}
-		if tb.Pk == "" {
+		rows.Close()
+	}
+	return
+}
+
+// GetConstraints for SQLite
+func (*SqliteDB) GetConstraints(db *sql.DB, table *Table, blackList map[string]bool) {
+	columns := sqliteTableInfo(db, table.Name)
+	sort.Sort(sqliteColumnsByPk(columns))
+	for _, col := range columns {
+		if col.pk == 0 {
 			continue
 		}
-		filename := getFileName(tb.Name)
-		fpath := path.Join(cPath, filename+".go")
-		var f *os.File
-		var err error
-		if isExist(fpath) {
-			ColorLog("[WARN] %v is exist, do you want to overwrite it? Yes or No?\n", fpath)
-			if askForConfirmation() {
-				f, err = os.OpenFile(fpath, os.O_RDWR|os.O_TRUNC, 0666)
-				if err != nil {
-					ColorLog("[WARN] %v\n", err)
-					continue
-				}
-			} else {
-				ColorLog("[WARN] skip create file\n")
-				continue
-			}
+		table.addPkColumn(col.name)
+		if col.pk == 1 {
+			table.Pk = col.name
 		} else {
-			f, err = os.OpenFile(fpath, os.O_CREATE|os.O_RDWR, 0666)
-			if err != nil {
-				ColorLog("[WARN] %v\n", err)
-				continue
-			}
+			table.Pk = ""
+			// add table to blacklist so that other struct will not reference it, because we are not
+			// registering blacklisted tables
+			blackList[table.Name] = true
 		}
-		fileStr := strings.Replace(CTRL_TPL, "{{ctrlName}}", camelCase(tb.Name), -1)
-		fileStr = strings.Replace(fileStr, "{{pkgPath}}", pkgPath, -1)
-		if _, err := f.WriteString(fileStr); err != nil {
-			ColorLog("[ERRO] Could not write controller file to %s\n", fpath)
-			os.Exit(2)
+	}
+	for _, idx := range sqliteIndexes(db, table.Name) {
+		if !idx.Unique {
+			continue
+		}
+		for _, column := range idx.Columns {
+			table.Uk = append(table.Uk, column)
+			table.addIndexColumn(idx.Name, true, column)
 		}
-		f.Close()
-		ColorLog("[INFO] controller => %s\n", fpath)
-		formatSourceCode(fpath)
 	}
-}
+
+	rows, err := db.Query("PRAGMA foreign_key_list(" + quoteSqliteName(table.Name) + ")")
+	if err != nil {
+		ColorLog("[ERRO] Could not query foreign_key_list of %s: %s\n", table.Name, err)
+		os.Exit(2)
+	}
+	defer rows.Close()
+	// SQLite foreign keys are unnamed, name them after their first column the way bee names the ones it creates
+	constraints := make(map[int]string)
+	for rows.Next() {
+		var id, seq int
+		var refTable, from, onUpdate, onDelete, match string
+		var to sql.NullString
+		if err := rows.Scan(&id, &seq, &refTable, &from, &to, &onUpdate, &onDelete, &match); err != nil {
+			ColorLog("[ERRO] Could not read foreign_key_list of %s: %s\n", table.Name, err)
+			os.Exit(2)
+		}
+		fk := new(ForeignKey)
+		if _, ok := constraints[id]; !ok {
+			constraints[id] = indexName("fk", table.Name, from)
+		}
+		fk.Name = from
+		fk.Constraint = constraints[id]
+		fk.RefTable = refTable
+		fk.RefColumn = to.String
+		if fk.RefColumn == "" {
+			// a foreign key without column references the primary key
+			for _, col := range sqliteTableInfo(db, refTable) {
+				if col.pk == 1 {
+					fk.RefColumn = col.name
+				}
+			}
+		}
+		table.Fk[from] = fk
+	}
+}
+
+// GetColumns for SQLite
+func (sqliteDB *SqliteDB) GetColumns(db *sql.DB, table *Table, blackList map[string]bool) {
+	for _, c := range sqliteTableInfo(db, table.Name) {
+		columnType := strings.ToLower(c.sqlType)
+		dataType := columnType
+		if i := strings.Index(dataType, "("); i >= 0 {
+			dataType = strings.TrimSpace(dataType[:i])
+		}
+		// create a column
+		col := new(Column)
+		col.Name = camelCase(c.name)
+		col.Type = sqliteDB.GetGoDataType(dataType)
+		col.SqlType = columnType
+		col.SqlDefault = c.defaultVal
+		// Tag info
+		tag := new(OrmTag)
+		tag.Column = c.name
+		if table.Pk == c.name {
+			col.Name = "Id"
+			col.Type = "int"
+			// an INTEGER PRIMARY KEY column is an alias of the rowid
+			if dataType == "integer" {
+				tag.Auto = true
+			} else {
+				tag.Pk = true
+			}
+		} else {
+			fkCol, isFk := table.Fk[c.name]
+			isBl := false
+			if isFk {
+				_, isBl = blackList[fkCol.RefTable]
+			}
+			// check if the current column is a foreign key
+			if isFk && !isBl {
+				tag.RelFk = true
+				refStructName := fkCol.RefTable
+				col.Name = camelCase(c.name)
+				col.Type = "*" + modelName(refStructName)
+
+				if !c.notNull {
+					tag.Null = true
+				}
+			} else {
+				// if the name of column is Id, and it's not primary key
+				if c.name == "id" {
+					col.Name = "Id_RENAME"
+				}
+				if !c.notNull {
+					tag.Null = true
+				}
+				if isSQLStringType(dataType) && strings.Contains(columnType, "(") {
+					tag.Size = extractColSize(columnType)
+				}
+				if isSQLTemporalType(dataType) {
+					tag.Type = dataType
+					if strings.ToUpper(c.defaultVal) == "CURRENT_TIMESTAMP" {
+						tag.AutoNowAdd = true
+					}
+					// need to import time package
+					table.ImportTimePkg = true
+				}
+				if isSQLDecimal(dataType) && strings.Contains(columnType, ",") {
+					tag.Digits, tag.Decimals = extractDecimal(columnType)
+				}
+				tag.Custom = customOrmOptions(dataType)
+			}
+		}
+		col.Tag = tag
+		table.Columns = append(table.Columns, col)
+	}
+}
+
+// GetGoDataType for SQLite. Column types are free-form in SQLite, types missing from
+// bee.json and typeMappingSqlite are mapped following the SQLite type affinity rules.
+func (*SqliteDB) GetGoDataType(sqlType string) (goType string) {
+	if t, ok := conf.Database.Types[sqlType]; ok {
+		return t.Type
+	}
+	if v, ok := typeMappingSqlite[sqlType]; ok {
+		return v
+	}
+	switch {
+	case strings.Contains(sqlType, "int"):
+		return "int64"
+	case strings.Contains(sqlType, "char"), strings.Contains(sqlType, "clob"), strings.Contains(sqlType, "text"):
+		return "string"
+	case sqlType == "", strings.Contains(sqlType, "blob"):
+		return "string"
+	case strings.Contains(sqlType, "real"), strings.Contains(sqlType, "floa"), strings.Contains(sqlType, "doub"):
+		return "float64"
+	}
+	return "float64" // numeric affinity
+}
+
+// GetIndexes for SQLite
+func (*SqliteDB) GetIndexes(db *sql.DB, table *Table) {
+	for _, idx := range sqliteIndexes(db, table.Name) {
+		if !idx.Unique {
+			table.Indexes = append(table.Indexes, idx)
+		}
+	}
+}
+
+// quoteSqliteName quotes an identifier for use in a PRAGMA statement
+func quoteSqliteName(name string) string {
+	return `"` + strings.Replace(name, `"`, `""`, -1) + `"`
+}
+
+type sqliteColumnsByPk []sqliteColumn
+
+func (s sqliteColumnsByPk) Len() int           { return len(s) }
+func (s sqliteColumnsByPk) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s sqliteColumnsByPk) Less(i, j int) bool { return s[i].pk < s[j].pk }
+
+// hasCompositeKey reports whether the primary key of the table has more than one column
+func (tb *Table) hasCompositeKey() bool {
+	return len(tb.PkColumns) > 1
+}
+
+// setCompositeKey prepares the columns of a composite primary key. The ORM only supports single
+// column keys, so the model is registered with the first key column as pk and the generated
+// functions filter on every key column. Key columns are kept as plain values, except the foreign
+// keys of a join table which the ORM needs as relations.
+func (tb *Table) setCompositeKey(tables []*Table) {
+	joinTable := tb.isJoinTable(tables)
+	for _, col := range tb.Columns {
+		i := indexOf(tb.PkColumns, col.Tag.Column)
+		if i < 0 {
+			continue
+		}
+		if col.Tag.RelFk {
+			col.RefType = refColumnType(tables, tb.Fk[col.Tag.Column])
+			if !joinTable {
+				col.Tag.RelFk = false
+				col.Name = camelCase(col.Tag.Column)
+				col.Type = col.RefType
+			}
+		}
+		col.Tag.Null = false
+		col.Tag.Pk = i == 0
+	}
+}
+
+// isJoinTable reports whether the table joins two other tables: its primary key is made of
+// two foreign keys, each referencing the single column primary key of a different table.
+func (tb *Table) isJoinTable(tables []*Table) bool {
+	if len(tb.PkColumns) != 2 {
+		return false
+	}
+	var refs []string
+	for _, column := range tb.PkColumns {
+		fk, ok := tb.Fk[column]
+		if !ok {
+			return false
+		}
+		for _, other := range tb.Fk {
+			if other.Constraint == fk.Constraint && other.Name != fk.Name {
+				return false
+			}
+		}
+		ref := findTable(tables, fk.RefTable)
+		if ref == nil || ref.Pk == "" || ref.Pk != fk.RefColumn {
+			return false
+		}
+		refs = append(refs, fk.RefTable)
+	}
+	return refs[0] != refs[1]
+}
+
+// sqlNullTypes maps Go types to the database/sql types of nullable columns
+var sqlNullTypes = map[string]string{
+	"string":    "sql.NullString",
+	"int":       "sql.NullInt64",
+	"int8":      "sql.NullInt64",
+	"int16":     "sql.NullInt64",
+	"int32":     "sql.NullInt64",
+	"int64":     "sql.NullInt64",
+	"uint":      "sql.NullInt64",
+	"uint8":     "sql.NullInt64",
+	"uint16":    "sql.NullInt64",
+	"uint32":    "sql.NullInt64",
+	"float32":   "sql.NullFloat64",
+	"float64":   "sql.NullFloat64",
+	"bool":      "sql.NullBool",
+	"time.Time": "sql.NullTime",
+}
+
+// setNullableTypes gives the nullable columns a Go type telling NULL apart from the zero value:
+// a pointer with nullable "pointer", a sql.NullX type with nullable "sql". Foreign keys are
+// pointers already, byte slices are nil for NULL.
+func setNullableTypes(tables []*Table, nullable string) {
+	if nullable == "zero" {
+		return
+	}
+	for _, tb := range tables {
+		for _, col := range tb.Columns {
+			if !col.Tag.Null || strings.HasPrefix(col.Type, "*") || strings.HasPrefix(col.Type, "[]") {
+				continue
+			}
+			if nullable == "pointer" {
+				col.Type = "*" + col.Type
+			} else if t, ok := sqlNullTypes[col.Type]; ok {
+				col.Type = t
+				tb.ImportSqlPkg = true
+			} else {
+				ColorLog("[WARN] %s.%s: no sql.Null type for %s, keep it\n", tb.Name, col.Tag.Column, col.Type)
+			}
+		}
+		// the time package may not be used any more
+		tb.ImportTimePkg = false
+		for _, col := range tb.Columns {
+			if strings.HasSuffix(col.Type, "time.Time") {
+				tb.ImportTimePkg = true
+			}
+		}
+	}
+}
+
+// sqlIntRanges are the ranges of the integer types narrower than their Go types
+var sqlIntRanges = map[string][2]int64{
+	"tinyint":            {-128, 127},
+	"tinyint unsigned":   {0, 255},
+	"smallint":           {-32768, 32767},
+	"smallint unsigned":  {0, 65535},
+	"mediumint":          {-8388608, 8388607},
+	"mediumint unsigned": {0, 16777215},
+	"int unsigned":       {0, 4294967295},
+	"integer unsigned":   {0, 4294967295},
+}
+
+// setValidation derives the validation functions of the columns from the schema: Required for
+// NOT NULL columns without default, MaxSize from the column size, Range from the integer type
+// and Match from the values of enum and set columns. Numbers and booleans are never Required as
+// their zero value can't be told apart from a missing one, nullable pointers and sql.NullX
+// values aren't checked.
+func setValidation(tables []*Table) {
+	for _, tb := range tables {
+		// views are read only
+		if tb.ViewQuery != "" {
+			continue
+		}
+		for _, col := range tb.Columns {
+			tag := col.Tag
+			if tag.Auto || tag.RelFk || tag.RelOne || strings.HasPrefix(col.Type, "*") || strings.HasPrefix(col.Type, "sql.") {
+				continue
+			}
+			if !tag.Null && col.SqlDefault == "" && (col.Type == "string" || col.Type == "time.Time") {
+				tag.Valid = append(tag.Valid, "Required")
+			}
+			if tag.Size != "" && col.Type == "string" {
+				tag.Valid = append(tag.Valid, "MaxSize("+tag.Size+")")
+			}
+			if r, ok := sqlIntRanges[sqlBaseType(col.SqlType)]; ok && strings.Contains(col.Type, "int") {
+				tag.Valid = append(tag.Valid, fmt.Sprintf("Range(%d, %d)", r[0], r[1]))
+			}
+			if match := enumMatch(col.SqlType, tag.Null); match != "" {
+				tag.Valid = append(tag.Valid, match)
+			}
+		}
+	}
+}
+
+// sqlBaseType returns a column type without its size, e.g. tinyint(3) unsigned => tinyint unsigned
+func sqlBaseType(sqlType string) string {
+	t := strings.ToLower(sqlType)
+	if i := strings.Index(t, "("); i >= 0 {
+		if j := strings.Index(t[i:], ")"); j >= 0 {
+			t = t[:i] + t[i+j+1:]
+		}
+	}
+	return strings.TrimSpace(t)
+}
+
+// enumMatch returns the Match validation of an enum or set column, e.g. enum('a','b') => Match(/^(a|b)$/)
+func enumMatch(sqlType string, null bool) string {
+	lower := strings.ToLower(sqlType)
+	isSet := strings.HasPrefix(lower, "set(")
+	if !(strings.HasPrefix(lower, "enum(") || isSet) || !strings.HasSuffix(sqlType, ")") || strings.Contains(sqlType, "`") {
+		return ""
+	}
+	values := sqlType[strings.Index(sqlType, "(")+2 : len(sqlType)-2]
+	var alternatives []string
+	for _, v := range strings.Split(values, "','") {
+		alternatives = append(alternatives, regexp.QuoteMeta(strings.Replace(v, "''", "'", -1)))
+	}
+	value := "(" + strings.Join(alternatives, "|") + ")"
+	if isSet {
+		return "Match(/^(" + value + "(," + value + ")*)?$/)"
+	}
+	if null {
+		// NULL reads as an empty string
+		return "Match(/^" + value + "?$/)"
+	}
+	return "Match(/^" + value + "$/)"
+}
+
+// addRelations adds the many-to-many and reverse relation fields to the models. The two tables
+// of a join table are related many-to-many through it, a table referenced by foreign keys gets
+// a reverse relation to the records referencing it.
+func addRelations(tables []*Table, pkgPath string) {
+	for _, tb := range tables {
+		if tb.isJoinTable(tables) {
+			keys := tb.keyColumns()
+			a := findTable(tables, tb.Fk[keys[0].Tag.Column].RefTable)
+			b := findTable(tables, tb.Fk[keys[1].Tag.Column].RefTable)
+			a.addRelation(b, "[]*", &OrmTag{RelM2M: true, RelThrough: pkgPath + "/models." + modelName(tb.Name)})
+			b.addRelation(a, "[]*", &OrmTag{ReverseMany: true})
+			continue
+		}
+		// the ORM can't tell which foreign key a reverse relation follows when there are several
+		refs := make(map[string]int)
+		for _, col := range tb.Columns {
+			if col.Tag.RelFk {
+				refs[tb.Fk[col.Tag.Column].RefTable]++
+			}
+		}
+		for _, col := range tb.Columns {
+			if !col.Tag.RelFk {
+				continue
+			}
+			ref := findTable(tables, tb.Fk[col.Tag.Column].RefTable)
+			if ref == nil || ref == tb || ref.Pk == "" || refs[ref.Name] > 1 {
+				continue
+			}
+			if tb.isUniqueColumn(col.Tag.Column) {
+				col.Tag.RelFk, col.Tag.RelOne = false, true
+				ref.addRelation(tb, "*", &OrmTag{ReverseOne: true})
+			} else {
+				ref.addRelation(tb, "[]*", &OrmTag{ReverseMany: true})
+			}
+		}
+	}
+}
+
+// addRelation adds a relation field to the records of another table. The field of a single record
+// is named after its model, the field of many records after the table.
+func (tb *Table) addRelation(other *Table, typePrefix string, tag *OrmTag) {
+	name := camelCase(stripTablePrefix(other.Name))
+	if typePrefix == "*" {
+		name = modelName(other.Name)
+	}
+	for _, col := range append(tb.Columns, tb.Relations...) {
+		if col.Name == name {
+			ColorLog("[WARN] %s: field %s already exists, skip its relation to %s\n", tb.Name, name, other.Name)
+			return
+		}
+	}
+	tag.JsonName = stripTablePrefix(other.Name)
+	tb.Relations = append(tb.Relations, &Column{Name: name, Type: typePrefix + modelName(other.Name), Tag: tag})
+}
+
+// relationNames returns the entries of the map from relation names to relation fields of a model
+func (tb *Table) relationNames() string {
+	var entries []string
+	for _, rel := range tb.Relations {
+		entries = append(entries, fmt.Sprintf("%q: %q", rel.Tag.JsonName, rel.Name))
+	}
+	return strings.Join(entries, ", ")
+}
+
+// isUniqueColumn reports whether a column is unique on its own
+func (tb *Table) isUniqueColumn(column string) bool {
+	for _, idx := range tb.Indexes {
+		if idx.Unique && len(idx.Columns) == 1 && idx.Columns[0] == column {
+			return true
+		}
+	}
+	return false
+}
+
+// keyType returns the Go type of a key column as a function parameter
+func (col *Column) keyType() string {
+	if col.Tag.RelFk {
+		return col.RefType
+	}
+	return col.Type
+}
+
+// keyColumns returns the columns of the primary key of the table, in key order
+func (tb *Table) keyColumns() (columns []*Column) {
+	for _, name := range tb.PkColumns {
+		for _, col := range tb.Columns {
+			if col.Tag.Column == name {
+				columns = append(columns, col)
+			}
+		}
+	}
+	return
+}
+
+// findTable returns the table with the given name, or nil
+func findTable(tables []*Table, name string) *Table {
+	for _, tb := range tables {
+		if tb.Name == name {
+			return tb
+		}
+	}
+	return nil
+}
+
+// refColumnType returns the Go type of the column referenced by a foreign key
+func refColumnType(tables []*Table, fk *ForeignKey) string {
+	if ref := findTable(tables, fk.RefTable); ref != nil {
+		for _, col := range ref.Columns {
+			if col.Tag.Column == fk.RefColumn && !strings.HasPrefix(col.Type, "*") {
+				return col.Type
+			}
+		}
+	}
+	return "int"
+}
+
+func indexOf(list []string, s string) int {
+	for i, v := range list {
+		if v == s {
+			return i
+		}
+	}
+	return -1
+}
+
+// addIndexColumn adds a column to the index of the table with the given name, creating the index if needed
+func (tb *Table) addIndexColumn(name string, unique bool, column string) {
+	for _, idx := range tb.Indexes {
+		if idx.Name == name {
+			if !containsString(idx.Columns, column) {
+				idx.Columns = append(idx.Columns, column)
+			}
+			return
+		}
+	}
+	tb.Indexes = append(tb.Indexes, &Index{Name: name, Unique: unique, Columns: []string{column}})
+}
+
+// addPkColumn records a column of the primary key of a table
+func (tb *Table) addPkColumn(column string) {
+	if !containsString(tb.PkColumns, column) {
+		tb.PkColumns = append(tb.PkColumns, column)
+	}
+}
+
+// deleteAndRecreatePaths removes several directories completely
+func createPaths(mode byte, paths *MvcPath) {
+	if (mode & O_MODEL) == O_MODEL {
+		os.Mkdir(paths.ModelPath, 0777)
+	}
+	if (mode & O_CONTROLLER) == O_CONTROLLER {
+		os.Mkdir(paths.ControllerPath, 0777)
+	}
+	if (mode & O_ROUTER) == O_ROUTER {
+		os.Mkdir(paths.RouterPath, 0777)
+	}
+}
+
+// writeSourceFiles generates source files for model/controller/router
+// It will wipe the following directories and recreate them:./models, ./controllers, ./routers
+// Newly geneated files will be inside these folders.
+func writeSourceFiles(pkgPath string, tables []*Table, procedures []*Procedure, mode byte, paths *MvcPath, selectedTables map[string]bool) {
+	procedures = selectProcedures(procedures, selectedTables)
+	if (O_MODEL & mode) == O_MODEL {
+		ColorLog("[INFO] Creating model files...\n")
+		writeModelFiles(tables, paths.ModelPath, selectedTables, pkgPath)
+		writeProcedureFile(procedures, paths.ModelPath)
+	}
+	if (O_CONTROLLER & mode) == O_CONTROLLER {
+		ColorLog("[INFO] Creating controller files...\n")
+		writeControllerFiles(tables, paths.ControllerPath, selectedTables, pkgPath)
+		if procRoutes {
+			writeProcedureControllerFile(procedures, paths.ControllerPath, pkgPath)
+		}
+	}
+	if (O_ROUTER & mode) == O_ROUTER {
+		ColorLog("[INFO] Creating router files...\n")
+		writeRouterFile(tables, procedures, paths.RouterPath, selectedTables, pkgPath)
+	}
+}
+
+// openGeneratedFile opens a generated file for writing. An existing file is overwritten with
+// -force or when told so, kept with -skip-existing, and the user is asked about it otherwise.
+func openGeneratedFile(fpath string, overwrite bool) (*os.File, bool) {
+	if isExist(fpath) && !overwrite && !forceOverwrite {
+		if skipExisting {
+			ColorLog("[WARN] %v is exist, skip create file\n", fpath)
+			return nil, false
+		}
+		ColorLog("[WARN] %v is exist, do you want to overwrite it? Yes or No?\n", fpath)
+		if !askForConfirmation() {
+			ColorLog("[WARN] skip create file\n")
+			return nil, false
+		}
+	}
+	f, err := os.OpenFile(fpath, os.O_CREATE|os.O_RDWR|os.O_TRUNC, 0666)
+	if err != nil {
+		ColorLog("[WARN] %v\n", err)
+		return nil, false
+	}
+	return f, true
+}
+
+// writeGeneratedFile writes and formats a generated source file
+func writeGeneratedFile(fpath, content, kind string, overwrite bool) {
+	f, ok := openGeneratedFile(fpath, overwrite)
+	if !ok {
+		return
+	}
+	if _, err := f.WriteString(content); err != nil {
+		ColorLog("[ERRO] Could not write %s file to %s\n", kind, fpath)
+		os.Exit(2)
+	}
+	f.Close()
+	ColorLog("[INFO] %s => %s\n", kind, fpath)
+	formatSourceCode(fpath)
+}
+
+// writeTableFile writes the model or controller of a table. With -split the generated code goes
+// to <table>_gen.go, which is always regenerated, and <table>.go is only created once to hold
+// the code written by hand.
+func writeTableFile(dir, filename, content, extContent, kind string) {
+	fpath := path.Join(dir, filename+".go")
+	if !splitFiles {
+		writeGeneratedFile(fpath, content, kind, false)
+		return
+	}
+	genPath := path.Join(dir, filename+"_gen.go")
+	if !isExist(fpath) {
+		writeGeneratedFile(fpath, extContent, kind, false)
+	} else if !isExist(genPath) {
+		ColorLog("[WARN] %s was generated without -split, move your code out of it and delete the generated one\n", fpath)
+	}
+	writeGeneratedFile(genPath, content, kind, true)
+}
+
+// writeModelFiles generates model files
+func writeModelFiles(tables []*Table, mPath string, selectedTables map[string]bool, pkgPath string) {
+	for _, tb := range tables {
+		// if selectedTables map is not nil and this table is not selected, ignore it
+		if selectedTables != nil {
+			if _, selected := selectedTables[tb.Name]; !selected {
+				continue
+			}
+		}
+		template := "appcode/model.go.tpl"
+		if tb.ViewQuery != "" {
+			template = "appcode/view_model.go.tpl"
+		} else if tb.hasCompositeKey() {
+			template = "appcode/composite_model.go.tpl"
+		} else if tb.Pk == "" {
+			template = "appcode/struct_model.go.tpl"
+		}
+		// if table contains time field, import time.Time package
+		timePkg := ""
+		if tb.ImportTimePkg {
+			timePkg = "\"time\"\n"
+		}
+		if tb.ImportSqlPkg {
+			timePkg += "\"database/sql\"\n"
+		}
+		data := tableTemplateData(tb, pkgPath)
+		if data["DeletedValue"] == "time.Now()" && !tb.ImportTimePkg {
+			timePkg += "\"time\"\n"
+		}
+		data["ModelStruct"] = tb.String()
+		data["Relations"] = tb.relationNames()
+		data["TimePkg"] = timePkg
+		fileStr := renderTemplate(template, data)
+		fileStr += compositeRelations(tb, tables)
+		writeTableFile(mPath, sourceFileName(tb.Name), fileStr, renderTemplate("appcode/model_ext.go.tpl", data), "model")
+	}
+}
+
+// writeControllerFiles generates controller files
+func writeControllerFiles(tables []*Table, cPath string, selectedTables map[string]bool, pkgPath string) {
+	for _, tb := range tables {
+		// if selectedTables map is not nil and this table is not selected, ignore it
+		if selectedTables != nil {
+			if _, selected := selectedTables[tb.Name]; !selected {
+				continue
+			}
+		}
+		template := "appcode/controller.go.tpl"
+		if tb.ViewQuery != "" {
+			template = "appcode/view_controller.go.tpl"
+		} else if tb.Pk == "" && !hasRoutableKey(tb) {
+			continue
+		} else if tb.hasCompositeKey() {
+			template = "appcode/composite_controller.go.tpl"
+		}
+		data := tableTemplateData(tb, pkgPath)
+		fileStr := renderTemplate(template, data)
+		writeTableFile(cPath, sourceFileName(tb.Name), fileStr, renderTemplate("appcode/controller_ext.go.tpl", data), "controller")
+	}
+}
 
 // writeRouterFile generates router file
-func writeRouterFile(tables []*Table, rPath string, selectedTables map[string]bool, pkgPath string) {
+func writeRouterFile(tables []*Table, procedures []*Procedure, rPath string, selectedTables map[string]bool, pkgPath string) {
 	var nameSpaces []string
 	for _, tb := range tables {
 		// if selectedTables map is not nil and this table is not selected, ignore it
@@ -854,52 +1919,458 @@ func writeRouterFile(tables []*Table, rPath string, selectedTables map[string]bo
 				continue
 			}
 		}
-		if tb.Pk == "" {
+		if tb.ViewQuery == "" && tb.Pk == "" && !hasRoutableKey(tb) {
 			continue
 		}
 		// add name spaces
-		tb_name := strings.Replace(tb.Name, "_", "-", -1)
-		nameSpace := strings.Replace(NAMESPACE_TPL, "{{nameSpace}}", tb_name, -1)
-		nameSpace = strings.Replace(nameSpace, "{{ctrlName}}", camelCase(tb.Name), -1)
-		nameSpaces = append(nameSpaces, nameSpace)
+		nameSpaces = append(nameSpaces, renderTemplate("appcode/namespace.go.tpl", map[string]interface{}{
+			"NameSpace": routeName(tb.Name),
+			"CtrlName":  modelName(tb.Name),
+		}))
+	}
+
+	nameSpaces = append(nameSpaces, renderTemplate("appcode/namespace.go.tpl", map[string]interface{}{
+		"NameSpace": routeName("reports"),
+		"CtrlName":  "Reports",
+	}))
+	if procRoutes && len(routableProcedures(procedures)) > 0 {
+		nameSpaces = append(nameSpaces, renderTemplate("appcode/namespace.go.tpl", map[string]interface{}{
+			"NameSpace": "procedures",
+			"CtrlName":  "Procedures",
+		}))
 	}
 
-	nameSpace := strings.Replace(NAMESPACE_TPL, "{{nameSpace}}", "reports", -1)
-	nameSpace = strings.Replace(nameSpace, "{{ctrlName}}", "Reports", -1)
-	nameSpaces = append(nameSpaces, nameSpace)
+	if authMode != "" {
+		nameSpaces = append(nameSpaces, renderTemplate("appcode/namespace.go.tpl", map[string]interface{}{
+			"NameSpace": "auth",
+			"CtrlName":  "Auth",
+		}))
+	}
 
 	// add export controller
-	fpath := path.Join(rPath, "router.go")
-	routerStr := strings.Replace(ROUTER_TPL, "{{nameSpaces}}", strings.Join(nameSpaces, ""), 1)
-	routerStr = strings.Replace(routerStr, "{{pkgPath}}", pkgPath, 1)
-	var f *os.File
-	var err error
-	if isExist(fpath) {
-		ColorLog("[WARN] %v is exist, do you want to overwrite it? Yes or No?\n", fpath)
-		if askForConfirmation() {
-			f, err = os.OpenFile(fpath, os.O_RDWR|os.O_TRUNC, 0666)
-			if err != nil {
-				ColorLog("[WARN] %v\n", err)
-				return
+	routerStr := renderTemplate("appcode/router.go.tpl", map[string]interface{}{
+		"NameSpaces":     strings.Join(nameSpaces, ""),
+		"PkgPath":        pkgPath,
+		"AuthDefinition": authDefinition(),
+	})
+	writeGeneratedFile(path.Join(rPath, "router.go"), routerStr, "router", false)
+}
+
+// hasRoutableKey reports whether a table has a composite primary key whose columns can all be
+// parsed from the path of a request
+func hasRoutableKey(tb *Table) bool {
+	if !tb.hasCompositeKey() {
+		return false
+	}
+	for _, col := range tb.keyColumns() {
+		if _, ok := keyParseCode(col); !ok {
+			ColorLog("[WARN] %s: key column %s of type %s can't be used in a route, skip creating its controller\n", tb.Name, col.Tag.Column, col.keyType())
+			return false
+		}
+	}
+	return true
+}
+
+// selectProcedures returns the procedures selected by -tables, all of them when none is selected
+func selectProcedures(procedures []*Procedure, selectedTables map[string]bool) (selected []*Procedure) {
+	if selectedTables == nil {
+		return procedures
+	}
+	for _, proc := range procedures {
+		if selectedTables[proc.Name] {
+			selected = append(selected, proc)
+		}
+	}
+	return
+}
+
+// routableProcedures returns the procedures whose parameters can all be parsed from the query
+// string of a request
+func routableProcedures(procedures []*Procedure) (routable []*Procedure) {
+	for _, proc := range procedures {
+		ok := true
+		for _, param := range proc.Params {
+			if _, ok = procedureParseCode(param); !ok {
+				ColorLog("[WARN] %s: parameter %s of type %s can't be read from a request, skip its endpoint\n", proc.Name, param.Tag.Column, param.Type)
+				break
 			}
-		} else {
-			ColorLog("[WARN] skip create file\n")
-			return
 		}
-	} else {
-		f, err = os.OpenFile(fpath, os.O_CREATE|os.O_RDWR, 0666)
-		if err != nil {
-			ColorLog("[WARN] %v\n", err)
-			return
+		if ok {
+			routable = append(routable, proc)
 		}
 	}
-	if _, err := f.WriteString(routerStr); err != nil {
-		ColorLog("[ERRO] Could not write router file to %s\n", fpath)
-		os.Exit(2)
+	return
+}
+
+// procedureParseCode returns the controller code reading a parameter of a stored procedure from
+// the query string. Dates are read as 2006-01-02 and other times as RFC 3339.
+func procedureParseCode(param *Column) (code string, ok bool) {
+	v := keyVarName(param)
+	value := fmt.Sprintf("c.GetString(%q)", param.Tag.Column)
+	switch param.Type {
+	case "time.Time":
+		layout := "time.RFC3339"
+		if sqlBaseType(param.SqlType) == "date" {
+			layout = `"2006-01-02"`
+		}
+		return fmt.Sprintf("%s, _ := time.Parse(%s, %s)", v, layout, value), true
+	case "float64":
+		return fmt.Sprintf("%s, _ := strconv.ParseFloat(%s, 64)", v, value), true
+	case "float32":
+		return fmt.Sprintf("%sValue, _ := strconv.ParseFloat(%s, 32)\n\t%s := float32(%sValue)", v, value, v, v), true
 	}
-	f.Close()
-	ColorLog("[INFO] router => %s\n", fpath)
-	formatSourceCode(fpath)
+	return parseCode(v, param.Type, value)
+}
+
+// procedureTemplateData returns the data of a stored procedure in the procedure templates
+func procedureTemplateData(proc *Procedure) map[string]interface{} {
+	var params, args, parse, docParams []string
+	for _, param := range proc.Params {
+		v := keyVarName(param)
+		params = append(params, v+" "+param.Type)
+		args = append(args, v)
+		code, _ := procedureParseCode(param)
+		parse = append(parse, code)
+		docParams = append(docParams, fmt.Sprintf("// @Param\t%s\tquery\t%s\tfalse\t\"%s of %s\"\n", param.Tag.Column, param.Type, param.Tag.Column, proc.Name))
+	}
+	argList := strings.Join(args, ", ")
+	if argList != "" {
+		argList = ", " + argList
+	}
+	return map[string]interface{}{
+		"Name":      proc.Name,
+		"FuncName":  "Call" + camelCase(proc.Name),
+		"Query":     proc.Query,
+		"Params":    strings.Join(params, ", "),
+		"Args":      argList,
+		"ArgNames":  strings.Join(args, ", "),
+		"Parse":     strings.Join(parse, "\n\t"),
+		"DocParams": strings.Join(docParams, ""),
+		"Route":     strings.Replace(proc.Name, "_", "-", -1),
+	}
+}
+
+// writeProcedureFile generates the models calling the stored procedures
+func writeProcedureFile(procedures []*Procedure, mPath string) {
+	if len(procedures) == 0 {
+		return
+	}
+	var data []map[string]interface{}
+	timePkg := ""
+	for _, proc := range procedures {
+		data = append(data, procedureTemplateData(proc))
+		for _, param := range proc.Params {
+			if param.Type == "time.Time" {
+				timePkg = "\"time\""
+			}
+		}
+	}
+	fileStr := renderTemplate("appcode/procedures.go.tpl", map[string]interface{}{
+		"Procedures": data,
+		"TimePkg":    timePkg,
+	})
+	writeGeneratedFile(path.Join(mPath, "procedures.go"), fileStr, "model", false)
+}
+
+// writeProcedureControllerFile generates the report endpoints of the stored procedures
+func writeProcedureControllerFile(procedures []*Procedure, cPath, pkgPath string) {
+	procedures = routableProcedures(procedures)
+	if len(procedures) == 0 {
+		return
+	}
+	var data []map[string]interface{}
+	imports := map[string]bool{}
+	for _, proc := range procedures {
+		d := procedureTemplateData(proc)
+		for _, pkg := range []string{"strconv", "time"} {
+			if strings.Contains(d["Parse"].(string), pkg+".") {
+				imports[pkg] = true
+			}
+		}
+		data = append(data, d)
+	}
+	var importList []string
+	for _, pkg := range []string{"strconv", "time"} {
+		if imports[pkg] {
+			importList = append(importList, strconv.Quote(pkg))
+		}
+	}
+	fileStr := renderTemplate("appcode/procedures_controller.go.tpl", map[string]interface{}{
+		"Procedures": data,
+		"Imports":    strings.Join(importList, "\n\t"),
+		"PkgPath":    pkgPath,
+		"AuthDoc":    authAnnotation(),
+	})
+	writeGeneratedFile(path.Join(cPath, "procedures.go"), fileStr, "controller", false)
+}
+
+// tableTemplateData returns the data of the model and controller templates of a table
+func tableTemplateData(tb *Table, pkgPath string) map[string]interface{} {
+	data := map[string]interface{}{
+		"Table":        tb,
+		"ModelName":    modelName(tb.Name),
+		"CtrlName":     modelName(tb.Name),
+		"TableName":    tb.Name,
+		"FileName":     sourceFileName(tb.Name),
+		"PkgPath":      pkgPath,
+		"ViewQuery":    tb.ViewQuery,
+		"AuthDoc":      authAnnotation(),
+		"OwnerField":   "",
+		"OwnerColumn":  "",
+		"OwnerModel":   "",
+		"OwnerKey":     "",
+		"OwnerKeyType": "",
+	}
+	if owner := tb.ownerColumn(); owner != nil {
+		data["OwnerField"], data["OwnerColumn"] = owner.Name, owner.Tag.Column
+		data["OwnerModel"], data["OwnerKey"], data["OwnerKeyType"] = tb.ownerKey(owner)
+	}
+	addQueryTemplateData(data, tb)
+	addSoftDeleteTemplateData(data, tb)
+	addAuditTemplateData(data, tb)
+	if tb.hasCompositeKey() {
+		addKeyTemplateData(data, tb)
+	}
+	return data
+}
+
+// softDeleteColumn returns the column marking the soft-deleted rows of the table, deleted_at or
+// is_deleted, nil if its rows are deleted for good
+func (tb *Table) softDeleteColumn() *Column {
+	for _, name := range []string{"deleted_at", "is_deleted"} {
+		for _, col := range tb.Columns {
+			if col.Tag.Column == name {
+				return col
+			}
+		}
+	}
+	return nil
+}
+
+// addSoftDeleteTemplateData adds the filter and condition of the rows not soft-deleted and the
+// value marking a row as deleted to the data of the templates of a table. deleted_at is set to the time of the
+// deletion, is_deleted to true or 1.
+func addSoftDeleteTemplateData(data map[string]interface{}, tb *Table) {
+	data["SoftDelete"] = false
+	data["DeletedColumn"], data["DeletedValue"] = "", ""
+	data["LiveFilter"], data["LiveCond"], data["LiveWhere"] = "", "", ""
+	col := tb.softDeleteColumn()
+	if col == nil {
+		return
+	}
+	name := col.Tag.Column
+	data["SoftDelete"] = true
+	data["DeletedColumn"] = name
+	if name == "deleted_at" {
+		data["DeletedValue"] = "time.Now()"
+		data["LiveFilter"] = `.Filter("deleted_at__isnull", true)`
+		data["LiveCond"] = `.And("deleted_at__isnull", true)`
+		data["LiveWhere"] = "deleted_at IS NULL"
+		return
+	}
+	live, deleted := "0", "1"
+	if strings.Contains(strings.ToLower(col.Type), "bool") {
+		live, deleted = "false", "true"
+	}
+	data["DeletedValue"] = deleted
+	data["LiveFilter"] = fmt.Sprintf(".Filter(%q, %s)", name, live)
+	data["LiveCond"] = fmt.Sprintf(".And(%q, %s)", name, live)
+	data["LiveWhere"] = name + " = " + live
+}
+
+// sqlBlobTypes are the column types left out of the query whitelist of a model
+var sqlBlobTypes = map[string]bool{
+	"binary": true, "varbinary": true, "blob": true, "tinyblob": true, "mediumblob": true, "longblob": true, "bytea": true,
+}
+
+// sqlLargeTextTypes are the column types a model isn't sorted by
+var sqlLargeTextTypes = map[string]bool{
+	"text": true, "tinytext": true, "mediumtext": true, "longtext": true, "json": true, "jsonb": true, "xml": true,
+}
+
+// addQueryTemplateData adds the query whitelist of a table to the data of its templates. Every
+// column but the binary ones can be filtered by, and sorted by unless it holds large text. The
+// nullable sort columns are listed, their pages use offsets. Foreign keys and relations can be joined.
+func addQueryTemplateData(data map[string]interface{}, tb *Table) {
+	var filter, sort, nullable, join []string
+	for _, col := range tb.Columns {
+		base := sqlBaseType(col.SqlType)
+		if sqlBlobTypes[base] {
+			continue
+		}
+		filter = append(filter, fmt.Sprintf("%q: %q", col.Tag.Column, queryValueType(col)))
+		if !sqlLargeTextTypes[base] {
+			sort = append(sort, strconv.Quote(col.Tag.Column))
+			if col.Tag.Null {
+				nullable = append(nullable, strconv.Quote(col.Tag.Column))
+			}
+		}
+		if col.Tag.RelFk || col.Tag.RelOne {
+			join = append(join, strconv.Quote(col.Tag.Column))
+		}
+	}
+	for _, rel := range tb.Relations {
+		join = append(join, strconv.Quote(rel.Tag.JsonName))
+	}
+	data["QueryFilter"] = strings.Join(filter, ", ")
+	data["QuerySort"] = strings.Join(sort, ", ")
+	data["QueryNullable"] = strings.Join(nullable, ", ")
+	data["QueryJoin"] = strings.Join(join, ", ")
+}
+
+// queryValueType returns the type of the values of a column in the filter grammar of the
+// generated helpers: string, int, uint, float, bool or time
+func queryValueType(col *Column) string {
+	t := strings.TrimPrefix(col.Type, "*")
+	if col.Tag.RelFk || col.Tag.RelOne {
+		// the orm only relates to integer keys
+		t = "int"
+	}
+	switch {
+	case t == "sql.NullInt64" || t == "sql.NullInt32" || strings.HasPrefix(t, "int"):
+		return "int"
+	case strings.HasPrefix(t, "uint"):
+		return "uint"
+	case t == "sql.NullFloat64" || strings.HasPrefix(t, "float"):
+		return "float"
+	case t == "sql.NullBool" || t == "bool":
+		return "bool"
+	case t == "sql.NullTime" || t == "time.Time":
+		return "time"
+	}
+	return "string"
+}
+
+// addKeyTemplateData adds the composite primary key of a table to the data of its templates
+func addKeyTemplateData(data map[string]interface{}, tb *Table) {
+	var names, params, filters, filtersM, columnSet, docParams, route, parse, args, fields, vars []string
+	strconvPkg := ""
+	for _, col := range tb.keyColumns() {
+		v := keyVarName(col)
+		names = append(names, col.Name)
+		params = append(params, v+" "+col.keyType())
+		filters = append(filters, fmt.Sprintf(".Filter(%q, %s)", col.Tag.Column, v))
+		filtersM = append(filtersM, fmt.Sprintf(".Filter(%q, m.%s)", col.Tag.Column, col.Name))
+		columnSet = append(columnSet, fmt.Sprintf("%q: true", col.Tag.Column))
+		docParams = append(docParams, fmt.Sprintf("// @Param\t%s\t\tpath \t%s\ttrue\t\t\"%s of the key\"\n", col.Tag.Column, col.keyType(), col.Tag.Column))
+		route = append(route, "/:"+col.Tag.Column)
+		code, _ := keyParseCode(col)
+		parse = append(parse, code)
+		if strings.Contains(code, "strconv.") {
+			strconvPkg = "\"strconv\""
+		}
+		args = append(args, v)
+		fields = append(fields, "v."+col.Name)
+		if col.Tag.RelFk {
+			vars = append(vars, fmt.Sprintf("&models.%s{Id: %s}", modelName(tb.Fk[col.Tag.Column].RefTable), v))
+		} else {
+			vars = append(vars, v)
+		}
+	}
+	data["KeyNames"] = strings.Join(names, " and ")
+	data["KeyParams"] = strings.Join(params, ", ")
+	data["KeyFilters"] = strings.Join(filters, "")
+	data["KeyFiltersM"] = strings.Join(filtersM, "")
+	data["KeyColumnSet"] = strings.Join(columnSet, ", ")
+	data["KeyDocParams"] = strings.Join(docParams, "")
+	data["KeyRoute"] = strings.Join(route, "")
+	data["KeyParse"] = strings.Join(parse, "\n\t")
+	data["KeyArgs"] = strings.Join(args, ", ")
+	data["KeyAssign"] = strings.Join(fields, ", ") + " = " + strings.Join(vars, ", ")
+	data["StrconvPkg"] = strconvPkg
+}
+
+// keyVarName returns the name of the variable holding a key column, e.g. post_id => postId
+func keyVarName(col *Column) string {
+	name := camelCase(col.Tag.Column)
+	name = strings.ToLower(name[:1]) + name[1:]
+	if token.Lookup(name).IsKeyword() {
+		name += "Key"
+	}
+	return name
+}
+
+// keyParseCode returns the controller code reading a key column from the path parameters
+func keyParseCode(col *Column) (code string, ok bool) {
+	return parseCode(keyVarName(col), col.keyType(), fmt.Sprintf("c.Ctx.Input.Params[\":%s\"]", col.Tag.Column))
+}
+
+// parseCode returns the controller code parsing the string param into the variable v of a Go type
+func parseCode(v, goType, param string) (code string, ok bool) {
+	switch goType {
+	case "string":
+		return fmt.Sprintf("%s := %s", v, param), true
+	case "int":
+		return fmt.Sprintf("%s, _ := strconv.Atoi(%s)", v, param), true
+	case "int64":
+		return fmt.Sprintf("%s, _ := strconv.ParseInt(%s, 10, 64)", v, param), true
+	case "int8", "int16", "int32":
+		return fmt.Sprintf("%sValue, _ := strconv.ParseInt(%s, 10, 64)\n\t%s := %s(%sValue)", v, param, v, goType, v), true
+	case "uint64":
+		return fmt.Sprintf("%s, _ := strconv.ParseUint(%s, 10, 64)", v, param), true
+	case "uint", "uint8", "uint16", "uint32":
+		return fmt.Sprintf("%sValue, _ := strconv.ParseUint(%s, 10, 64)\n\t%s := %s(%sValue)", v, param, v, goType, v), true
+	case "bool":
+		return fmt.Sprintf("%s, _ := strconv.ParseBool(%s)", v, param), true
+	}
+	return "", false
+}
+
+// compositeRelations returns the methods loading the records a table references through foreign keys
+// to composite primary keys. The ORM can't relate them, so the key columns are plain fields.
+func compositeRelations(tb *Table, tables []*Table) string {
+	byConstraint := make(map[string][]*ForeignKey)
+	var constraints []string
+	for _, fk := range tb.Fk {
+		ref := findTable(tables, fk.RefTable)
+		if ref == nil || !ref.hasCompositeKey() {
+			continue
+		}
+		if _, ok := byConstraint[fk.Constraint]; !ok {
+			constraints = append(constraints, fk.Constraint)
+		}
+		byConstraint[fk.Constraint] = append(byConstraint[fk.Constraint], fk)
+	}
+	sort.Strings(constraints)
+
+	rv := ""
+	methods := make(map[string]bool)
+	for _, constraint := range constraints {
+		fks := byConstraint[constraint]
+		ref := findTable(tables, fks[0].RefTable)
+		var args, columns []string
+		for _, key := range ref.keyColumns() {
+			for _, fk := range fks {
+				if fk.RefColumn != key.Tag.Column {
+					continue
+				}
+				for _, col := range tb.Columns {
+					if col.Tag.Column != fk.Name {
+						continue
+					}
+					arg := "t." + col.Name
+					if col.Type != key.keyType() {
+						arg = key.keyType() + "(" + arg + ")"
+					}
+					args = append(args, arg)
+					columns = append(columns, fk.Name)
+				}
+			}
+		}
+		if len(args) != len(ref.PkColumns) {
+			ColorLog("[WARN] %s: foreign key %s doesn't cover the primary key of %s, skip its relation\n", tb.Name, constraint, ref.Name)
+			continue
+		}
+		method := modelName(ref.Name)
+		if methods[method] {
+			method = camelCase(constraint)
+		}
+		methods[method] = true
+		rv += fmt.Sprintf("\n// %s returns the %s referenced by %s\n", method, modelName(ref.Name), strings.Join(columns, " and "))
+		rv += fmt.Sprintf("func (t *%s) %s() (*%s, error) {\n", modelName(tb.Name), method, modelName(ref.Name))
+		rv += fmt.Sprintf("\treturn Get%sByPk(%s)\n}\n", modelName(ref.Name), strings.Join(args, ", "))
+	}
+	return rv
 }
 
 // formatSourceCode formats source files
@@ -944,7 +2415,7 @@ func isSQLBitType(t string) bool {
 	return t == "bit"
 }
 func isSQLStrangeType(t string) bool {
-	return t == "interval" || t == "uuid" || t == "json"
+	return t == "interval" || t == "uuid" || t == "json" || t == "jsonb"
 }
 
 // extractColSize extracts field size: e.g. varchar(255) => 255
@@ -1006,332 +2477,3 @@ func getPackagePath(curpath string) (packpath string) {
 	packpath = strings.Join(strings.Split(curpath[len(appsrcpath)+1:], string(filepath.Separator)), "/")
 	return
 }
-
-const (
-	STRUCT_MODEL_TPL = `package models
-import (
-	{{timePkg}}
-
-	"github.com/aamsur/beego/orm"
-)
-
-{{modelStruct}}
-
-// GetAll{{modelName}} retrieves all {{modelName}}.
-// Returns empty list if no records exist
-func GetAll{{modelName}}() (ml []interface{}, err error, totals int64) {
-
-	qb, _ := orm.NewQueryBuilder("mysql")
-
-	qb.Select("*")
-	qb.From("{{tableName}}")
-
-	o := orm.NewOrm()
-	sql := qb.String()
-
-	var m []{{modelName}}
-	if _, err := o.Raw(sql).QueryRows(&m); err == nil {
-		for _, v := range m {
-			ml = append(ml, v)
-		}
-	}
-
-
-	return ml, err, totals
-}
-`
-
-	MODEL_TPL = `package models
-
-import (
-	"fmt"
-	"reflect"
-	{{timePkg}}
-
-	"github.com/aamsur/beego/orm"
-	"{{pkgPath}}/helpers"
-)
-
-{{modelStruct}}
-
-func (t *{{modelName}}) TableName() string {
-	return "{{tableName}}"
-}
-
-func init() {
-	orm.RegisterModel(new({{modelName}}))
-}
-
-
-// Add{{modelName}} insert a new {{modelName}} into database and returns
-// last inserted Id on success.
-func Add{{modelName}}(m *{{modelName}}) (id int64, err error) {
-	o := orm.NewOrm()
-	id, err = o.Insert(m)
-	return
-}
-
-// Get{{modelName}}ById retrieves {{modelName}} by Id. Returns error if
-// Id doesn't exist
-func Get{{modelName}}ById(id int) (v *{{modelName}}, err error) {
-	var m {{modelName}}
-	o := orm.NewOrm()
-
-	if err = o.QueryTable(new({{modelName}})).Filter("id", id).RelatedSel().One(&m); err == nil {
-		return &m, nil
-	}
-
-	return nil, err
-}
-
-// GetAll{{modelName}} retrieves all {{modelName}} matches certain condition. Returns empty list if
-// no records exist
-func GetAll{{modelName}}(query map[int]map[string]string, fields []string, groupby []string, sortby []string, order []string,
-	offset int64, limit int64, join []string) (ml []interface{}, err error, totals int64) {
-
-	o := orm.NewOrm()
-	qs := o.QueryTable(new({{modelName}})).SetCond(helpers.QueryCondition(query)).RelatedSel(helpers.QueryJoin(join)).GroupBy(groupby...)
-
-	// count the current query
-	cnt, err := qs.Count()
-	if err != nil {
-		return nil, err, cnt
-	}
-
-	// order by:
-	sortFields := helpers.SetSorting(sortby, order)
-
-	var l []{{modelName}}
-	qs = qs.OrderBy(sortFields...)
-	if _, err := qs.Limit(limit, offset).All(&l, fields...); err == nil {
-		if len(fields) == 0 {
-			for _, v := range l {
-				ml = append(ml, v)
-			}
-		} else {
-			// trim unused fields
-			for _, v := range l {
-				m := make(map[string]interface{})
-				val := reflect.ValueOf(v)
-				for _, fname := range fields {
-					m[fname] = val.FieldByName(helpers.CamelString(fname)).Interface()
-				}
-				ml = append(ml, m)
-			}
-		}
-
-		return ml, nil, cnt
-	}
-	return nil, err, cnt
-}
-
-// Update{{modelName}} updates {{modelName}} by Id and returns error if
-// the record to be updated doesn't exist
-func Update{{modelName}}ById(m *{{modelName}}, keys []string) (err error) {
-	o := orm.NewOrm()
-	v := {{modelName}}{Id: m.Id}
-	// ascertain id exists in the database
-	if err = o.Read(&v); err == nil {
-		// update only the keys provided
-		o.Update(m, keys...)
-	}
-	return
-}
-
-// Delete{{modelName}} deletes {{modelName}} by Id and returns error if
-// the record to be deleted doesn't exist
-func Delete{{modelName}}(id int) (err error) {
-	o := orm.NewOrm()
-	v := {{modelName}}{Id: id}
-	// ascertain id exists in the database
-	if err = o.Read(&v); err == nil {
-		var num int64
-		if num, err = o.Delete(&{{modelName}}{Id: id}); err == nil {
-			fmt.Println("Number of records deleted in database:", num)
-		}
-	}
-	return
-}
-`
-	CTRL_TPL = `package controllers
-
-import (
-	"encoding/json"
-	"strconv"
-
-	"{{pkgPath}}/models"
-	"{{pkgPath}}/helpers"
-	"github.com/aamsur/beego"
-)
-
-// oprations for {{ctrlName}}
-type {{ctrlName}}Controller struct {
-	beego.Controller
-}
-
-func (c *{{ctrlName}}Controller) URLMapping() {
-	c.Mapping("Post", c.Post)
-	c.Mapping("GetOne", c.GetOne)
-	c.Mapping("GetAll", c.GetAll)
-	c.Mapping("Put", c.Put)
-	c.Mapping("Delete", c.Delete)
-}
-
-// @Title Post
-// @Description create {{ctrlName}}
-// @Param	body		body 	models.{{ctrlName}}	true		"body for {{ctrlName}} content"
-// @Success 200 {int} models.{{ctrlName}}.Id
-// @Failure 403 body is empty
-// @router / [post]
-func (c *{{ctrlName}}Controller) Post() {
-	var v models.{{ctrlName}}
-	json.Unmarshal(c.Ctx.Input.RequestBody, &v)
-	
-	// validate the model
-	if res, errData := helpers.Validator(&v); res == false {
-		c.Data["json"] = errData
-	} else {
-		if id, err := models.Add{{ctrlName}}(&v); err == nil {
-			helpers.Rf.Success(c.Ctx.Request.Method, int(id))
-			c.Data["json"] = helpers.Rf.Data
-		} else {
-			helpers.Rf.Fail(err.Error())
-			c.Data["json"] = helpers.Rf.Data
-		}
-	}
-
-	c.ServeJson()
-}
-
-// @Title Get
-// @Description get {{ctrlName}} by id
-// @Param	id		path 	string	true		"The key for staticblock"
-// @Success 200 {object} models.{{ctrlName}}
-// @Failure 403 :id is empty
-// @router /:id [get]
-func (c *{{ctrlName}}Controller) GetOne() {
-	idStr := c.Ctx.Input.Params[":id"]
-	id, _ := strconv.Atoi(idStr)
-	v, err := models.Get{{ctrlName}}ById(id)
-	if err != nil {
-		c.Data["json"] = nil
-	} else {
-		c.Data["json"] = v
-	}
-	c.ServeJson()
-}
-
-// @Title Get All
-// @Description get {{ctrlName}}
-// @Param	query	query	string	false	"Filter. e.g. col1:v1,col2:v2 ..."
-// @Param	fields	query	string	false	"Fields returned. e.g. col1,col2 ..."
-// @Param	groupby	query	string	false	"Group-by fields. e.g. col1,col2 ..."
-// @Param	sortby	query	string	false	"Sorted-by fields. e.g. col1,col2 ..."
-// @Param	order	query	string	false	"Order corresponding to each sortby field, if single value, apply to all sortby fields. e.g. desc,asc ..."
-// @Param	limit	query	string	false	"Limit the size of result set. Must be an integer"
-// @Param	offset	query	string	false	"Start position of result set. Must be an integer"
-// @Success 200 {object} models.{{ctrlName}}
-// @Failure 403
-// @router / [get]
-func (c *{{ctrlName}}Controller) GetAll() {
-
-	// Get all with query string
-	l, err, totals := models.GetAll{{ctrlName}}(helpers.QueryString(c.Input()))
-
-	helpers.Rf.Data = make(map[string]interface{})
-	helpers.Rf.Data["totals"] = totals
-
-	if err != nil {
-		// if error, we a nil value, same as no row found
-		c.Data["json"] = nil
-	} else {
-		if l == nil {
-			// no row found
-			c.Data["json"] = nil
-		} else {
-			helpers.Rf.Success(c.Ctx.Request.Method, 0, l)
-			c.Data["json"] = helpers.Rf.Data
-		}
-	}
-
-	c.ServeJson()
-}
-
-// @Title Update
-// @Description update the {{ctrlName}}
-// @Param	id		path 	string				true		"The id you want to update"
-// @Param	body	body 	models.{{ctrlName}}	true		"body for {{ctrlName}} content"
-// @Success 200 {object} models.{{ctrlName}}
-// @Failure 403 :id is not int
-// @router /:id [put]
-func (c *{{ctrlName}}Controller) Put() {
-	idStr := c.Ctx.Input.Params[":id"]
-	id, _ := strconv.Atoi(idStr)
-	v := models.{{ctrlName}}{Id: id}
-
-	// bind input into model struct
-	json.Unmarshal(c.Ctx.Input.RequestBody, &v)
-
-	// get input keys
-	keys := helpers.GetInputKeys(c.Ctx.Input.RequestBody)
-
-	// validate the model
-	if res, errData := helpers.Validator(&v); res == false {
-		c.Data["json"] = errData
-	} else {
-		if err := models.Update{{ctrlName}}ById(&v, keys); err == nil {
-			helpers.Rf.Success(c.Ctx.Request.Method, int(id))
-			c.Data["json"] = helpers.Rf.Data
-		} else {
-			helpers.Rf.Fail(err.Error())
-			c.Data["json"] = helpers.Rf.Data
-		}
-	}
-	c.ServeJson()
-}
-
-// @Title Delete
-// @Description delete the {{ctrlName}}
-// @Param	id		path 	string	true		"The id you want to delete"
-// @Success 200 {string} delete success!
-// @Failure 403 id is empty
-// @router /:id [delete]
-func (c *{{ctrlName}}Controller) Delete() {
-	idStr := c.Ctx.Input.Params[":id"]
-	id, _ := strconv.Atoi(idStr)
-	if err := models.Delete{{ctrlName}}(id); err == nil {
-		c.Data["json"] = "OK"
-	} else {
-		c.Data["json"] = nil
-	}
-	c.ServeJson()
-}
-`
-	ROUTER_TPL = `// @APIVersion 1.0.0
-// @Title Application API
-// @Description application api
-// @License Apache 2.0
-// @LicenseUrl http://www.apache.org/licenses/LICENSE-2.0.html
-package routers
-
-import (
-	"{{pkgPath}}/controllers"
-
-	"github.com/aamsur/beego"
-)
-
-func init() {
-	ns := beego.NewNamespace("/v1",
-		{{nameSpaces}}
-	)
-	beego.AddNamespace(ns)
-}
-`
-	NAMESPACE_TPL = `
-		beego.NSNamespace("/{{nameSpace}}",
-			beego.NSInclude(
-				&controllers.{{ctrlName}}Controller{},
-			),
-		),
-`
-)
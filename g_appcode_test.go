@@ -0,0 +1,681 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io/ioutil"
+	"os"
+	"path"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// openSqliteFixture creates an on-disk SQLite database from testdata/sqlite/schema.sql
+func openSqliteFixture(t *testing.T) *sql.DB {
+	return createSqliteFixture(t, path.Join(t.TempDir(), "fixture.db"))
+}
+
+// createSqliteFixture creates the SQLite database of testdata/sqlite/schema.sql in file
+func createSqliteFixture(t *testing.T, file string) *sql.DB {
+	schema, err := ioutil.ReadFile("testdata/sqlite/schema.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := sql.Open("sqlite3", file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, stmt := range splitSqlStatements(string(schema), "sqlite3") {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("%s: %s", stmt, err)
+		}
+	}
+	return db
+}
+
+func TestSqliteTransformer(t *testing.T) {
+	db := openSqliteFixture(t)
+	defer db.Close()
+	trans := dbDriver["sqlite3"]
+	names := trans.GetTableNames(db)
+	if !reflect.DeepEqual(names, []string{"post_tag_links", "post_tags", "posts", "profiles", "tag_votes", "tags", "user"}) {
+		t.Fatalf("unexpected tables: %v", names)
+	}
+	tables := getTableObjects(names, db, trans)
+	postTags, posts, user := tables[1], tables[2], tables[6]
+
+	if postTags.Pk != "" || !reflect.DeepEqual(postTags.PkColumns, []string{"post_id", "tag"}) {
+		t.Errorf("unexpected composite key: %q %v", postTags.Pk, postTags.PkColumns)
+	}
+	if fk := postTags.Fk["post_id"]; fk == nil || fk.RefTable != "posts" || fk.RefColumn != "id" {
+		t.Errorf("unexpected foreign key: %+v", fk)
+	}
+	// the ORM is given the first key column as pk, foreign keys in the key are plain values
+	if key := postTags.keyColumns(); len(key) != 2 || key[0].String() != "PostId int `orm:\"column(post_id);pk\" json:\"post_id\"`" {
+		t.Errorf("unexpected key columns: %v", key)
+	}
+	if !reflect.DeepEqual(user.Uk, []string{"email"}) {
+		t.Errorf("unexpected unique keys: %v", user.Uk)
+	}
+	if len(posts.Indexes) != 1 || posts.Indexes[0].Name != "idx_posts_title" {
+		t.Errorf("unexpected indexes: %v", posts.Indexes)
+	}
+
+	expected := []string{
+		"Id int `orm:\"column(id);auto\" json:\"id\"`",
+		"Title string `orm:\"column(title);size(255)\" json:\"title\"`",
+		"Body string `orm:\"column(body);null\" json:\"body\"`",
+		"Score float64 `orm:\"column(score);null;digits(5);decimals(2)\" json:\"score\"`",
+		"AuthorId *User `orm:\"column(author_id);rel(fk)\" json:\"author_id\"`",
+	}
+	for i, col := range posts.Columns {
+		if col.String() != expected[i] {
+			t.Errorf("column %d: got %s, expected %s", i, col.String(), expected[i])
+		}
+	}
+	if created := user.Columns[3]; created.Type != "time.Time" || !created.Tag.AutoNowAdd || !user.ImportTimePkg {
+		t.Errorf("unexpected created column: %s", created.String())
+	}
+}
+
+func TestGoDataType(t *testing.T) {
+	defer func(types map[string]*sqlTypeMapping) { conf.Database.Types = types }(conf.Database.Types)
+	conf.Database.Types = map[string]*sqlTypeMapping{
+		"citext": {Type: "string", Orm: "type(text)"},
+		"json":   {Type: "json.RawMessage"},
+	}
+	for sqlType, expected := range map[string]string{
+		"jsonb":                    "string",
+		"timestamp with time zone": "time.Time",
+		"citext":                   "string",
+		"json":                     "json.RawMessage",
+		"tsrange":                  "string",
+	} {
+		if got := dbDriver["postgres"].GetGoDataType(sqlType); got != expected {
+			t.Errorf("%s: got %s, expected %s", sqlType, got, expected)
+		}
+	}
+	if got := dbDriver["mysql"].GetGoDataType("year"); got != "int16" {
+		t.Errorf("year: got %s, expected int16", got)
+	}
+	if got := customOrmOptions("citext"); got != "type(text)" {
+		t.Errorf("unexpected orm options of citext: %s", got)
+	}
+}
+
+func TestSetNullableTypes(t *testing.T) {
+	db := openSqliteFixture(t)
+	defer db.Close()
+	trans := dbDriver["sqlite3"]
+	names := trans.GetTableNames(db)
+
+	for nullable, expected := range map[string][]string{
+		"zero":    {"int", "string", "string", "float64", "*User"},
+		"pointer": {"int", "string", "*string", "*float64", "*User"},
+		"sql":     {"int", "string", "sql.NullString", "sql.NullFloat64", "*User"},
+	} {
+		tables := getTableObjects(names, db, trans)
+		setNullableTypes(tables, nullable)
+		var got []string
+		for _, col := range tables[2].Columns {
+			got = append(got, col.Type)
+		}
+		if !reflect.DeepEqual(got, expected) {
+			t.Errorf("%s: got %v, expected %v", nullable, got, expected)
+		}
+		// user.created is the only time column
+		if user := tables[6]; user.ImportTimePkg != (nullable != "sql") || user.ImportSqlPkg != (nullable == "sql") {
+			t.Errorf("%s: unexpected imports of user: time %v, sql %v", nullable, user.ImportTimePkg, user.ImportSqlPkg)
+		}
+	}
+}
+
+func TestAddRelations(t *testing.T) {
+	db := openSqliteFixture(t)
+	defer db.Close()
+	trans := dbDriver["sqlite3"]
+	tables := getTableObjects(trans.GetTableNames(db), db, trans)
+	addRelations(tables, "app")
+	links, posts, profiles, tags, user := tables[0], tables[2], tables[3], tables[5], tables[6]
+
+	relations := map[*Table][]string{
+		posts: {"Tags []*Tags `orm:\"rel(m2m);rel_through(app/models.PostTagLinks)\" json:\"tags,omitempty\"`"},
+		tags:  {"Posts []*Posts `orm:\"reverse(many)\" json:\"posts,omitempty\"`"},
+		user: {
+			"Posts []*Posts `orm:\"reverse(many)\" json:\"posts,omitempty\"`",
+			"Profiles *Profiles `orm:\"reverse(one)\" json:\"profiles,omitempty\"`",
+		},
+	}
+	for tb, expected := range relations {
+		var got []string
+		for _, rel := range tb.Relations {
+			got = append(got, rel.String())
+		}
+		if !reflect.DeepEqual(got, expected) {
+			t.Errorf("relations of %s: got %v, expected %v", tb.Name, got, expected)
+		}
+	}
+	// the keys of a join table stay relations, a unique foreign key is one-to-one
+	if key := links.keyColumns(); key[1].String() != "TagId *Tags `orm:\"column(tag_id);rel(fk)\" json:\"tag_id\"`" {
+		t.Errorf("unexpected join table key: %s", key[1].String())
+	}
+	if !profiles.Columns[1].Tag.RelOne {
+		t.Errorf("unique foreign key is not one-to-one: %s", profiles.Columns[1].String())
+	}
+	if got := user.relationNames(); got != `"posts": "Posts", "profiles": "Profiles"` {
+		t.Errorf("unexpected relation names: %s", got)
+	}
+}
+
+func TestAddQueryTemplateData(t *testing.T) {
+	posts := &Table{
+		Name: "posts",
+		Columns: []*Column{
+			{Name: "Id", Type: "int", SqlType: "INTEGER", Tag: &OrmTag{Column: "id", Auto: true}},
+			{Name: "Body", Type: "*string", SqlType: "TEXT", Tag: &OrmTag{Column: "body", Null: true}},
+			{Name: "Cover", Type: "string", SqlType: "BLOB", Tag: &OrmTag{Column: "cover"}},
+			{Name: "Score", Type: "sql.NullFloat64", SqlType: "DECIMAL(5,2)", Tag: &OrmTag{Column: "score", Null: true}},
+			{Name: "AuthorId", Type: "*User", SqlType: "INTEGER", Tag: &OrmTag{Column: "author_id", RelFk: true}},
+		},
+		Relations: []*Column{{Name: "Tags", Type: "[]*Tags", Tag: &OrmTag{RelM2M: true, JsonName: "tags"}}},
+	}
+	data := map[string]interface{}{}
+	addQueryTemplateData(data, posts)
+	expected := map[string]interface{}{
+		"QueryFilter":   `"id": "int", "body": "string", "score": "float", "author_id": "int"`,
+		"QuerySort":     `"id", "score", "author_id"`,
+		"QueryNullable": `"score"`,
+		"QueryJoin":     `"author_id", "tags"`,
+	}
+	if !reflect.DeepEqual(data, expected) {
+		t.Errorf("unexpected query whitelist: %v", data)
+	}
+}
+
+func TestSqliteAppcode(t *testing.T) {
+	db := openSqliteFixture(t)
+	defer db.Close()
+	trans := dbDriver["sqlite3"]
+	tables := getTableObjects(trans.GetTableNames(db), db, trans)
+	addRelations(tables, "app")
+
+	dir := t.TempDir()
+	mvcPath := &MvcPath{
+		ModelPath:      path.Join(dir, "models"),
+		ControllerPath: path.Join(dir, "controllers"),
+		RouterPath:     path.Join(dir, "routers"),
+	}
+	mode := O_MODEL | O_CONTROLLER | O_ROUTER
+	createPaths(mode, mvcPath)
+	writeSourceFiles("app", tables, nil, mode, mvcPath, nil)
+
+	model, err := ioutil.ReadFile(path.Join(mvcPath.ModelPath, "posts.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(model), "type Posts struct") {
+		t.Errorf("model of posts is missing its struct:\n%s", model)
+	}
+	model, err = ioutil.ReadFile(path.Join(mvcPath.ModelPath, "post_tags.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(model), `func GetPostTagsByPk(postId int, tag string) (v *PostTags, err error)`) {
+		t.Errorf("model of post_tags is missing its key functions:\n%s", model)
+	}
+	model, err = ioutil.ReadFile(path.Join(mvcPath.ModelPath, "tag_votes.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(model), "return GetPostTagsByPk(t.PostId, t.Tag)") {
+		t.Errorf("model of tag_votes doesn't relate to post_tags:\n%s", model)
+	}
+	ctrl, err := ioutil.ReadFile(path.Join(mvcPath.ControllerPath, "post_tags.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(ctrl), "// @router /:post_id/:tag [get]") {
+		t.Errorf("controller of post_tags doesn't route its key:\n%s", ctrl)
+	}
+	router, err := ioutil.ReadFile(path.Join(mvcPath.RouterPath, "router.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(router), "&controllers.UserController{}") {
+		t.Errorf("router doesn't register the user controller:\n%s", router)
+	}
+}
+
+func TestSqliteViewsAndProcedures(t *testing.T) {
+	defer func() { procRoutes = false }()
+	procRoutes = true
+	db := openSqliteFixture(t)
+	defer db.Close()
+	trans := dbDriver["sqlite3"]
+	names := trans.GetViewNames(db)
+	if !reflect.DeepEqual(names, []string{"post_scores"}) {
+		t.Fatalf("unexpected views: %v", names)
+	}
+	views := getTableObjects(names, db, trans)
+	views[0].ViewQuery = `SELECT * FROM "post_scores"`
+	procedures := []*Procedure{{
+		Name:   "daily_sales",
+		Query:  "CALL `daily_sales`(?, ?)",
+		Params: []*Column{procedureParam("day", "time.Time", "date"), procedureParam("store_id", "int", "int(11)")},
+	}}
+
+	dir := t.TempDir()
+	mvcPath := &MvcPath{
+		ModelPath:      path.Join(dir, "models"),
+		ControllerPath: path.Join(dir, "controllers"),
+		RouterPath:     path.Join(dir, "routers"),
+	}
+	mode := O_MODEL | O_CONTROLLER | O_ROUTER
+	createPaths(mode, mvcPath)
+	writeSourceFiles("app", views, procedures, mode, mvcPath, nil)
+
+	expected := map[string][]string{
+		"models/post_scores.go":      {"func GetAllPostScores(offset int64, limit int64)", `sql := "SELECT * FROM \"post_scores\""`},
+		"controllers/post_scores.go": {`c.Mapping("GetAll", c.GetAll)`},
+		"models/procedures.go":       {"func CallDailySales(day time.Time, storeId int) (rows []orm.Params, err error)", "o.Raw(\"CALL `daily_sales`(?, ?)\", day, storeId)"},
+		"controllers/procedures.go":  {`day, _ := time.Parse("2006-01-02", c.GetString("day"))`, "// @router /daily-sales [get]"},
+		"routers/router.go":          {"&controllers.PostScoresController{}", "&controllers.ProceduresController{}"},
+	}
+	for file, parts := range expected {
+		content, err := ioutil.ReadFile(path.Join(dir, file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, part := range parts {
+			if !strings.Contains(string(content), part) {
+				t.Errorf("%s is missing %s:\n%s", file, part, content)
+			}
+		}
+	}
+	if ctrl, _ := ioutil.ReadFile(path.Join(mvcPath.ControllerPath, "post_scores.go")); strings.Contains(string(ctrl), "[post]") {
+		t.Errorf("controller of a view writes to it:\n%s", ctrl)
+	}
+}
+
+func TestWriteTableFileSplit(t *testing.T) {
+	defer func() { splitFiles = false }()
+	splitFiles = true
+	dir := t.TempDir()
+
+	writeTableFile(dir, "posts", "package models\n\ntype Posts struct{}\n", "package models\n", "model")
+	ext := path.Join(dir, "posts.go")
+	if err := ioutil.WriteFile(ext, []byte("package models\n\nfunc (p *Posts) Slug() string { return \"\" }\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// the generated file is replaced without asking, the file written by hand is kept
+	writeTableFile(dir, "posts", "package models\n\ntype Posts struct {\n\tId int\n}\n", "package models\n", "model")
+	gen, _ := ioutil.ReadFile(path.Join(dir, "posts_gen.go"))
+	if !strings.Contains(string(gen), "Id int") {
+		t.Errorf("posts_gen.go was not regenerated:\n%s", gen)
+	}
+	own, _ := ioutil.ReadFile(ext)
+	if !strings.Contains(string(own), "Slug()") {
+		t.Errorf("posts.go was overwritten:\n%s", own)
+	}
+}
+
+func TestSetValidation(t *testing.T) {
+	tables := []*Table{{Name: "orders", Columns: []*Column{
+		{Name: "Id", Type: "int", SqlType: "int(11)", Tag: &OrmTag{Column: "id", Auto: true}},
+		{Name: "Code", Type: "string", SqlType: "varchar(16)", Tag: &OrmTag{Column: "code", Size: "16"}},
+		{Name: "Note", Type: "string", SqlType: "varchar(255)", SqlDefault: "''", Tag: &OrmTag{Column: "note", Size: "255"}},
+		{Name: "Qty", Type: "uint8", SqlType: "tinyint(3) unsigned", Tag: &OrmTag{Column: "qty"}},
+		{Name: "Status", Type: "string", SqlType: "enum('new','it''s paid')", Tag: &OrmTag{Column: "status", Null: true}},
+		{Name: "Flags", Type: "string", SqlType: "set('a','b')", SqlDefault: "a", Tag: &OrmTag{Column: "flags"}},
+		{Name: "Memo", Type: "*string", SqlType: "varchar(64)", Tag: &OrmTag{Column: "memo", Size: "64", Null: true}},
+	}}}
+	setValidation(tables)
+
+	expected := []string{
+		"",
+		"Required;MaxSize(16)",
+		"MaxSize(255)",
+		"Range(0, 255)",
+		"Match(/^(new|it's paid)?$/)",
+		"Match(/^((a|b)(,(a|b))*)?$/)",
+		"",
+	}
+	for i, col := range tables[0].Columns {
+		if got := strings.Join(col.Tag.Valid, ";"); got != expected[i] {
+			t.Errorf("%s: got %q, expected %q", col.Name, got, expected[i])
+		}
+	}
+	if got := tables[0].Columns[1].String(); got != "Code string `orm:\"column(code);size(16)\" json:\"code\" valid:\"Required;MaxSize(16)\"`" {
+		t.Errorf("unexpected field: %s", got)
+	}
+}
+
+func TestAuthAnnotations(t *testing.T) {
+	defer func() { authMode = "" }()
+	authMode = "jwt"
+	if names := withoutAuthTables([]string{"auth_users", "posts", "auth_tokens"}); !reflect.DeepEqual(names, []string{"posts"}) {
+		t.Errorf("auth tables are not left out: %v", names)
+	}
+	db := openSqliteFixture(t)
+	defer db.Close()
+	trans := dbDriver["sqlite3"]
+	tables := getTableObjects([]string{"posts"}, db, trans)
+	setNullableTypes(tables, "zero")
+
+	dir := t.TempDir()
+	mvcPath := &MvcPath{
+		ModelPath:      path.Join(dir, "models"),
+		ControllerPath: path.Join(dir, "controllers"),
+		RouterPath:     path.Join(dir, "routers"),
+	}
+	mode := O_MODEL | O_CONTROLLER | O_ROUTER
+	createPaths(mode, mvcPath)
+	writeSourceFiles("app", tables, nil, mode, mvcPath, nil)
+
+	expected := map[string][]string{
+		"controllers/posts.go": {"// @Auth jwt\n// @Failure 401 missing or invalid credentials\n// @Failure 403 not allowed by conf/rbac.json\n// @router / [post]"},
+		"routers/router.go":    {"// @Auth jwt header Authorization\n", `beego.NSNamespace("/auth"`, "&controllers.AuthController{}"},
+	}
+	for file, parts := range expected {
+		content, err := ioutil.ReadFile(path.Join(dir, file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, part := range parts {
+			if !strings.Contains(string(content), part) {
+				t.Errorf("%s is missing %s:\n%s", file, part, content)
+			}
+		}
+	}
+
+	// the owner method of a model owned through owner_id must not collide with its OwnerId field
+	if _, err := db.Exec("CREATE TABLE auth_users (id INTEGER PRIMARY KEY AUTOINCREMENT, username VARCHAR(128) NOT NULL)"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("CREATE TABLE documents (id INTEGER PRIMARY KEY AUTOINCREMENT, title VARCHAR(128) NOT NULL, owner_id INTEGER NOT NULL REFERENCES auth_users (id))"); err != nil {
+		t.Fatal(err)
+	}
+	tables = getTableObjects([]string{"documents"}, db, trans)
+	useAuthUsers(tables)
+	setNullableTypes(tables, "zero")
+	dir = t.TempDir()
+	mvcPath = &MvcPath{ModelPath: path.Join(dir, "models"), ControllerPath: path.Join(dir, "controllers")}
+	createPaths(O_MODEL|O_CONTROLLER, mvcPath)
+	writeSourceFiles("app", tables, nil, O_MODEL|O_CONTROLLER, mvcPath, nil)
+	writetofile(path.Join(dir, "models", "auth.go"), renderTemplate("api/auth_model.go.tpl", map[string]interface{}{"Auth": "jwt", "PkgPath": "app"}))
+	for _, err := range typeCheck(t, mvcPath.ModelPath) {
+		t.Errorf("models don't compile: %s", err)
+	}
+	ctrl, _ := ioutil.ReadFile(path.Join(mvcPath.ControllerPath, "documents.go"))
+	if !strings.Contains(string(ctrl), "helpers.IsOwner(c.Ctx, v.RowOwner())") {
+		t.Errorf("controller of documents doesn't check the owner:\n%s", ctrl)
+	}
+
+}
+
+func TestOwnerOfUsersTable(t *testing.T) {
+	defer func() { authMode = "" }()
+	db := openSqliteFixture(t)
+	defer db.Close()
+	if _, err := db.Exec("CREATE TABLE orders (id INTEGER PRIMARY KEY AUTOINCREMENT, total INTEGER NOT NULL, owner_id INTEGER NOT NULL REFERENCES user (id))"); err != nil {
+		t.Fatal(err)
+	}
+	trans := dbDriver["sqlite3"]
+	tables := getTableObjects([]string{"user", "orders"}, db, trans)
+	if owner := tables[1].ownerColumn(); owner != nil {
+		t.Fatalf("orders are owned without -auth: %s", owner.Name)
+	}
+	authMode = "jwt"
+	useAuthUsers(tables)
+	setNullableTypes(tables, "zero")
+	if owner := tables[1].ownerColumn(); owner == nil || owner.Tag.Column != "owner_id" {
+		t.Fatalf("orders.owner_id isn't the owner: %v", owner)
+	}
+
+	dir := t.TempDir()
+	os.MkdirAll(path.Join(dir, "conf"), 0755)
+	mvcPath := &MvcPath{ModelPath: path.Join(dir, "models"), ControllerPath: path.Join(dir, "controllers")}
+	createPaths(O_MODEL|O_CONTROLLER, mvcPath)
+	writeSourceFiles("app", tables, nil, O_MODEL|O_CONTROLLER, mvcPath, nil)
+	for _, err := range typeCheck(t, mvcPath.ModelPath) {
+		t.Errorf("models don't compile: %s", err)
+	}
+	ctrl, _ := ioutil.ReadFile(path.Join(mvcPath.ControllerPath, "orders.go"))
+	for _, expected := range []string{"helpers.IsOwner(c.Ctx, v.RowOwner())", `q.Cond = q.Cond.And("owner_id", userId)`, "v.SetRowOwner(userId)"} {
+		if !strings.Contains(string(ctrl), expected) {
+			t.Errorf("controller of orders is missing %s:\n%s", expected, ctrl)
+		}
+	}
+	model, _ := ioutil.ReadFile(path.Join(mvcPath.ModelPath, "orders.go"))
+	if !strings.Contains(string(model), "t.OwnerId = &User{Id: userId}") {
+		t.Errorf("model of orders doesn't set its owner:\n%s", model)
+	}
+
+	writePolicyFile(tables, nil, dir, nil)
+	b, err := ioutil.ReadFile(path.Join(dir, "conf", "rbac.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var policies Policies
+	if err := json.Unmarshal(b, &policies); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(policies["user"]["orders"], []string{"read", "create", "update:own", "delete:own"}) {
+		t.Errorf("orders don't have the :own policies:\n%s", b)
+	}
+}
+
+func TestWritePolicyFile(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(path.Join(dir, "conf"), 0755)
+	kept := `{"user": {"posts": ["read", "update:own"]}, "editor": {"*": ["read", "update"]}}`
+	if err := ioutil.WriteFile(path.Join(dir, "conf", "rbac.json"), []byte(kept), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tables := []*Table{
+		{Name: "posts", Pk: "id"},
+		{Name: "notes", Pk: "id", Columns: []*Column{{Name: "UserId", Type: "*AuthUser", Tag: &OrmTag{Column: "user_id", RelFk: true}}}},
+		{Name: "post_stats", ViewQuery: "SELECT 1"},
+	}
+	writePolicyFile(tables, nil, dir, nil)
+
+	b, err := ioutil.ReadFile(path.Join(dir, "conf", "rbac.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var policies Policies
+	if err := json.Unmarshal(b, &policies); err != nil {
+		t.Fatal(err)
+	}
+	expected := Policies{
+		"admin":  {"*": {"*"}},
+		"editor": {"*": {"read", "update"}},
+		"user": {
+			"posts":      {"read", "update:own"},
+			"notes":      {"read", "create", "update:own", "delete:own"},
+			"post-stats": {"read"},
+			"reports":    {"read"},
+		},
+	}
+	if !reflect.DeepEqual(policies, expected) {
+		t.Errorf("wrong policies:\n%s", b)
+	}
+}
+
+func TestSoftDelete(t *testing.T) {
+	defer func() { authMode = "" }()
+	authMode = "jwt"
+	db := openSqliteFixture(t)
+	defer db.Close()
+	if _, err := db.Exec("CREATE TABLE events (id INTEGER PRIMARY KEY AUTOINCREMENT, name VARCHAR(64), deleted_at DATETIME, created_by INTEGER, updated_by VARCHAR(128))"); err != nil {
+		t.Fatal(err)
+	}
+	trans := dbDriver["sqlite3"]
+	tables := getTableObjects([]string{"events"}, db, trans)
+	setNullableTypes(tables, "pointer")
+
+	dir := t.TempDir()
+	mvcPath := &MvcPath{
+		ModelPath:      path.Join(dir, "models"),
+		ControllerPath: path.Join(dir, "controllers"),
+		RouterPath:     path.Join(dir, "routers"),
+	}
+	mode := O_MODEL | O_CONTROLLER
+	createPaths(mode, mvcPath)
+	writeSourceFiles("app", tables, nil, mode, mvcPath, nil)
+
+	expected := map[string][]string{
+		"models/events.go": {
+			`"time"`,
+			`Filter("id", id).Filter("deleted_at__isnull", true), join)`,
+			`Update(orm.Params{"deleted_at": time.Now()})`,
+			// the cursor condition of Paginate extends q.Cond, which holds the live filter of the page
+			"if !q.WithDeleted {\n\t\tq.Cond = q.Cond.And(\"deleted_at__isnull\", true)\n\t}\n\tqs := o.QueryTable(new(Events)).SetCond(q.Cond)",
+			"createdBy := int(p.UserId)\n\tt.CreatedBy = &createdBy\n",
+			"updatedBy := p.Username\n\t\tt.UpdatedBy = &updatedBy\n",
+			"return deleteEvents(o, ids[i])",
+		},
+		"controllers/events.go": {
+			"get = models.GetEventsByIdWithDeleted",
+			"v.AuditCreate(helpers.CurrentPrincipal(c.Ctx))",
+			"keys = v.AuditUpdate(helpers.CurrentPrincipal(c.Ctx), keys)",
+			"k = v.AuditUpdate(helpers.CurrentPrincipal(c.Ctx), k)",
+			"// @router /bulk [delete]",
+			"// @router /import [post]",
+			"models.ExportEvents(q, export)",
+		},
+	}
+	for file, parts := range expected {
+		content, err := ioutil.ReadFile(path.Join(dir, file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, part := range parts {
+			if !strings.Contains(string(content), part) {
+				t.Errorf("%s is missing %s:\n%s", file, part, content)
+			}
+		}
+	}
+	model, _ := ioutil.ReadFile(path.Join(dir, "models/events.go"))
+	if strings.Contains(string(model), "qs = qs.Filter(") {
+		t.Errorf("the live filter of events is lost by the cursor condition of Paginate:\n%s", model)
+	}
+}
+
+func TestKeysetPage(t *testing.T) {
+	db := openSqliteFixture(t)
+	defer db.Close()
+	if _, err := db.Exec("CREATE TABLE events (id INTEGER PRIMARY KEY AUTOINCREMENT, name VARCHAR(64) NOT NULL, starts_at DATETIME, deleted_at DATETIME)"); err != nil {
+		t.Fatal(err)
+	}
+	trans := dbDriver["sqlite3"]
+	tables := getTableObjects([]string{"events"}, db, trans)
+	setNullableTypes(tables, "pointer")
+
+	dir := t.TempDir()
+	mvcPath := &MvcPath{ModelPath: path.Join(dir, "models")}
+	createPaths(O_MODEL, mvcPath)
+	writeSourceFiles("app", tables, nil, O_MODEL, mvcPath, nil)
+	model, err := ioutil.ReadFile(path.Join(mvcPath.ModelPath, "events.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a cursor can't hold NULL, pages sorted by starts_at use offsets
+	for _, part := range []string{`Nullable: []string{"starts_at", "deleted_at"}`, "q.Paginate(qs.SetCond(q.Where()))"} {
+		if !strings.Contains(string(model), part) {
+			t.Errorf("model of events is missing %s:\n%s", part, model)
+		}
+	}
+
+	// the cursor condition extends q.Cond, the query set given to Paginate keeps its filters
+	helper := renderTemplate("api/pagination.go.tpl", nil)
+	paginate := helper[strings.Index(helper, "func (q *Query) Paginate"):strings.Index(helper, "func (q *Query) Where")]
+	if strings.Contains(paginate, ".SetCond(") {
+		t.Errorf("Paginate replaces the condition of the query set:\n%s", paginate)
+	}
+	if !strings.Contains(helper, "contains(q.fields.Nullable, q.sortKey())") {
+		t.Errorf("keyset pages are not refused for nullable sort fields:\n%s", helper)
+	}
+}
+
+func TestReport(t *testing.T) {
+	db := openSqliteFixture(t)
+	defer db.Close()
+	report, err := parseReportSql("post_scores", `-- @description scores of the posts
+-- @param author string required "name of the author"
+-- @param min float
+-- @column total float64
+SELECT user.name AS author, posts.title, SUM(posts.score) AS total
+FROM posts JOIN user ON user.id = posts.author_id
+WHERE user.name = :author AND (:min IS NULL OR posts.score >= :min) AND posts.title <> ':title'
+GROUP BY user.name, posts.title;
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(report.Placeholders, []string{"author", "min", "min"}) {
+		t.Fatalf("unexpected placeholders: %v", report.Placeholders)
+	}
+	if !strings.Contains(report.Query, "user.name = ? AND (? IS NULL OR posts.score >= ?)") || strings.HasSuffix(report.Query, ";") || strings.Contains(report.Query, "@param") {
+		t.Fatalf("unexpected query: %s", report.Query)
+	}
+	if p := report.Params[0]; !p.Required || p.Description != "name of the author" {
+		t.Fatalf("unexpected param: %+v", p)
+	}
+	if err = report.readColumns(db, "sqlite3", dbDriver["sqlite3"]); err != nil {
+		t.Fatal(err)
+	}
+	var fields []string
+	for _, col := range report.Columns {
+		fields = append(fields, col.String())
+	}
+	want := []string{"Author string `orm:\"column(author);null\" json:\"author\"`", "Title string `orm:\"column(title);null\" json:\"title\"`", "Total float64 `orm:\"column(total)\" json:\"total\"`"}
+	if !reflect.DeepEqual(fields, want) {
+		t.Fatalf("unexpected row fields:\n%s", strings.Join(fields, "\n"))
+	}
+
+	for _, content := range []string{"-- @param limit int\nSELECT 1", "SELECT :day", "-- @param day date\nSELECT :day"} {
+		if _, err := parseReportSql("bad", content); err == nil {
+			t.Errorf("no error for %q", content)
+		}
+	}
+	if _, names, _ := replacePlaceholders("SELECT x::date, :a -- :b\n", map[string]*ReportParam{"a": {}}); !reflect.DeepEqual(names, []string{"a"}) {
+		t.Errorf("unexpected placeholders: %v", names)
+	}
+}
+
+// typeCheck type-checks the package in dir. The imported packages aren't available to the tests,
+// their uses are not checked.
+func typeCheck(t *testing.T, dir string) (errs []error) {
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, dir, nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, pkg := range pkgs {
+		var files []*ast.File
+		for _, f := range pkg.Files {
+			files = append(files, f)
+		}
+		conf := types.Config{
+			Importer: importerFunc(func(path string) (*types.Package, error) { return nil, fmt.Errorf("not available") }),
+			Error: func(err error) {
+				if !strings.Contains(err.Error(), "could not import") {
+					errs = append(errs, err)
+				}
+			},
+		}
+		conf.Check(pkg.Name, fset, files, nil)
+	}
+	return
+}
+
+type importerFunc func(path string) (*types.Package, error)
+
+func (f importerFunc) Import(path string) (*types.Package, error) { return f(path) }
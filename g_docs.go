@@ -39,6 +39,7 @@ var globalDocsTemplate = `package docs
 
 import (
 	"encoding/json"
+	"fmt"
 	"strings"
 
 	"github.com/aamsur/beego"
@@ -48,6 +49,7 @@ import (
 const (
     Rootinfo string = {{.rootinfo}}
     Subapi string = {{.subapi}}
+    Authinfo string = {{.authinfo}}
     BasePath string= "{{.version}}"
 )
 
@@ -72,6 +74,55 @@ func init() {
 		v.BasePath = BasePath
 		beego.GlobalDocApi[strings.Trim(k, "/")] = v
 	}
+	addAuthorizations()
+}
+
+// addAuthorizations adds the authorizations of the @Auth annotations to the docs
+func addAuthorizations() {
+	var auth struct {
+		Authorizations map[string]interface{}
+		Operations     map[string]map[string][]string
+	}
+	if err := json.Unmarshal([]byte(Authinfo), &auth); err != nil || len(auth.Authorizations) == 0 {
+		return
+	}
+	root := docMap(rootapi)
+	root["authorizations"] = auth.Authorizations
+	beego.GlobalDocApi["Root"] = root
+	for k, v := range apilist {
+		schemes := make(map[string][]string)
+		for op, names := range auth.Operations[k] {
+			schemes[urlReplace(k+op)] = names
+		}
+		doc := docMap(v)
+		apis, _ := doc["apis"].([]interface{})
+		for _, a := range apis {
+			api, _ := a.(map[string]interface{})
+			ops, _ := api["operations"].([]interface{})
+			for _, o := range ops {
+				op, _ := o.(map[string]interface{})
+				method, _ := op["httpMethod"].(string)
+				if method == "" {
+					method, _ = op["method"].(string)
+				}
+				if names, ok := schemes[fmt.Sprint(api["path"])+" "+method]; ok {
+					authorizations := make(map[string][]interface{})
+					for _, name := range names {
+						authorizations[name] = []interface{}{}
+					}
+					op["authorizations"] = authorizations
+				}
+			}
+		}
+		beego.GlobalDocApi[strings.Trim(k, "/")] = doc
+	}
+}
+
+func docMap(v interface{}) map[string]interface{} {
+	m := make(map[string]interface{})
+	b, _ := json.Marshal(v)
+	json.Unmarshal(b, &m)
+	return m
 }
 
 
@@ -104,9 +155,15 @@ var apilist map[string]*swagger.ApiDeclaration
 var controllerList map[string][]swagger.Api
 var modelsList map[string]map[string]swagger.Model
 var rootapi swagger.ResourceListing
+var authDefinitions map[string]interface{}        //scheme name:swagger authorization
+var controllerAuth map[string]map[string][]string //controller:"path METHOD":scheme names
+var apiAuth map[string]map[string][]string        //resource path:"path METHOD":scheme names
 
 func init() {
 	pkgCache = make(map[string]bool)
+	authDefinitions = make(map[string]interface{})
+	controllerAuth = make(map[string]map[string][]string)
+	apiAuth = make(map[string]map[string][]string)
 	controllerComments = make(map[string]string)
 	importlist = make(map[string]string)
 	apilist = make(map[string]*swagger.ApiDeclaration)
@@ -144,6 +201,11 @@ func generateDocs(curpath string) {
 					rootapi.Infos.License = strings.TrimSpace(s[len("@License"):])
 				} else if strings.HasPrefix(s, "@LicenseUrl") {
 					rootapi.Infos.LicenseUrl = strings.TrimSpace(s[len("@LicenseUrl"):])
+				} else if strings.HasPrefix(s, "@Auth") {
+					// @Auth name passAs keyname, e.g. @Auth jwt header Authorization
+					if p := strings.Fields(s[len("@Auth"):]); len(p) == 3 {
+						authDefinitions[p[0]] = apiKeyAuthorization(p[1], p[2])
+					}
 				}
 			}
 		}
@@ -200,6 +262,10 @@ func generateDocs(curpath string) {
 	if err != nil {
 		panic(err)
 	}
+	authinfo, err := json.Marshal(docsAuthinfo())
+	if err != nil {
+		panic(err)
+	}
 	os.Mkdir(path.Join(curpath, "docs"), 0755)
 	fd, err := os.Create(path.Join(curpath, "docs", "docs.go"))
 	if err != nil {
@@ -208,6 +274,7 @@ func generateDocs(curpath string) {
 	defer fd.Close()
 	a := strings.Replace(globalDocsTemplate, "{{.rootinfo}}", "`"+string(apiinfo)+"`", -1)
 	a = strings.Replace(a, "{{.subapi}}", "`"+string(subapi)+"`", -1)
+	a = strings.Replace(a, "{{.authinfo}}", "`"+string(authinfo)+"`", -1)
 	fd.WriteString(a)
 }
 
@@ -253,9 +320,41 @@ func analisysNSInclude(baseurl string, ce *ast.CallExpr) string {
 		}
 	}
 	apilist[baseurl] = a
+	for op, names := range controllerAuth[cname] {
+		if apiAuth[baseurl] == nil {
+			apiAuth[baseurl] = make(map[string][]string)
+		}
+		apiAuth[baseurl][op] = names
+	}
 	return cname
 }
 
+// apiKeyAuthorization returns the swagger authorization of credentials passed as a header or
+// query parameter
+func apiKeyAuthorization(passAs, keyname string) map[string]string {
+	return map[string]string{"type": "apiKey", "passAs": passAs, "keyname": keyname}
+}
+
+// docsAuthinfo returns the authorizations of the docs and the schemes of each operation. The
+// schemes of -auth are defined by default, others by an @Auth name passAs keyname line of router.go.
+func docsAuthinfo() map[string]interface{} {
+	used := make(map[string]interface{})
+	for _, ops := range apiAuth {
+		for _, names := range ops {
+			for _, name := range names {
+				if def, ok := authDefinitions[name]; ok {
+					used[name] = def
+				} else if header, ok := authSchemes[name]; ok {
+					used[name] = apiKeyAuthorization("header", header)
+				} else {
+					ColorLog("[WARN] @Auth %s is not defined in router.go\n", name)
+				}
+			}
+		}
+	}
+	return map[string]interface{}{"authorizations": used, "operations": apiAuth}
+}
+
 func analisyscontrollerPkg(pkgpath string) {
 	pkgpath = strings.Trim(pkgpath, "\"")
 	if isSystemPackage(pkgpath) {
@@ -347,6 +446,7 @@ func isSystemPackage(pkgpath string) bool {
 func parserComments(comments *ast.CommentGroup, funcName, controllerName, pkgpath string) error {
 	innerapi := swagger.Api{}
 	opts := swagger.Operation{}
+	var auth []string
 	if comments != nil && comments.List != nil {
 		for _, c := range comments.List {
 			t := strings.TrimSpace(strings.TrimLeft(c.Text, "//"))
@@ -452,6 +552,10 @@ func parserComments(comments *ast.CommentGroup, funcName, controllerName, pkgpat
 				}
 				rs.Code, _ = strconv.Atoi(string(cd))
 				opts.ResponseMessages = append(opts.ResponseMessages, rs)
+			} else if strings.HasPrefix(t, "@Auth") {
+				auth = append(auth, strings.FieldsFunc(t[len("@Auth"):], func(r rune) bool {
+					return r == ',' || unicode.IsSpace(r)
+				})...)
 			} else if strings.HasPrefix(t, "@Type") {
 				opts.Type = strings.TrimSpace(t[len("@Type"):])
 			} else if strings.HasPrefix(t, "@Accept") {
@@ -476,6 +580,12 @@ func parserComments(comments *ast.CommentGroup, funcName, controllerName, pkgpat
 		}
 	}
 	innerapi.Operations = append(innerapi.Operations, opts)
+	if innerapi.Path != "" && len(auth) > 0 {
+		if controllerAuth[pkgpath+controllerName] == nil {
+			controllerAuth[pkgpath+controllerName] = make(map[string][]string)
+		}
+		controllerAuth[pkgpath+controllerName][innerapi.Path+" "+opts.HttpMethod] = auth
+	}
 	if innerapi.Path != "" {
 		if _, ok := controllerList[pkgpath+controllerName]; ok {
 			controllerList[pkgpath+controllerName] = append(controllerList[pkgpath+controllerName], innerapi)
@@ -18,7 +18,6 @@ import (
 	"fmt"
 	"os"
 	"path"
-	"strings"
 	"time"
 )
 
@@ -30,7 +29,8 @@ const (
 // generateMigration generates migration file template for database schema update.
 // The generated file template consists of an up() method for updating schema and
 // a down() method for reverting the update.
-func generateMigration(mname, upsql, downsql, curpath string) {
+// The migration embeds txMigration of bee_migration.go, which runs it in a transaction.
+func generateMigration(mname, upsql, downsql, curpath, driver string) {
 	migrationFilePath := path.Join(curpath, "database", M_PATH)
 	if _, err := os.Stat(migrationFilePath); os.IsNotExist(err) {
 		// create migrations directory
@@ -39,15 +39,22 @@ func generateMigration(mname, upsql, downsql, curpath string) {
 			os.Exit(2)
 		}
 	}
+	if err := writeMigrationBase(migrationFilePath); err != nil {
+		ColorLog("[ERRO] Could not write %s: %s\n", MIGRATION_BASE_FILE, err)
+		os.Exit(2)
+	}
 	// create file
 	today := time.Now().Format(M_DATE_FORMAT)
 	fpath := path.Join(migrationFilePath, fmt.Sprintf("%s_%s.go", today, mname))
 	if f, err := os.OpenFile(fpath, os.O_CREATE|os.O_EXCL|os.O_RDWR, 0666); err == nil {
 		defer f.Close()
-		content := strings.Replace(MIGRATION_TPL, "{{StructName}}", camelCase(mname)+"_"+today, -1)
-		content = strings.Replace(content, "{{CurrTime}}", today, -1)
-		content = strings.Replace(content, "{{UpSQL}}", upsql, -1)
-		content = strings.Replace(content, "{{DownSQL}}", downsql, -1)
+		content := renderTemplate("migration.go.tpl", map[string]interface{}{
+			"StructName": camelCase(mname) + "_" + today,
+			"CurrTime":   today,
+			"UpSQL":      upsql,
+			"DownSQL":    downsql,
+			"Prelude":    migrationPrelude(driver),
+		})
 		f.WriteString(content)
 		// gofmt generated source code
 		formatSourceCode(fpath)
@@ -59,33 +66,11 @@ func generateMigration(mname, upsql, downsql, curpath string) {
 	}
 }
 
-const MIGRATION_TPL = `package main
-
-import (
-	"github.com/aamsur/beego/migration"
-)
-
-// DO NOT MODIFY
-type {{StructName}} struct {
-	migration.Migration
-}
-
-// DO NOT MODIFY
-func init() {
-	m := &{{StructName}}{}
-	m.Created = "{{CurrTime}}"
-	migration.Register("{{StructName}}", m)
-}
-
-// Run the migrations
-func (m *{{StructName}}) Up() {
-	m.Sql("SET FOREIGN_KEY_CHECKS =0;")
-	{{UpSQL}}
-}
-
-// Reverse the migrations
-func (m *{{StructName}}) Down() {
-	m.Sql("SET FOREIGN_KEY_CHECKS =0;")
-	{{DownSQL}}
+// migrationPrelude returns the statement opening the methods of a migration: MySQL doesn't check
+// the foreign keys while the tables are changed
+func migrationPrelude(driver string) string {
+	if driver == "mysql" {
+		return `m.Sql("SET FOREIGN_KEY_CHECKS =0;")`
+	}
+	return ""
 }
-`
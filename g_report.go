@@ -0,0 +1,385 @@
+// Copyright 2013 bee authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Report is a report read from an SQL file by bee generate report
+type Report struct {
+	Name         string // snake case name, e.g. daily_sales
+	SqlFile      string
+	Description  string
+	Query        string         // the SQL with a ? for each placeholder, without the annotations
+	Params       []*ReportParam // declared by -- @param name type [required] [description]
+	Placeholders []string       // the parameter of each placeholder of Query, in order
+	Columns      []*Column      // the row of the report
+	columnTypes  map[string]string
+}
+
+// ReportParam is a query parameter of a report
+type ReportParam struct {
+	Name        string
+	Type        string // string, int, uint, float, bool or time, as the filters of helpers.QueryFields
+	Required    bool
+	Description string
+}
+
+// reportParamTypes are the Go types of the report parameter types
+var reportParamTypes = map[string]string{
+	"string": "string",
+	"int":    "int64",
+	"uint":   "uint64",
+	"float":  "float64",
+	"bool":   "bool",
+	"time":   "string",
+}
+
+// reservedReportParams are the query parameters of the report endpoints themselves
+var reservedReportParams = map[string]bool{
+	"limit": true, "offset": true, "count": true, "cursor": true, "format": true, "query": true,
+	"fields": true, "groupby": true, "sortby": true, "order": true, "join": true, "with_deleted": true,
+}
+
+var reportNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// reportAnnotation matches the annotation lines of a report SQL file, e.g. -- @param day time required
+var reportAnnotation = regexp.MustCompile(`^\s*--\s*@(\w+)\s*(.*)$`)
+
+// generateReport generates the model and the endpoint of a report from an SQL file. The columns
+// of its rows are read from the database by running the query without rows.
+func generateReport(name, sqlFile, curpath, dbms, connStr, nullable string) {
+	name = strings.ToLower(snakeString(strings.Replace(name, "-", "_", -1)))
+	if !reportNamePattern.MatchString(name) {
+		ColorLog("[ERRO] Invalid report name: %s\n", name)
+		ColorLog("[HINT] Use letters, digits and underscores, e.g. daily_sales\n")
+		os.Exit(2)
+	}
+	switch nullable {
+	case "pointer", "sql", "zero":
+	default:
+		ColorLog("[ERRO] Invalid 'nullable' option: %s\n", nullable)
+		ColorLog("[HINT] Nullable must be either pointer, sql or zero\n")
+		os.Exit(2)
+	}
+	trans, ok := dbDriver[dbms]
+	if !ok {
+		ColorLog("[ERRO] Unknown database driver: %s\n", dbms)
+		ColorLog("[HINT] Driver must be one of mysql, postgres or sqlite\n")
+		os.Exit(2)
+	}
+	content, err := ioutil.ReadFile(sqlFile)
+	if err != nil {
+		ColorLog("[ERRO] Could not read the report query: %s\n", err)
+		os.Exit(2)
+	}
+	report, err := parseReportSql(name, string(content))
+	if err != nil {
+		ColorLog("[ERRO] %s: %s\n", sqlFile, err)
+		os.Exit(2)
+	}
+	report.SqlFile = path.Base(sqlFile)
+
+	db, err := sql.Open(dbms, connStr)
+	if err != nil {
+		ColorLog("[ERRO] Could not connect to %s database: %s, %s\n", dbms, connStr, err)
+		os.Exit(2)
+	}
+	defer db.Close()
+	if err = report.readColumns(db, dbms, trans); err != nil {
+		ColorLog("[ERRO] Could not run the report query: %s\n", err)
+		ColorLog("[HINT] Every parameter is NULL while the columns are read\n")
+		os.Exit(2)
+	}
+	tb := &Table{Name: name, Columns: report.Columns}
+	setNullableTypes([]*Table{tb}, nullable)
+
+	mPath := path.Join(curpath, "models")
+	cPath := path.Join(curpath, "controllers")
+	createPaths(O_MODEL|O_CONTROLLER, &MvcPath{ModelPath: mPath, ControllerPath: cPath})
+	data := report.templateData(getPackagePath(curpath))
+	data["TimePkg"] = reportImports(tb)
+	writeGeneratedFile(path.Join(mPath, "report_"+name+".go"), renderTemplate("appcode/report_model.go.tpl", data), "model", false)
+	writeGeneratedFile(path.Join(cPath, "report_"+name+".go"), renderTemplate("appcode/report_controller.go.tpl", data), "controller", false)
+
+	// applications created before the reports were generated lack the shared parts
+	if fpath := path.Join(cPath, "reports.go"); !isExist(fpath) {
+		writeGeneratedFile(fpath, renderTemplate("api/reports.go.tpl", data), "controller", false)
+	}
+	if fpath := path.Join(curpath, "helpers", "report.go"); !isExist(fpath) && isExist(path.Dir(fpath)) {
+		writeGeneratedFile(fpath, renderTemplate("api/report.go.tpl", data), "helper", false)
+	}
+}
+
+// parseReportSql reads the annotations of a report SQL file and replaces its :name placeholders
+// by ?. The annotations are comment lines:
+//
+//	-- @description daily sales of a store
+//	-- @param day time required the day of the sales
+//	-- @column total float64
+//
+// A column annotation sets the Go type of a column the database doesn't tell.
+func parseReportSql(name, content string) (*Report, error) {
+	report := &Report{Name: name, columnTypes: make(map[string]string)}
+	params := make(map[string]*ReportParam)
+	var lines []string
+	for _, line := range strings.Split(content, "\n") {
+		m := reportAnnotation.FindStringSubmatch(line)
+		if m == nil {
+			lines = append(lines, strings.TrimRight(line, " \t\r"))
+			continue
+		}
+		fields := strings.Fields(m[2])
+		switch m[1] {
+		case "description":
+			report.Description = strings.TrimSpace(m[2])
+		case "param":
+			if len(fields) < 2 {
+				return nil, fmt.Errorf("a parameter is declared as @param name type [required] [description]: %s", line)
+			}
+			p := &ReportParam{Name: fields[0], Type: fields[1]}
+			if _, ok := reportParamTypes[p.Type]; !ok {
+				return nil, fmt.Errorf("parameter %s: unknown type %s, use string, int, uint, float, bool or time", p.Name, p.Type)
+			}
+			if reservedReportParams[p.Name] || params[p.Name] != nil {
+				return nil, fmt.Errorf("parameter %s is already a parameter of the report endpoints", p.Name)
+			}
+			fields = fields[2:]
+			if len(fields) > 0 && fields[0] == "required" {
+				p.Required, fields = true, fields[1:]
+			}
+			p.Description = strings.Trim(strings.Join(fields, " "), `"`)
+			params[p.Name] = p
+			report.Params = append(report.Params, p)
+		case "column":
+			if len(fields) != 2 {
+				return nil, fmt.Errorf("a column is declared as @column name type: %s", line)
+			}
+			report.columnTypes[fields[0]] = fields[1]
+		default:
+			return nil, fmt.Errorf("unknown annotation @%s", m[1])
+		}
+	}
+	query := strings.TrimSpace(strings.Join(lines, "\n"))
+	query = strings.TrimSpace(strings.TrimSuffix(query, ";"))
+	if query == "" {
+		return nil, fmt.Errorf("the file has no query")
+	}
+
+	var err error
+	report.Query, report.Placeholders, err = replacePlaceholders(query, params)
+	return report, err
+}
+
+// replacePlaceholders replaces the :name placeholders of a query by ?, leaving the quoted text,
+// the comments and the :: casts of PostgreSQL alone
+func replacePlaceholders(query string, params map[string]*ReportParam) (string, []string, error) {
+	var b strings.Builder
+	var names []string
+	isName := func(c byte) bool {
+		return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+	}
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case c == '\'' || c == '"' || c == '`':
+			end := strings.IndexByte(query[i+1:], c)
+			if end < 0 {
+				return "", nil, fmt.Errorf("unterminated %c quote", c)
+			}
+			b.WriteString(query[i : i+end+2])
+			i += end + 1
+		case c == '-' && strings.HasPrefix(query[i:], "--"):
+			end := strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				end = len(query) - i
+			}
+			b.WriteString(query[i : i+end])
+			i += end - 1
+		case c == ':' && i+1 < len(query) && isName(query[i+1]) && (i == 0 || query[i-1] != ':'):
+			j := i + 1
+			for j < len(query) && isName(query[j]) {
+				j++
+			}
+			name := query[i+1 : j]
+			if params[name] == nil {
+				return "", nil, fmt.Errorf("placeholder :%s has no @param", name)
+			}
+			names = append(names, name)
+			b.WriteByte('?')
+			i = j - 1
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String(), names, nil
+}
+
+// readColumns runs the query of a report without rows to read its columns, with NULL parameters
+func (report *Report) readColumns(db *sql.DB, dbms string, trans DbTransformer) error {
+	query := report.Query + "\nLIMIT 0"
+	if dbms == "postgres" {
+		// the driver numbers its placeholders
+		parts := strings.Split(query, "?")
+		query = parts[0]
+		for i, part := range parts[1:] {
+			query += "$" + strconv.Itoa(i+1) + part
+		}
+	}
+	args := make([]interface{}, len(report.Placeholders))
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		return err
+	}
+
+	seen := make(map[string]bool)
+	for _, t := range types {
+		name := t.Name()
+		if !reportNamePattern.MatchString(strings.ToLower(name)) {
+			return fmt.Errorf("column %q needs a name, give it an alias", name)
+		}
+		if seen[name] {
+			return fmt.Errorf("column %s is read twice, give one an alias", name)
+		}
+		seen[name] = true
+		col := &Column{Name: camelCase(name), Tag: &OrmTag{Column: name}}
+		if goType, ok := report.columnTypes[name]; ok {
+			// declared types are kept as they are
+			col.Type = goType
+		} else {
+			col.SqlType = reportSqlType(dbms, t.DatabaseTypeName())
+			if col.SqlType == "" {
+				ColorLog("[WARN] %s: the database doesn't tell the type of column %s, using string\n", report.Name, name)
+				ColorLog("[HINT] Declare it with -- @column %s <Go type>\n", name)
+			}
+			col.Type = trans.GetGoDataType(col.SqlType)
+			nullable, ok := t.Nullable()
+			col.Tag.Null = nullable || !ok
+		}
+		report.Columns = append(report.Columns, col)
+	}
+	for name := range report.columnTypes {
+		if !seen[name] {
+			ColorLog("[WARN] %s: the declared column %s isn't read by the query\n", report.Name, name)
+		}
+	}
+	return nil
+}
+
+// postgresTypeNames are the names of the PostgreSQL types as told by the driver, in the type map
+var postgresTypeNames = map[string]string{
+	"int2":   "smallint",
+	"int4":   "integer",
+	"int8":   "bigint",
+	"float4": "real",
+	"float8": "double precision",
+	"bool":   "boolean",
+	"bpchar": "char",
+}
+
+// reportSqlType maps the type name of a result column given by a driver to the type map of the
+// driver, e.g. UNSIGNED INT => int unsigned
+func reportSqlType(dbms, typeName string) string {
+	t := sqlBaseType(typeName)
+	switch dbms {
+	case "mysql":
+		if strings.HasPrefix(t, "unsigned ") {
+			t = strings.TrimPrefix(t, "unsigned ") + " unsigned"
+		}
+	case "postgres":
+		if name, ok := postgresTypeNames[t]; ok {
+			t = name
+		}
+	}
+	return t
+}
+
+// reportImports returns the imports of the row struct of a report
+func reportImports(tb *Table) string {
+	imports := ""
+	for _, col := range tb.Columns {
+		if strings.Contains(col.Type, "time.") {
+			imports = "\"time\"\n"
+		}
+	}
+	if tb.ImportSqlPkg {
+		imports += "\"database/sql\"\n"
+	}
+	return imports
+}
+
+// templateData returns the data of the report templates
+func (report *Report) templateData(pkgPath string) map[string]interface{} {
+	camel := camelCase(report.Name)
+	rv := fmt.Sprintf("type Report%s struct {\n", camel)
+	for _, col := range report.Columns {
+		rv += col.String() + "\n"
+	}
+	rv += "}\n"
+
+	var args []string
+	for _, name := range report.Placeholders {
+		args = append(args, strconv.Quote(name))
+	}
+	query := strconv.Quote(report.Query)
+	if !strings.Contains(report.Query, "`") {
+		query = "`" + report.Query + "`"
+	}
+	description := report.Description
+	if description == "" {
+		description = "run the " + report.Name + " report"
+	}
+	var docParams []string
+	for _, p := range report.Params {
+		desc := p.Description
+		if desc == "" {
+			desc = p.Name + " of the report"
+		}
+		if p.Type == "time" {
+			desc += ", RFC 3339 or 2006-01-02"
+		}
+		docParams = append(docParams, fmt.Sprintf("// @Param\t%s\tquery\t%s\t%t\t%q\n", p.Name, reportParamTypes[p.Type], p.Required, desc))
+	}
+	return map[string]interface{}{
+		"Name":        report.Name,
+		"ModelName":   "Report" + camel,
+		"FuncName":    "GetReport" + camel,
+		"VarName":     "report" + camel,
+		"FileName":    "report_" + report.Name,
+		"SqlFile":     report.SqlFile,
+		"ModelStruct": rv,
+		"Params":      report.Params,
+		"Query":       query,
+		"Args":        strings.Join(args, ", "),
+		"Description": description,
+		"DocParams":   strings.Join(docParams, ""),
+		"Route":       strings.Replace(report.Name, "_", "-", -1),
+		"PkgPath":     pkgPath,
+		"AuthDoc":     authAnnotation(),
+	}
+}
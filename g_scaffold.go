@@ -31,7 +31,7 @@ func generateScaffold(sname, fields, crupath, driver, conn string) {
 			upsql = `m.Sql("CREATE TABLE ` + sname + "(" + generateSQLFromFields(fields) + `)");`
 			downsql = `m.Sql("DROP TABLE ` + "`" + sname + "`" + `")`
 		}
-		generateMigration(sname, upsql, downsql, crupath)
+		generateMigration(sname, upsql, downsql, crupath, driver)
 	}
 	// run migration
 	ColorLog("[INFO] Do you want to go ahead and migrate the database? [yes|no]]  ")
@@ -0,0 +1,818 @@
+// Copyright 2013 bee authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// generateMigrationFromModels compares the ORM models in the models directory against the
+// live database schema and generates a migration with the statements needed to bring the
+// schema in line with the models.
+func generateMigrationFromModels(mname, curpath, driver, connStr string) {
+	trans, ok := dbDriver[driver]
+	if !ok {
+		ColorLog("[ERRO] Generating migration from %s database is not supported yet.\n", driver)
+		os.Exit(2)
+	}
+	db, err := sql.Open(driver, connStr)
+	if err != nil {
+		ColorLog("[ERRO] Could not connect to %s database: %s, %s\n", driver, connStr, err)
+		os.Exit(2)
+	}
+	defer db.Close()
+
+	ColorLog("[INFO] Analyzing models...\n")
+	models := getModelTables(path.Join(curpath, "models"), driver)
+	if len(models) == 0 {
+		ColorLog("[ERRO] No ORM model found in %s\n", path.Join(curpath, "models"))
+		os.Exit(2)
+	}
+	ColorLog("[INFO] Analyzing database tables...\n")
+	dbTables := getTableObjects(trans.GetTableNames(db), db, trans)
+
+	up, down := diffSchema(driver, models, dbTables)
+	if len(up) == 0 {
+		ColorLog("[INFO] Database schema is up to date with the models, nothing to migrate\n")
+		return
+	}
+	generateMigration(mname, migrationSqlCalls(up), migrationSqlCalls(down), curpath, driver)
+}
+
+// migrationSqlCalls turns SQL statements into m.Sql() calls of a migration
+func migrationSqlCalls(stmts []string) string {
+	calls := make([]string, len(stmts))
+	for i, stmt := range stmts {
+		calls[i] = fmt.Sprintf("m.Sql(%q)", stmt)
+	}
+	return strings.Join(calls, "\n")
+}
+
+// getModelTables parses the Go sources in dir and returns a table for every struct
+// that is an ORM model, that is a struct with orm tags or a TableName method. Structs embedded
+// by models add their columns to the tables of the models.
+// Column SQL types are those beego orm would create for the driver.
+func getModelTables(dir, driver string) (tables []*Table) {
+	fileSet := token.NewFileSet()
+	astPkgs, err := parser.ParseDir(fileSet, dir, func(info os.FileInfo) bool {
+		name := info.Name()
+		return !info.IsDir() && !strings.HasPrefix(name, ".") && strings.HasSuffix(name, ".go") && !strings.HasSuffix(name, "_test.go")
+	}, 0)
+	if err != nil {
+		ColorLog("[ERRO] Could not parse models in %s: %s\n", dir, err)
+		os.Exit(2)
+	}
+
+	structs := make(map[string]*ast.StructType)
+	tableNames := make(map[string]string)
+	for _, pkg := range astPkgs {
+		for _, fl := range pkg.Files {
+			for _, d := range fl.Decls {
+				switch decl := d.(type) {
+				case *ast.GenDecl:
+					for _, spec := range decl.Specs {
+						if ts, ok := spec.(*ast.TypeSpec); ok {
+							if st, ok := ts.Type.(*ast.StructType); ok {
+								structs[ts.Name.Name] = st
+							}
+						}
+					}
+				case *ast.FuncDecl:
+					if name, ok := tableNameMethod(decl); ok {
+						tableNames[receiverName(decl)] = name
+					}
+				}
+			}
+		}
+	}
+
+	// structs embedded by others hold columns of their tables, they aren't tables themselves
+	embedded := make(map[string]bool)
+	for _, st := range structs {
+		for _, field := range st.Fields.List {
+			if len(field.Names) == 0 {
+				embedded[strings.TrimPrefix(exprString(field.Type), "*")] = true
+			}
+		}
+	}
+	var names []string
+	for name, st := range structs {
+		if _, ok := tableNames[name]; ok || (hasOrmTag(st) && !embedded[name]) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	tableOf := func(structName string) string {
+		if name, ok := tableNames[structName]; ok {
+			return name
+		}
+		return snakeString(structName)
+	}
+
+	for _, name := range names {
+		tb := &Table{Name: tableOf(name), Fk: make(map[string]*ForeignKey)}
+		var addFields func(st *ast.StructType)
+		addFields = func(st *ast.StructType) {
+			for _, field := range st.Fields.List {
+				var stag reflect.StructTag
+				if field.Tag != nil {
+					stag = reflect.StructTag(strings.Trim(field.Tag.Value, "`"))
+				}
+				ormTag := stag.Get("orm")
+				if ormTag == "-" {
+					continue
+				}
+				// the orm maps the fields of embedded structs to columns of the table
+				if len(field.Names) == 0 {
+					embedded := strings.TrimPrefix(exprString(field.Type), "*")
+					if est, ok := structs[embedded]; ok {
+						addFields(est)
+					} else {
+						ColorLog("[WARN] %s embeds %s, whose columns are unknown: no column of %s is dropped\n", name, embedded, tb.Name)
+						tb.PartialModel = true
+					}
+					continue
+				}
+				for _, fieldName := range field.Names {
+					if !ast.IsExported(fieldName.Name) {
+						continue
+					}
+					col := &Column{Name: fieldName.Name, Type: exprString(field.Type), Tag: parseOrmTag(ormTag)}
+					if col.Tag.ReverseOne || col.Tag.ReverseMany || col.Tag.RelM2M {
+						// reverse and many to many relations have no column in this table
+						continue
+					}
+					if col.Tag.Column == "" {
+						col.Tag.Column = snakeString(col.Name)
+						if col.Tag.RelFk || col.Tag.RelOne {
+							col.Tag.Column += "_id"
+						}
+					}
+					if col.Tag.RelFk || col.Tag.RelOne {
+						tb.Fk[col.Tag.Column] = &ForeignKey{
+							Name:     col.Tag.Column,
+							RefTable: tableOf(strings.TrimPrefix(col.Type, "*")),
+						}
+					}
+					// an int field named Id is the auto increment primary key by convention
+					if col.Tag.Pk || col.Tag.Auto || (tb.Pk == "" && col.Name == "Id" && isGoIntType(col.Type)) {
+						tb.Pk = col.Tag.Column
+						col.Tag.Pk = true
+						col.Tag.Auto = col.Tag.Auto || isGoIntType(col.Type)
+					}
+					tb.Columns = append(tb.Columns, col)
+				}
+			}
+		}
+		addFields(structs[name])
+		tables = append(tables, tb)
+	}
+
+	// resolve SQL types once all primary keys are known, foreign keys take the type of the key they reference
+	pkOf := make(map[string]*Column)
+	for _, tb := range tables {
+		for _, col := range tb.Columns {
+			if col.Tag.Column == tb.Pk {
+				pkOf[tb.Name] = col
+			}
+		}
+	}
+	for _, tb := range tables {
+		for _, col := range tb.Columns {
+			if fk, ok := tb.Fk[col.Tag.Column]; ok {
+				refPk, found := pkOf[fk.RefTable]
+				if !found {
+					ColorLog("[WARN] %s.%s references %s which is not a model with a primary key\n", tb.Name, col.Tag.Column, fk.RefTable)
+					delete(tb.Fk, col.Tag.Column)
+					col.SqlType, _ = goSqlType(driver, "int", &OrmTag{})
+					continue
+				}
+				fk.RefColumn = refPk.Tag.Column
+				fk.Constraint = fmt.Sprintf("fk_%s_%s", tb.Name, col.Tag.Column)
+				col.SqlType, err = goSqlType(driver, refPk.Type, &OrmTag{})
+			} else {
+				col.SqlType, err = goSqlType(driver, col.Type, col.Tag)
+			}
+			if err != nil {
+				ColorLog("[ERRO] %s.%s: %s\n", tb.Name, col.Tag.Column, err)
+				ColorLog("[HINT] Give the field an orm:\"-\" tag if it isn't a column\n")
+				os.Exit(2)
+			}
+		}
+	}
+	return
+}
+
+// tableNameMethod reports the table name returned by a TableName method declaration
+func tableNameMethod(decl *ast.FuncDecl) (string, bool) {
+	if decl.Recv == nil || decl.Name.Name != "TableName" || decl.Body == nil || len(decl.Body.List) != 1 {
+		return "", false
+	}
+	ret, ok := decl.Body.List[0].(*ast.ReturnStmt)
+	if !ok || len(ret.Results) != 1 {
+		return "", false
+	}
+	lit, ok := ret.Results[0].(*ast.BasicLit)
+	if !ok || lit.Kind != token.STRING {
+		return "", false
+	}
+	name, err := strconv.Unquote(lit.Value)
+	return name, err == nil
+}
+
+// receiverName returns the type name of a method receiver
+func receiverName(decl *ast.FuncDecl) string {
+	return strings.TrimPrefix(exprString(decl.Recv.List[0].Type), "*")
+}
+
+// hasOrmTag reports whether any field of a struct carries an orm tag
+func hasOrmTag(st *ast.StructType) bool {
+	for _, field := range st.Fields.List {
+		if field.Tag != nil && reflect.StructTag(strings.Trim(field.Tag.Value, "`")).Get("orm") != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// exprString returns the source form of a field type, e.g. *User or time.Time
+func exprString(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.Ident:
+		return t.Name
+	case *ast.StarExpr:
+		return "*" + exprString(t.X)
+	case *ast.SelectorExpr:
+		return exprString(t.X) + "." + t.Sel.Name
+	case *ast.ArrayType:
+		return "[]" + exprString(t.Elt)
+	}
+	return fmt.Sprint(expr)
+}
+
+// parseOrmTag parses the value of an orm struct tag, e.g. column(name);size(64);null
+func parseOrmTag(s string) *OrmTag {
+	tag := new(OrmTag)
+	for _, opt := range strings.Split(s, ";") {
+		opt = strings.TrimSpace(opt)
+		name, arg := opt, ""
+		if i := strings.Index(opt, "("); i > 0 && strings.HasSuffix(opt, ")") {
+			name, arg = opt[:i], opt[i+1:len(opt)-1]
+		}
+		switch name {
+		case "auto":
+			tag.Auto = true
+		case "pk":
+			tag.Pk = true
+		case "null":
+			tag.Null = true
+		case "index":
+			tag.Index = true
+		case "unique":
+			tag.Unique = true
+		case "column":
+			tag.Column = arg
+		case "size":
+			tag.Size = arg
+		case "digits":
+			tag.Digits = arg
+		case "decimals":
+			tag.Decimals = arg
+		case "auto_now":
+			tag.AutoNow = true
+		case "auto_now_add":
+			tag.AutoNowAdd = true
+		case "type":
+			tag.Type = arg
+		case "default":
+			tag.Default = arg
+		case "rel":
+			switch arg {
+			case "fk":
+				tag.RelFk = true
+			case "one":
+				tag.RelOne = true
+			case "m2m":
+				tag.RelM2M = true
+			}
+		case "reverse":
+			switch arg {
+			case "one":
+				tag.ReverseOne = true
+			case "many":
+				tag.ReverseMany = true
+			}
+		}
+	}
+	return tag
+}
+
+func isGoIntType(t string) bool {
+	switch t {
+	case "int", "int8", "int16", "int32", "int64", "uint", "uint8", "uint16", "uint32", "uint64":
+		return true
+	}
+	return false
+}
+
+// sqlNullBaseTypes are the Go types of the values of the sql.NullX types
+var sqlNullBaseTypes = map[string]string{
+	"sql.NullString":  "string",
+	"sql.NullInt64":   "int64",
+	"sql.NullInt32":   "int32",
+	"sql.NullInt16":   "int16",
+	"sql.NullByte":    "uint8",
+	"sql.NullFloat64": "float64",
+	"sql.NullBool":    "bool",
+	"sql.NullTime":    "time.Time",
+}
+
+// goSqlType maps a model field to the SQL column type beego orm creates for it. Pointers and
+// sql.NullX types are the column of their value, whose NULL is set by the null tag.
+func goSqlType(driver, goType string, tag *OrmTag) (string, error) {
+	postgres := driver == "postgres"
+	goType = strings.TrimPrefix(goType, "*")
+	if t, ok := sqlNullBaseTypes[goType]; ok {
+		goType = t
+	}
+	switch goType {
+	case "string":
+		switch tag.Type {
+		case "text", "longtext", "mediumtext":
+			if postgres {
+				return "text", nil
+			}
+			return tag.Type, nil
+		case "char":
+			return "char(" + sizeOrDefault(tag.Size) + ")", nil
+		}
+		return "varchar(" + sizeOrDefault(tag.Size) + ")", nil
+	case "bool":
+		if postgres {
+			return "boolean", nil
+		}
+		return "tinyint(1)", nil
+	case "time.Time":
+		if tag.Type == "date" {
+			return "date", nil
+		}
+		if postgres {
+			return "timestamp with time zone", nil
+		}
+		return "datetime", nil
+	case "float32", "float64":
+		if tag.Digits != "" {
+			if postgres {
+				return fmt.Sprintf("numeric(%s,%s)", tag.Digits, tag.Decimals), nil
+			}
+			return fmt.Sprintf("decimal(%s,%s)", tag.Digits, tag.Decimals), nil
+		}
+		return "double precision", nil
+	case "int8":
+		if postgres {
+			return "smallint", nil
+		}
+		return "tinyint", nil
+	case "int16":
+		return "smallint", nil
+	case "int64":
+		return "bigint", nil
+	case "uint8", "uint16", "uint32", "uint", "uint64":
+		if postgres {
+			return "bigint", nil
+		}
+		return map[string]string{
+			"uint8":  "tinyint unsigned",
+			"uint16": "smallint unsigned",
+			"uint32": "int unsigned",
+			"uint":   "int unsigned",
+			"uint64": "bigint unsigned",
+		}[goType], nil
+	case "int", "int32":
+		if postgres {
+			return "integer", nil
+		}
+		return "int", nil
+	}
+	return "", fmt.Errorf("no column type for the Go type %s", goType)
+}
+
+func sizeOrDefault(size string) string {
+	if size == "" {
+		return "255"
+	}
+	return size
+}
+
+var intDisplayWidth = regexp.MustCompile(`^(tinyint|smallint|mediumint|int|integer|bigint)\([0-9]+\)`)
+
+// sqlTypeAliases maps SQL type names to the name they are compared by, in matching order
+var sqlTypeAliases = [][2]string{
+	{"character varying", "varchar"},
+	{"character", "char"},
+	{"integer", "int"},
+	{"boolean", "bool"},
+	{"numeric", "decimal"},
+}
+
+// normalizeSqlType makes column types read from the database comparable with
+// the ones derived from models, e.g. int(11) => int, character varying => varchar
+func normalizeSqlType(t string) string {
+	t = strings.ToLower(strings.TrimSpace(t))
+	switch t {
+	case "tinyint(1)":
+		return "bool"
+	case "double":
+		return "double precision"
+	}
+	t = intDisplayWidth.ReplaceAllString(t, "$1")
+	for _, alias := range sqlTypeAliases {
+		if t == alias[0] || strings.HasPrefix(t, alias[0]+"(") {
+			return alias[1] + t[len(alias[0]):]
+		}
+	}
+	return t
+}
+
+// diffSchema returns the statements migrating the database tables to the model tables,
+// and the statements reverting them. Tables without a model are left alone.
+func diffSchema(driver string, models, dbTables []*Table) (up, down []string) {
+	d := sqlDialect{driver}
+	dbByName := make(map[string]*Table)
+	for _, tb := range dbTables {
+		dbByName[tb.Name] = tb
+	}
+	// change records a statement and the one reverting it, down statements run in reverse order
+	change := func(u, r string) {
+		up = append(up, u)
+		down = append([]string{r}, down...)
+	}
+	// new tables are created after the tables they reference, the foreign keys of a cycle are
+	// added once its tables exist. SQLite accepts references to tables created later.
+	created := make(map[string]bool)
+	for _, tb := range dbTables {
+		created[tb.Name] = true
+	}
+	var deferred [][2]string
+	for _, model := range creationOrder(models, dbByName) {
+		var later []*ForeignKey
+		for _, col := range model.Columns {
+			if fk, isFk := model.Fk[col.Tag.Column]; isFk && fk.RefTable != model.Name && !created[fk.RefTable] && d.driver != "sqlite3" && d.driver != "sqlite" {
+				later = append(later, fk)
+			}
+		}
+		change(d.createTable(model, later), d.dropTable(model.Name))
+		created[model.Name] = true
+		for _, col := range model.Columns {
+			if col.Tag.Index && !col.Tag.Unique && col.Tag.Column != model.Pk {
+				change(d.addIndex(model.Name, col.Tag.Column), d.dropIndex(model.Name, col.Tag.Column))
+			}
+		}
+		for _, fk := range later {
+			deferred = append(deferred, [2]string{d.addForeignKey(model.Name, fk), d.dropForeignKey(model.Name, fk)})
+		}
+	}
+	for _, stmts := range deferred {
+		change(stmts[0], stmts[1])
+	}
+	for _, model := range models {
+		dbTable, ok := dbByName[model.Name]
+		if !ok {
+			continue
+		}
+		dbCols := make(map[string]*Column)
+		for _, col := range dbTable.Columns {
+			dbCols[col.Tag.Column] = col
+		}
+		modelCols := make(map[string]bool)
+		for _, col := range model.Columns {
+			name := col.Tag.Column
+			modelCols[name] = true
+			dbCol, exists := dbCols[name]
+			if !exists {
+				change(d.addColumn(model.Name, col), d.dropColumn(model.Name, name))
+			} else if name != dbTable.Pk && (!sameSqlType(col, dbCol) || col.Tag.Null != dbCol.Tag.Null) {
+				change(d.modifyColumn(model.Name, col), d.modifyColumn(model.Name, dbCol))
+			}
+			if col.Tag.Unique && !containsString(dbTable.Uk, name) {
+				change(d.addUnique(model.Name, name), d.dropUnique(model.Name, name))
+			} else if col.Tag.Index && !col.Tag.Unique && name != model.Pk && !hasColumnIndex(dbTable, name) {
+				change(d.addIndex(model.Name, name), d.dropIndex(model.Name, name))
+			} else if !col.Tag.Index && hasIndex(dbTable, indexName("idx", model.Name, name)) {
+				// only the indexes of generated migrations are dropped, others may back foreign keys
+				change(d.dropIndex(model.Name, name), d.addIndex(model.Name, name))
+			}
+			if fk, isFk := model.Fk[name]; isFk && dbTable.Fk[name] == nil {
+				change(d.addForeignKey(model.Name, fk), d.dropForeignKey(model.Name, fk))
+			}
+			if fk, isFk := dbTable.Fk[name]; isFk && model.Fk[name] == nil {
+				change(d.dropForeignKey(model.Name, fk), d.addForeignKey(model.Name, fk))
+			}
+			if !col.Tag.Unique && containsString(dbTable.Uk, name) {
+				ColorLog("[WARN] %s.%s is unique in the database but not in the model, drop its index by hand\n", model.Name, name)
+			}
+		}
+		for _, dbCol := range dbTable.Columns {
+			name := dbCol.Tag.Column
+			if modelCols[name] || model.PartialModel {
+				continue
+			}
+			if fk, isFk := dbTable.Fk[name]; isFk {
+				change(d.dropForeignKey(model.Name, fk), d.addForeignKey(model.Name, fk))
+			}
+			change(d.dropColumn(model.Name, name), d.addColumn(model.Name, dbCol))
+		}
+	}
+	return
+}
+
+// creationOrder returns the model tables missing from the database, each after the new tables
+// its foreign keys reference unless they are part of a cycle
+func creationOrder(models []*Table, dbByName map[string]*Table) (order []*Table) {
+	byName := make(map[string]*Table)
+	for _, model := range models {
+		if dbByName[model.Name] == nil {
+			byName[model.Name] = model
+		}
+	}
+	visited := make(map[string]bool)
+	var visit func(tb *Table)
+	visit = func(tb *Table) {
+		if visited[tb.Name] {
+			return
+		}
+		visited[tb.Name] = true
+		for _, col := range tb.Columns {
+			if fk, isFk := tb.Fk[col.Tag.Column]; isFk && byName[fk.RefTable] != nil {
+				visit(byName[fk.RefTable])
+			}
+		}
+		order = append(order, tb)
+	}
+	for _, model := range models {
+		if byName[model.Name] != nil {
+			visit(model)
+		}
+	}
+	return
+}
+
+// sameSqlType reports whether a model column has the type of a database column. sql.NullInt64
+// and sql.NullFloat64 hold any integer or floating point column.
+func sameSqlType(col, dbCol *Column) bool {
+	t := normalizeSqlType(dbCol.SqlType)
+	switch strings.TrimPrefix(col.Type, "*") {
+	case "sql.NullInt64":
+		switch strings.TrimSuffix(t, " unsigned") {
+		case "tinyint", "smallint", "mediumint", "int", "bigint":
+			return true
+		}
+		return false
+	case "sql.NullFloat64":
+		return t == "double precision" || t == "real" || t == "float" || strings.HasPrefix(t, "decimal")
+	}
+	return normalizeSqlType(col.SqlType) == t
+}
+
+// hasColumnIndex reports whether a table has a non unique index on a column alone
+func hasColumnIndex(tb *Table, column string) bool {
+	for _, idx := range tb.Indexes {
+		if !idx.Unique && len(idx.Columns) == 1 && idx.Columns[0] == column {
+			return true
+		}
+	}
+	return false
+}
+
+// hasIndex reports whether a table has an index of that name
+func hasIndex(tb *Table, name string) bool {
+	for _, idx := range tb.Indexes {
+		if idx.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// sqlDialect writes DDL statements for a database driver
+type sqlDialect struct {
+	driver string
+}
+
+func (d sqlDialect) quote(name string) string {
+	if d.driver == "mysql" {
+		return "`" + name + "`"
+	}
+	return `"` + name + `"`
+}
+
+// columnDef returns the column definition used by CREATE TABLE and ADD COLUMN
+func (d sqlDialect) columnDef(col *Column) string {
+	sqlType := col.SqlType
+	if col.Tag.Auto && d.driver == "postgres" {
+		sqlType = "serial"
+		if col.Type == "int64" || normalizeSqlType(col.SqlType) == "bigint" {
+			sqlType = "bigserial"
+		}
+	}
+	def := d.quote(col.Tag.Column) + " " + sqlType
+	if !col.Tag.Null {
+		def += " NOT NULL"
+	}
+	if col.Tag.Auto && d.driver == "mysql" {
+		def += " AUTO_INCREMENT"
+	}
+	if col.SqlDefault != "" && !col.Tag.Auto {
+		def += " DEFAULT " + d.defaultExpr(col.SqlDefault)
+	} else if col.Tag.Default != "" {
+		def += " DEFAULT " + d.literal(col.Tag.Default)
+	}
+	return def
+}
+
+// defaultExpr returns a column default read from the database as an SQL expression.
+// PostgreSQL reports defaults as expressions, MySQL reports the bare value of literals.
+func (d sqlDialect) defaultExpr(def string) string {
+	if d.driver != "mysql" || strings.HasPrefix(def, "'") || strings.HasPrefix(strings.ToUpper(def), "CURRENT_TIMESTAMP") {
+		return def
+	}
+	if _, err := strconv.ParseFloat(def, 64); err == nil {
+		return def
+	}
+	return d.literal(def)
+}
+
+// literal quotes a string as an SQL string literal
+func (d sqlDialect) literal(s string) string {
+	s = strings.Replace(s, "'", "''", -1)
+	if d.driver == "mysql" {
+		// MySQL treats backslashes in string literals as escape characters
+		s = strings.Replace(s, `\`, `\\`, -1)
+	}
+	return "'" + s + "'"
+}
+
+// createTable returns the CREATE TABLE statement of a model table, without the foreign keys
+// listed in skip
+func (d sqlDialect) createTable(tb *Table, skip []*ForeignKey) string {
+	var defs []string
+	for _, col := range tb.Columns {
+		defs = append(defs, d.columnDef(col))
+	}
+	if tb.Pk != "" {
+		defs = append(defs, "PRIMARY KEY ("+d.quote(tb.Pk)+")")
+	}
+	for _, col := range tb.Columns {
+		if col.Tag.Unique {
+			defs = append(defs, "CONSTRAINT "+d.quote(indexName("uk", tb.Name, col.Tag.Column))+" UNIQUE ("+d.quote(col.Tag.Column)+")")
+		}
+		if fk, ok := tb.Fk[col.Tag.Column]; ok && !containsForeignKey(skip, fk) {
+			defs = append(defs, "CONSTRAINT "+d.quote(fk.Constraint)+" FOREIGN KEY ("+d.quote(fk.Name)+") REFERENCES "+d.quote(fk.RefTable)+" ("+d.quote(fk.RefColumn)+")")
+		}
+	}
+	stmt := "CREATE TABLE " + d.quote(tb.Name) + " (" + strings.Join(defs, ", ") + ")"
+	if d.driver == "mysql" {
+		stmt += " ENGINE=InnoDB DEFAULT CHARSET=utf8"
+	}
+	return stmt
+}
+
+func containsForeignKey(fks []*ForeignKey, fk *ForeignKey) bool {
+	for _, f := range fks {
+		if f == fk {
+			return true
+		}
+	}
+	return false
+}
+
+// dumpTable returns the CREATE TABLE statement of a table read from the database.
+// Indexes and foreign keys are created by separate statements.
+func (d sqlDialect) dumpTable(tb *Table) string {
+	var defs []string
+	for _, col := range tb.Columns {
+		def := d.columnDef(col)
+		if col.Tag.AutoNow && d.driver == "mysql" {
+			def += " ON UPDATE CURRENT_TIMESTAMP"
+		}
+		defs = append(defs, def)
+	}
+	if len(tb.PkColumns) > 0 {
+		quoted := make([]string, len(tb.PkColumns))
+		for i, column := range tb.PkColumns {
+			quoted[i] = d.quote(column)
+		}
+		defs = append(defs, "PRIMARY KEY ("+strings.Join(quoted, ", ")+")")
+	}
+	stmt := "CREATE TABLE " + d.quote(tb.Name) + " (\n\t" + strings.Join(defs, ",\n\t") + "\n)"
+	if d.driver == "mysql" {
+		stmt += " ENGINE=InnoDB DEFAULT CHARSET=utf8"
+	}
+	return stmt
+}
+
+// dumpIndex returns the statement creating a unique constraint or an index read from the database
+func (d sqlDialect) dumpIndex(table string, idx *Index) string {
+	quoted := make([]string, len(idx.Columns))
+	for i, column := range idx.Columns {
+		quoted[i] = d.quote(column)
+	}
+	if idx.Unique {
+		return "ALTER TABLE " + d.quote(table) + " ADD CONSTRAINT " + d.quote(idx.Name) + " UNIQUE (" + strings.Join(quoted, ", ") + ")"
+	}
+	return "CREATE INDEX " + d.quote(idx.Name) + " ON " + d.quote(table) + " (" + strings.Join(quoted, ", ") + ")"
+}
+
+// nullLiteral quotes a nullable string as an SQL literal
+func (d sqlDialect) nullLiteral(s sql.NullString) string {
+	if !s.Valid {
+		return "NULL"
+	}
+	return d.literal(s.String)
+}
+
+func (d sqlDialect) dropTable(table string) string {
+	return "DROP TABLE " + d.quote(table)
+}
+
+func (d sqlDialect) addColumn(table string, col *Column) string {
+	return "ALTER TABLE " + d.quote(table) + " ADD COLUMN " + d.columnDef(col)
+}
+
+func (d sqlDialect) dropColumn(table, column string) string {
+	return "ALTER TABLE " + d.quote(table) + " DROP COLUMN " + d.quote(column)
+}
+
+func (d sqlDialect) modifyColumn(table string, col *Column) string {
+	if d.driver == "postgres" {
+		null := "SET NOT NULL"
+		if col.Tag.Null {
+			null = "DROP NOT NULL"
+		}
+		return "ALTER TABLE " + d.quote(table) + " ALTER COLUMN " + d.quote(col.Tag.Column) + " TYPE " + col.SqlType +
+			", ALTER COLUMN " + d.quote(col.Tag.Column) + " " + null
+	}
+	return "ALTER TABLE " + d.quote(table) + " MODIFY COLUMN " + d.columnDef(col)
+}
+
+func (d sqlDialect) addUnique(table, column string) string {
+	return "ALTER TABLE " + d.quote(table) + " ADD CONSTRAINT " + d.quote(indexName("uk", table, column)) + " UNIQUE (" + d.quote(column) + ")"
+}
+
+func (d sqlDialect) dropUnique(table, column string) string {
+	if d.driver == "mysql" {
+		return "ALTER TABLE " + d.quote(table) + " DROP INDEX " + d.quote(indexName("uk", table, column))
+	}
+	return "ALTER TABLE " + d.quote(table) + " DROP CONSTRAINT " + d.quote(indexName("uk", table, column))
+}
+
+func (d sqlDialect) addIndex(table, column string) string {
+	return "CREATE INDEX " + d.quote(indexName("idx", table, column)) + " ON " + d.quote(table) + " (" + d.quote(column) + ")"
+}
+
+func (d sqlDialect) dropIndex(table, column string) string {
+	if d.driver == "mysql" {
+		return "DROP INDEX " + d.quote(indexName("idx", table, column)) + " ON " + d.quote(table)
+	}
+	return "DROP INDEX " + d.quote(indexName("idx", table, column))
+}
+
+func (d sqlDialect) addForeignKey(table string, fk *ForeignKey) string {
+	return "ALTER TABLE " + d.quote(table) + " ADD CONSTRAINT " + d.quote(fk.Constraint) +
+		" FOREIGN KEY (" + d.quote(fk.Name) + ") REFERENCES " + d.quote(fk.RefTable) + " (" + d.quote(fk.RefColumn) + ")"
+}
+
+func (d sqlDialect) dropForeignKey(table string, fk *ForeignKey) string {
+	if d.driver == "mysql" {
+		return "ALTER TABLE " + d.quote(table) + " DROP FOREIGN KEY " + d.quote(fk.Constraint)
+	}
+	return "ALTER TABLE " + d.quote(table) + " DROP CONSTRAINT " + d.quote(fk.Constraint)
+}
+
+// indexName names an index created by a generated migration, e.g. idx_post_user_id
+func indexName(prefix, table, column string) string {
+	return prefix + "_" + table + "_" + column
+}
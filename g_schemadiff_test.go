@@ -0,0 +1,121 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDiffSchema(t *testing.T) {
+	models := getModelTables("testdata/models", "mysql")
+	if len(models) != 2 || models[0].Name != "posts" || models[1].Name != "user" {
+		t.Fatalf("unexpected model tables: %v", models)
+	}
+	// user is up to date except the email column, posts doesn't exist yet
+	dbTables := []*Table{{
+		Name: "user",
+		Pk:   "id",
+		Fk:   map[string]*ForeignKey{},
+		Columns: []*Column{
+			{Name: "Id", Type: "int", SqlType: "int(11)", Tag: &OrmTag{Column: "id", Auto: true}},
+			{Name: "Name", Type: "string", SqlType: "varchar(64)", Tag: &OrmTag{Column: "name"}},
+			{Name: "Created", Type: "time.Time", SqlType: "datetime", Tag: &OrmTag{Column: "created"}},
+			{Name: "Nickname", Type: "string", SqlType: "varchar(32)", Tag: &OrmTag{Column: "nickname", Null: true}},
+		},
+	}}
+	up, down := diffSchema("mysql", models, dbTables)
+	expectedUp := []string{
+		"CREATE TABLE `posts` (`id` int NOT NULL AUTO_INCREMENT, `title` varchar(255) NOT NULL, `body` text, `author_id` int NOT NULL, " +
+			"PRIMARY KEY (`id`), CONSTRAINT `fk_posts_author_id` FOREIGN KEY (`author_id`) REFERENCES `user` (`id`)) ENGINE=InnoDB DEFAULT CHARSET=utf8",
+		"ALTER TABLE `user` ADD COLUMN `email` varchar(128) NOT NULL",
+		"ALTER TABLE `user` ADD CONSTRAINT `uk_user_email` UNIQUE (`email`)",
+		"ALTER TABLE `user` DROP COLUMN `nickname`",
+	}
+	expectedDown := []string{
+		"ALTER TABLE `user` ADD COLUMN `nickname` varchar(32)",
+		"ALTER TABLE `user` DROP INDEX `uk_user_email`",
+		"ALTER TABLE `user` DROP COLUMN `email`",
+		"DROP TABLE `posts`",
+	}
+	if !reflect.DeepEqual(up, expectedUp) {
+		t.Errorf("up statements:\n%q\nexpected:\n%q", up, expectedUp)
+	}
+	if !reflect.DeepEqual(down, expectedDown) {
+		t.Errorf("down statements:\n%q\nexpected:\n%q", down, expectedDown)
+	}
+}
+
+func TestDiffSchemaCreationOrder(t *testing.T) {
+	// posts references user, which sorts after it
+	up, down := diffSchema("postgres", getModelTables("testdata/models", "postgres"), nil)
+	if len(up) != 2 || !strings.HasPrefix(up[0], `CREATE TABLE "user"`) || !strings.HasPrefix(up[1], `CREATE TABLE "posts"`) {
+		t.Errorf("user is not created before posts:\n%q", up)
+	}
+	if !reflect.DeepEqual(down, []string{`DROP TABLE "posts"`, `DROP TABLE "user"`}) {
+		t.Errorf("posts is not dropped before user:\n%q", down)
+	}
+
+	// the foreign keys of a cycle are added once both tables exist, and dropped first
+	cycle := func(name, ref string) *Table {
+		fk := &ForeignKey{Name: ref + "_id", Constraint: "fk_" + name + "_" + ref + "_id", RefTable: ref, RefColumn: "id"}
+		return &Table{Name: name, Pk: "id", Fk: map[string]*ForeignKey{fk.Name: fk}, Columns: []*Column{
+			{Name: "Id", Type: "int", SqlType: "integer", Tag: &OrmTag{Column: "id", Auto: true}},
+			{Name: camelCase(fk.Name), Type: "int", SqlType: "integer", Tag: &OrmTag{Column: fk.Name, Null: true}},
+		}}
+	}
+	up, down = diffSchema("postgres", []*Table{cycle("a", "b"), cycle("b", "a")}, nil)
+	expectedUp := []string{
+		`CREATE TABLE "b" ("id" serial NOT NULL, "a_id" integer, PRIMARY KEY ("id"))`,
+		`CREATE TABLE "a" ("id" serial NOT NULL, "b_id" integer, PRIMARY KEY ("id"), CONSTRAINT "fk_a_b_id" FOREIGN KEY ("b_id") REFERENCES "b" ("id"))`,
+		`ALTER TABLE "b" ADD CONSTRAINT "fk_b_a_id" FOREIGN KEY ("a_id") REFERENCES "a" ("id")`,
+	}
+	expectedDown := []string{`ALTER TABLE "b" DROP CONSTRAINT "fk_b_a_id"`, `DROP TABLE "a"`, `DROP TABLE "b"`}
+	if !reflect.DeepEqual(up, expectedUp) || !reflect.DeepEqual(down, expectedDown) {
+		t.Errorf("unexpected statements of a cycle:\n%q\n%q", up, down)
+	}
+}
+
+func TestDiffSchemaNullable(t *testing.T) {
+	models := getModelTables("testdata/nullmodels", "mysql")
+	if len(models) != 2 || models[0].Name != "event" || models[1].Name != "log" || !models[1].PartialModel {
+		t.Fatalf("unexpected model tables: %v", models)
+	}
+	col := func(name, sqlType string, null bool) *Column {
+		return &Column{Name: camelCase(name), SqlType: sqlType, Tag: &OrmTag{Column: name, Null: null}}
+	}
+	// the columns of event are up to date, the indexes aren't; the columns of log embedded from
+	// another package are kept
+	dbTables := []*Table{{
+		Name:    "event",
+		Pk:      "id",
+		Fk:      map[string]*ForeignKey{},
+		Indexes: []*Index{{Name: "idx_event_kind", Columns: []string{"kind"}}},
+		Columns: []*Column{col("created", "datetime", false), col("deleted", "datetime", true), col("id", "int(11)", false),
+			col("name", "varchar(64)", true), col("kind", "varchar(8)", false), col("score", "int(11)", true),
+			col("rate", "double", true), col("note", "varchar(128)", true)},
+	}, {
+		Name:    "log",
+		Pk:      "id",
+		Fk:      map[string]*ForeignKey{},
+		Columns: []*Column{col("id", "int(11)", false), col("msg", "text", false), col("created", "datetime", false)},
+	}}
+	up, down := diffSchema("mysql", models, dbTables)
+	expectedUp := []string{
+		"CREATE INDEX `idx_event_name` ON `event` (`name`)",
+		"DROP INDEX `idx_event_kind` ON `event`",
+	}
+	expectedDown := []string{
+		"CREATE INDEX `idx_event_kind` ON `event` (`kind`)",
+		"DROP INDEX `idx_event_name` ON `event`",
+	}
+	if !reflect.DeepEqual(up, expectedUp) {
+		t.Errorf("up statements:\n%q\nexpected:\n%q", up, expectedUp)
+	}
+	if !reflect.DeepEqual(down, expectedDown) {
+		t.Errorf("down statements:\n%q\nexpected:\n%q", down, expectedDown)
+	}
+
+	if _, err := goSqlType("mysql", "map[string]string", &OrmTag{}); err == nil {
+		t.Error("no error for a Go type without column type")
+	}
+}
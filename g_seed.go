@@ -0,0 +1,72 @@
+// Copyright 2013 bee authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"time"
+)
+
+const S_PATH = "seeds"
+
+// generateSeed generates a seed file in database/seeds. The seed is either a data file
+// in JSON or YAML format listing the rows of a table, or a Go file loading the data through the ORM.
+func generateSeed(sname, format, curpath string) {
+	var tpl, ext string
+	switch format {
+	case "json":
+		tpl, ext = "seed/seed.json.tpl", ".json"
+	case "yaml", "yml":
+		tpl, ext = "seed/seed.yml.tpl", ".yml"
+	case "go":
+		tpl, ext = "seed/seed.go.tpl", ".go"
+	default:
+		ColorLog("[ERRO] Unknown seed format: %s\n", format)
+		ColorLog("[HINT] Format must be one of json, yaml or go\n")
+		os.Exit(2)
+	}
+	seedFilePath := path.Join(curpath, "database", S_PATH)
+	if _, err := os.Stat(seedFilePath); os.IsNotExist(err) {
+		// create seeds directory
+		if err := os.MkdirAll(seedFilePath, 0777); err != nil {
+			ColorLog("[ERRO] Could not create seed directory: %s\n", err)
+			os.Exit(2)
+		}
+	}
+	// create file
+	today := time.Now().Format(M_DATE_FORMAT)
+	name := fmt.Sprintf("%s_%s", today, sname)
+	fpath := path.Join(seedFilePath, name+ext)
+	if f, err := os.OpenFile(fpath, os.O_CREATE|os.O_EXCL|os.O_RDWR, 0666); err == nil {
+		defer f.Close()
+		content := renderTemplate(tpl, map[string]interface{}{
+			"SeedName":  name,
+			"FuncName":  "Seed" + camelCase(sname) + "_" + today,
+			"TableName": sname,
+		})
+		f.WriteString(content)
+		if format == "go" {
+			// gofmt generated source code
+			formatSourceCode(fpath)
+		}
+		ColorLog("[INFO] Seed file generated: %s\n", fpath)
+	} else {
+		// error creating file
+		ColorLog("[ERRO] Could not create seed file: %s\n", err)
+		os.Exit(2)
+	}
+}
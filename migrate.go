@@ -15,8 +15,11 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"hash/crc32"
+	"io/ioutil"
 	"os"
 	"os/exec"
 	"path"
@@ -32,38 +35,54 @@ var cmdMigrate = &Command{
 bee migrate [-database=test] [-driver=mysql] [-conn="root:@tcp(127.0.0.1:3306)/test"]
     run all outstanding migrations
     -database: database name (default: test)
-    -driver: [mysql | postgresql | sqlite] (default: mysql)
+    -driver: [mysql | postgres | sqlite] (default: mysql)
     -conn:   the connection string used by the driver, the default is root:@tcp(127.0.0.1:3306)/test
 
 bee migrate rollback [-database=test] [-driver=mysql] [-conn="root:@tcp(127.0.0.1:3306)/test"]
     rollback the last migration operation
     -database: database name (default: test)
-    -driver: [mysql | postgresql | sqlite] (default: mysql)
+    -driver: [mysql | postgres | sqlite] (default: mysql)
     -conn:   the connection string used by the driver, the default is root:@tcp(127.0.0.1:3306)/test
 
 bee migrate reset  [-database=test] [-driver=mysql] [-conn="root:@tcp(127.0.0.1:3306)/test"]
     rollback all migrations
     -database: database name (default: test)
-    -driver: [mysql | postgresql | sqlite] (default: mysql)
+    -driver: [mysql | postgres | sqlite] (default: mysql)
     -conn:   the connection string used by the driver, the default is root:@tcp(127.0.0.1:3306)/test
 
 bee migrate refresh [-database=test] [-driver=mysql] [-conn="root:@tcp(127.0.0.1:3306)/test"]
     rollback all migrations and run them all again
     -database: database name (default: test)
-    -driver: [mysql | postgresql | sqlite] (default: mysql)
+    -driver: [mysql | postgres | sqlite] (default: mysql)
     -conn:   the connection string used by the driver, the default is root:@tcp(127.0.0.1:3306)/test
+
+bee migrate unlock [-database=test] [-driver=mysql] [-conn="root:@tcp(127.0.0.1:3306)/test"]
+    remove a stale migration lock left behind by a crashed migration run
+    -database: database name (default: test)
+    -driver: [mysql | postgres | sqlite] (default: mysql)
+    -conn:   the connection string used by the driver, the default is root:@tcp(127.0.0.1:3306)/test
+
+All commands accept -db=name to use a database connection defined in bee.json
+instead of -conn.
+
+Every migration command holds a database-level lock for the whole run, so
+only one runner can migrate a database at a time. On postgres and sqlite
+each migration is a transaction: when a migration fails, its schema changes
+and its migrations row are rolled back, the migrations before it are kept.
 `,
 }
 
 var mDriver docValue
 var mConn docValue
 var mDatabase docValue
+var mDb docValue
 
 func init() {
 	cmdMigrate.Run = runMigration
-	cmdMigrate.Flag.Var(&mDriver, "driver", "database driver: mysql, postgresql, sqlite, etc.")
+	cmdMigrate.Flag.Var(&mDriver, "driver", "database driver: mysql, postgres or sqlite")
 	cmdMigrate.Flag.Var(&mConn, "conn", "connection string used by the driver to connect to a database instance")
 	cmdMigrate.Flag.Var(&mDatabase, "database", "specify the database want to use.")
+	cmdMigrate.Flag.Var(&mDb, "db", "name of a database connection defined in bee.json")
 }
 
 // runMigration is the entry point for starting a migration
@@ -87,26 +106,9 @@ func runMigration(cmd *Command, args []string) int {
 		cmd.Flag.Parse(args[1:])
 	}
 
-	if mDatabase != "" {
-		newConn := fmt.Sprint("root:@tcp(127.0.0.1:3306)/", mDatabase)
-		mConn = docValue(newConn)
-	}
-
-	if mDriver == "" {
-		mDriver = docValue(conf.Database.Driver)
-		if mDriver == "" {
-			mDriver = "mysql"
-		}
-	}
-	if mConn == "" {
-		mConn = docValue(conf.Database.Conn)
-		if mConn == "" {
-			mConn = "root:@tcp(127.0.0.1:3306)/test"
-		}
-	}
-	ColorLog("[INFO] Using '%s' as 'driver'\n", mDriver)
-	ColorLog("[INFO] Using '%s' as 'conn'\n", mConn)
-	driverStr, connStr := string(mDriver), string(mConn)
+	driverStr, connStr := resolveDbConn(mDb.String(), mDriver.String(), mConn.String(), mDatabase.String(), "test")
+	ColorLog("[INFO] Using '%s' as 'driver'\n", driverStr)
+	ColorLog("[INFO] Using '%s' as 'conn'\n", connStr)
 	if len(args) == 0 {
 		// run all outstanding migrations
 		ColorLog("[INFO] Running all outstanding migrations\n")
@@ -123,6 +125,11 @@ func runMigration(cmd *Command, args []string) int {
 		case "refresh":
 			ColorLog("[INFO] Refreshing all migrations\n")
 			migrateRefresh(crupath, driverStr, connStr)
+		case "unlock":
+			ColorLog("[INFO] Removing the migration lock\n")
+			migrateUnlock(driverStr, connStr)
+			ColorLog("[SUCC] Migration lock removed!\n")
+			return 0
 		default:
 			ColorLog("[ERRO] Command is missing\n")
 			os.Exit(2)
@@ -164,39 +171,179 @@ func migrate(goal, crupath, driver, connStr string) {
 		os.Exit(2)
 	}
 	defer db.Close()
-	checkForSchemaUpdateTable(db)
+	heldMigrationLock = acquireMigrationLock(db, driver)
+	checkForSchemaUpdateTable(db, driver)
 	latestName, latestTime := getLatestMigration(db, goal)
 	writeMigrationSourceFile(dir, source, driver, connStr, latestTime, latestName, goal)
 	buildMigrationBinary(dir, binary)
 	runMigrationBinary(dir, binary)
 	removeTempFile(dir, source)
 	removeTempFile(dir, binary)
+	if goal == "reset" || goal == "refresh" {
+		// the seeded data went away with the tables, so seeds have to be applied again.
+		// The seeds table doesn't exist until 'bee db seed' runs.
+		if tableExists(db, "seeds") {
+			if _, err := db.Exec("DELETE FROM seeds"); err != nil {
+				ColorLog("[ERRO] Could not clear seeds table: %s\n", err)
+				ColorLog("[HINT] Delete the rows of the seeds table before running 'bee db seed' again\n")
+				exitMigration()
+			}
+		}
+	}
+	heldMigrationLock.release()
+	heldMigrationLock = nil
 }
 
-// checkForSchemaUpdateTable checks the existence of migrations table.
-// It checks for the proper table structures and creates the table using MYSQL_MIGRATION_DDL if it does not exist.
-func checkForSchemaUpdateTable(db *sql.DB) {
-	if rows, err := db.Query("SHOW TABLES LIKE 'migrations'"); err != nil {
-		ColorLog("[ERRO] Could not show migrations table: %s\n", err)
+// migrateUnlock removes the lock row left in the lock table by a crashed migration run.
+// Lock functions of MySQL and PostgreSQL are released by the server when the session ends,
+// so there is nothing to remove for them.
+func migrateUnlock(driver, connStr string) {
+	if isSessionLockDriver(driver) {
+		ColorLog("[INFO] %s releases migration locks when the session ends, nothing to remove\n", driver)
+		return
+	}
+	db, err := sql.Open(driver, connStr)
+	if err != nil {
+		ColorLog("[ERRO] Could not connect to %s: %s\n", driver, connStr)
+		os.Exit(2)
+	}
+	defer db.Close()
+	if _, err := db.Exec("DELETE FROM " + MIGRATION_LOCK_TABLE); err != nil {
+		ColorLog("[ERRO] Could not remove migration lock: %s\n", err)
+		os.Exit(2)
+	}
+}
+
+// migrationLock is a database-level lock held for the whole migration run.
+// The lock lives on a dedicated connection, so that it is not released by
+// the connection pool while the migration binary does its work.
+type migrationLock struct {
+	conn   *sql.Conn
+	driver string
+}
+
+// heldMigrationLock is the lock acquired by the running migration, if any
+var heldMigrationLock *migrationLock
+
+// acquireMigrationLock takes the migration lock using GET_LOCK on MySQL, pg_advisory_lock on
+// PostgreSQL and a lock table on other databases.
+// It exits the system when the lock is held by another runner.
+func acquireMigrationLock(db *sql.DB, driver string) *migrationLock {
+	conn, err := db.Conn(context.Background())
+	if err != nil {
+		ColorLog("[ERRO] Could not connect to database: %s\n", err)
 		os.Exit(2)
-	} else if !rows.Next() {
+	}
+	var acquired bool
+	switch driver {
+	case "mysql":
+		var got sql.NullInt64
+		err = conn.QueryRowContext(context.Background(), "SELECT GET_LOCK(?, 0)", MIGRATION_LOCK_NAME).Scan(&got)
+		acquired = got.Valid && got.Int64 == 1
+	case "postgres":
+		err = conn.QueryRowContext(context.Background(), "SELECT pg_try_advisory_lock($1)", migrationLockKey()).Scan(&acquired)
+	default:
+		if _, err = conn.ExecContext(context.Background(), MIGRATION_LOCK_DDL); err == nil {
+			// the lock table has a single row at most, a second runner violates its primary key
+			_, e := conn.ExecContext(context.Background(), "INSERT INTO "+MIGRATION_LOCK_TABLE+" (id_lock) VALUES (1)")
+			acquired = e == nil
+		}
+	}
+	if err != nil {
+		conn.Close()
+		ColorLog("[ERRO] Could not acquire migration lock: %s\n", err)
+		os.Exit(2)
+	}
+	if !acquired {
+		conn.Close()
+		ColorLog("[ERRO] Another migration is running against this database\n")
+		if !isSessionLockDriver(driver) {
+			ColorLog("[HINT] If no migration is running, remove the stale lock with 'bee migrate unlock'\n")
+		}
+		os.Exit(2)
+	}
+	ColorLog("[INFO] Acquired migration lock\n")
+	return &migrationLock{conn: conn, driver: driver}
+}
+
+// release gives the migration lock back and closes its connection
+func (l *migrationLock) release() {
+	var err error
+	switch l.driver {
+	case "mysql":
+		_, err = l.conn.ExecContext(context.Background(), "SELECT RELEASE_LOCK(?)", MIGRATION_LOCK_NAME)
+	case "postgres":
+		_, err = l.conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", migrationLockKey())
+	default:
+		_, err = l.conn.ExecContext(context.Background(), "DELETE FROM "+MIGRATION_LOCK_TABLE)
+	}
+	if err != nil {
+		ColorLog("[WARN] Could not release migration lock: %s\n", err)
+	}
+	l.conn.Close()
+}
+
+// migrationLockKey maps the lock name to the integer key used by PostgreSQL advisory locks
+func migrationLockKey() int64 {
+	return int64(crc32.ChecksumIEEE([]byte(MIGRATION_LOCK_NAME)))
+}
+
+// isSessionLockDriver reports whether the database releases the migration lock by itself
+// when the session holding it ends.
+func isSessionLockDriver(driver string) bool {
+	return driver == "mysql" || driver == "postgres"
+}
+
+// isTransactionalDDL reports whether schema changes can be rolled back on the database.
+func isTransactionalDDL(driver string) bool {
+	return driver == "postgres" || driver == "sqlite" || driver == "sqlite3"
+}
+
+// driverPkg returns the blank import of the database/sql driver package for a driver name
+func driverPkg(driver string) string {
+	switch driver {
+	case "postgres":
+		return `_ "github.com/lib/pq"`
+	case "sqlite", "sqlite3":
+		return `_ "github.com/mattn/go-sqlite3"`
+	}
+	return `_ "github.com/go-sql-driver/mysql"`
+}
+
+// exitMigration releases the migration lock, if held, and exits the system
+func exitMigration() {
+	if heldMigrationLock != nil {
+		heldMigrationLock.release()
+	}
+	os.Exit(2)
+}
+
+// checkForSchemaUpdateTable checks the existence of migrations table.
+// It creates the table with the DDL of the driver if it does not exist, and checks the
+// table structure on MySQL.
+func checkForSchemaUpdateTable(db *sql.DB, driver string) {
+	if !tableExists(db, "migrations") {
 		// no migrations table, create anew
 		ColorLog("[INFO] Creating 'migrations' table...\n")
-		if _, err := db.Query(MYSQL_MIGRATION_DDL); err != nil {
+		if _, err := db.Exec(sqlDialect{driver}.migrationsTable()); err != nil {
 			ColorLog("[ERRO] Could not create migrations table: %s\n", err)
-			os.Exit(2)
+			exitMigration()
 		}
 	}
+	if driver != "mysql" {
+		return
+	}
 	// checking that migrations table schema are expected
 	if rows, err := db.Query("DESC migrations"); err != nil {
 		ColorLog("[ERRO] Could not show columns of migrations table: %s\n", err)
-		os.Exit(2)
+		exitMigration()
 	} else {
+		defer rows.Close()
 		for rows.Next() {
 			var fieldBytes, typeBytes, nullBytes, keyBytes, defaultBytes, extraBytes []byte
 			if err := rows.Scan(&fieldBytes, &typeBytes, &nullBytes, &keyBytes, &defaultBytes, &extraBytes); err != nil {
 				ColorLog("[ERRO] Could not read column information: %s\n", err)
-				os.Exit(2)
+				exitMigration()
 			}
 			fieldStr, typeStr, nullStr, keyStr, defaultStr, extraStr :=
 				string(fieldBytes), string(typeBytes), string(nullBytes), string(keyBytes), string(defaultBytes), string(extraBytes)
@@ -204,42 +351,55 @@ func checkForSchemaUpdateTable(db *sql.DB) {
 				if keyStr != "PRI" || extraStr != "auto_increment" {
 					ColorLog("[ERRO] Column migration.id_migration type mismatch: KEY: %s, EXTRA: %s\n", keyStr, extraStr)
 					ColorLog("[HINT] Expecting KEY: PRI, EXTRA: auto_increment\n")
-					os.Exit(2)
+					exitMigration()
 				}
 			} else if fieldStr == "name" {
 				if !strings.HasPrefix(typeStr, "varchar") || nullStr != "YES" {
 					ColorLog("[ERRO] Column migration.name type mismatch: TYPE: %s, NULL: %s\n", typeStr, nullStr)
 					ColorLog("[HINT] Expecting TYPE: varchar, NULL: YES\n")
-					os.Exit(2)
+					exitMigration()
 				}
 
 			} else if fieldStr == "created_at" {
 				if typeStr != "timestamp" || defaultStr != "CURRENT_TIMESTAMP" {
 					ColorLog("[ERRO] Column migration.timestamp type mismatch: TYPE: %s, DEFAULT: %s\n", typeStr, defaultStr)
 					ColorLog("[HINT] Expecting TYPE: timestamp, DEFAULT: CURRENT_TIMESTAMP\n")
-					os.Exit(2)
+					exitMigration()
 				}
 			}
 		}
 	}
 }
 
+// migrationsTable returns the DDL of the migrations table
+func (d sqlDialect) migrationsTable() string {
+	switch d.driver {
+	case "postgres":
+		return POSTGRES_MIGRATION_DDL
+	case "sqlite", "sqlite3":
+		return SQLITE_MIGRATION_DDL
+	}
+	return MYSQL_MIGRATION_DDL
+}
+
 // getLatestMigration retrives latest migration with status 'update'
 func getLatestMigration(db *sql.DB, goal string) (file string, createdAt int64) {
 	sql := "SELECT name FROM migrations where status = 'update' ORDER BY id_migration DESC LIMIT 1"
 	if rows, err := db.Query(sql); err != nil {
 		ColorLog("[ERRO] Could not retrieve migrations: %s\n", err)
-		os.Exit(2)
+		exitMigration()
 	} else {
+		// an open result set keeps SQLite from writing
+		defer rows.Close()
 		if rows.Next() {
 			if err := rows.Scan(&file); err != nil {
 				ColorLog("[ERRO] Could not read migrations in database: %s\n", err)
-				os.Exit(2)
+				exitMigration()
 			}
 			createdAtStr := file[len(file)-15:]
 			if t, err := time.Parse("20060102_150405", createdAtStr); err != nil {
 				ColorLog("[ERRO] Could not parse time: %s\n", err)
-				os.Exit(2)
+				exitMigration()
 			} else {
 				createdAt = t.Unix()
 			}
@@ -247,7 +407,7 @@ func getLatestMigration(db *sql.DB, goal string) (file string, createdAt int64)
 			// migration table has no 'update' record, no point rolling back
 			if goal == "rollback" {
 				ColorLog("[ERRO] There is nothing to rollback\n")
-				os.Exit(2)
+				exitMigration()
 			}
 			file, createdAt = "", 0
 		}
@@ -260,21 +420,36 @@ func writeMigrationSourceFile(dir, source, driver, connStr string, latestTime in
 	changeDir(dir)
 	if f, err := os.OpenFile(source, os.O_CREATE|os.O_EXCL|os.O_RDWR, 0666); err != nil {
 		ColorLog("[ERRO] Could not create file: %s\n", err)
-		os.Exit(2)
+		exitMigration()
 	} else {
 		content := strings.Replace(MIGRATION_MAIN_TPL, "{{DBDriver}}", driver, -1)
+		content = strings.Replace(content, "{{DriverPkg}}", driverPkg(driver), -1)
 		content = strings.Replace(content, "{{ConnStr}}", connStr, -1)
 		content = strings.Replace(content, "{{LatestTime}}", strconv.FormatInt(latestTime, 10), -1)
 		content = strings.Replace(content, "{{LatestName}}", latestName, -1)
 		content = strings.Replace(content, "{{Task}}", task, -1)
 		if _, err := f.WriteString(content); err != nil {
 			ColorLog("[ERRO] Could not write to file: %s\n", err)
-			os.Exit(2)
+			exitMigration()
+		}
+		if err := writeMigrationBase(dir); err != nil {
+			ColorLog("[ERRO] Could not write %s: %s\n", MIGRATION_BASE_FILE, err)
+			exitMigration()
 		}
 		f.Close()
 	}
 }
 
+// writeMigrationBase writes the txMigration type embedded by the migrations into dir, unless it
+// is there already
+func writeMigrationBase(dir string) error {
+	fpath := path.Join(dir, MIGRATION_BASE_FILE)
+	if isExist(fpath) {
+		return nil
+	}
+	return ioutil.WriteFile(fpath, []byte(MIGRATION_BASE_TPL), 0666)
+}
+
 // buildMigrationBinary changes directory to database/migrations folder and go-build the source
 func buildMigrationBinary(dir, binary string) {
 	changeDir(dir)
@@ -284,7 +459,7 @@ func buildMigrationBinary(dir, binary string) {
 		formatShellErrOutput(string(out))
 		removeTempFile(dir, binary)
 		removeTempFile(dir, binary+".go")
-		os.Exit(2)
+		exitMigration()
 	}
 }
 
@@ -297,7 +472,7 @@ func runMigrationBinary(dir, binary string) {
 		ColorLog("[ERRO] Could not run migration binary: %s\n", err)
 		removeTempFile(dir, binary)
 		removeTempFile(dir, binary+".go")
-		os.Exit(2)
+		exitMigration()
 	} else {
 		formatShellOutput(string(out))
 	}
@@ -308,7 +483,7 @@ func runMigrationBinary(dir, binary string) {
 func changeDir(dir string) {
 	if err := os.Chdir(dir); err != nil {
 		ColorLog("[ERRO] Could not find migration directory: %s\n", err)
-		os.Exit(2)
+		exitMigration()
 	}
 }
 
@@ -349,7 +524,7 @@ import(
 	"github.com/aamsur/beego/orm"
 	"github.com/aamsur/beego/migration"
 
-	_ "github.com/go-sql-driver/mysql"
+	{{DriverPkg}}
 )
 
 func init(){
@@ -358,26 +533,91 @@ func init(){
 
 func main(){
 	task := "{{Task}}"
+	var err error
 	switch task {
 	case "upgrade":
-		if err := migration.Upgrade({{LatestTime}}); err != nil {
-			os.Exit(2)
-		}
+		err = migration.Upgrade({{LatestTime}})
 	case "rollback":
-		if err := migration.Rollback("{{LatestName}}"); err != nil {
-			os.Exit(2)
-		}
+		err = migration.Rollback("{{LatestName}}")
 	case "reset":
-		if err := migration.Reset(); err != nil {
-			os.Exit(2)
-		}
+		err = migration.Reset()
 	case "refresh":
-		if err := migration.Refresh(); err != nil {
-			os.Exit(2)
-		}
+		err = migration.Refresh()
+	}
+	if err != nil {
+		os.Exit(2)
+	}
+}
+
+`
+	MIGRATION_BASE_FILE = "bee_migration.go"
+	MIGRATION_BASE_TPL  = `package main
+
+import (
+	"strings"
+	"time"
+
+	"github.com/aamsur/beego/migration"
+	"github.com/aamsur/beego/orm"
+)
+
+// txMigration is embedded by the migrations: it runs the statements of a migration and its row
+// of the migrations table in one transaction, so a failing migration leaves no trace. MySQL
+// commits schema changes by itself, the transaction only keeps the statements on one session.
+type txMigration struct {
+	migration.Migration
+	sqls []string
+}
+
+// Sql adds a statement to the migration
+func (m *txMigration) Sql(sql string) {
+	m.sqls = append(m.sqls, sql)
+}
+
+// Reset clears the statements of the migration
+func (m *txMigration) Reset() {
+	m.sqls = nil
+}
+
+// Exec runs the statements of the migration, status is up or down
+func (m *txMigration) Exec(name, status string) error {
+	o := orm.NewOrm()
+	if err := o.Begin(); err != nil {
+		return err
 	}
+	err := m.exec(o, name, status)
+	if err != nil {
+		o.Rollback()
+		return err
+	}
+	return o.Commit()
 }
 
+func (m *txMigration) exec(o orm.Ormer, name, status string) error {
+	for _, s := range m.sqls {
+		if _, err := o.Raw(s).Exec(); err != nil {
+			return err
+		}
+	}
+	now := time.Now().Format("2006-01-02 15:04:05")
+	if status == "down" {
+		_, err := o.Raw("UPDATE migrations SET status = ?, rollback_statements = ?, created_at = ? WHERE name = ?",
+			"rollback", strings.Join(m.sqls, "; "), now, name).Exec()
+		return err
+	}
+	_, err := o.Raw("INSERT INTO migrations (name, created_at, statements, status) VALUES (?, ?, ?, ?)",
+		name, now, strings.Join(m.sqls, "; "), "update").Exec()
+	return err
+}
+`
+	MIGRATION_LOCK_NAME  = "bee_migrations"
+	MIGRATION_LOCK_TABLE = "migrations_lock"
+	MIGRATION_LOCK_DDL   = `
+CREATE TABLE IF NOT EXISTS migrations_lock (
+	id_lock int NOT NULL,
+	locked_at timestamp DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (id_lock)
+)
 `
 	MYSQL_MIGRATION_DDL = `
 CREATE TABLE migrations (
@@ -389,5 +629,26 @@ CREATE TABLE migrations (
 	status ENUM('update', 'rollback') COMMENT 'update indicates it is a normal migration while rollback means this migration is rolled back',
 	PRIMARY KEY (id_migration)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8
+`
+	POSTGRES_MIGRATION_DDL = `
+CREATE TABLE migrations (
+	id_migration serial NOT NULL,
+	name varchar(255) DEFAULT NULL,
+	created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	statements text,
+	rollback_statements text,
+	status varchar(8) CHECK (status IN ('update', 'rollback')),
+	PRIMARY KEY (id_migration)
+)
+`
+	SQLITE_MIGRATION_DDL = `
+CREATE TABLE migrations (
+	id_migration integer PRIMARY KEY AUTOINCREMENT,
+	name varchar(255) DEFAULT NULL,
+	created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	statements text,
+	rollback_statements text,
+	status varchar(8) CHECK (status IN ('update', 'rollback'))
+)
 `
 )
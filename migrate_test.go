@@ -0,0 +1,69 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+)
+
+func TestMigrationTransactions(t *testing.T) {
+	wd, _ := os.Getwd()
+	defer os.Chdir(wd)
+	dir := t.TempDir()
+	writeMigrationSourceFile(dir, "m.go", "sqlite3", "conn", 0, "", "upgrade")
+	b, err := ioutil.ReadFile(path.Join(dir, MIGRATION_BASE_FILE))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// each migration commits or rolls back on its own
+	for _, part := range []string{"func (m *txMigration) Exec(name, status string) error {", "o.Begin()", "o.Rollback()", "return o.Commit()"} {
+		if !strings.Contains(string(b), part) {
+			t.Errorf("runner is missing %s:\n%s", part, b)
+		}
+	}
+	if strings.Contains(string(b), `Raw("BEGIN")`) {
+		t.Errorf("runner wraps the whole run in a transaction:\n%s", b)
+	}
+
+	generateMigration("create_posts", `m.Sql("CREATE TABLE posts (id int)")`, `m.Sql("DROP TABLE posts")`, dir, "postgres")
+	// the migrations package needs txMigration to build on its own
+	if !isExist(path.Join(dir, "database", M_PATH, MIGRATION_BASE_FILE)) {
+		t.Fatalf("%s isn't written next to the migrations", MIGRATION_BASE_FILE)
+	}
+	files, _ := ioutil.ReadDir(path.Join(dir, "database", M_PATH))
+	if len(files) != 2 || files[0].Name() == MIGRATION_BASE_FILE {
+		t.Fatalf("unexpected migrations: %v", files)
+	}
+	b, _ = ioutil.ReadFile(path.Join(dir, "database", M_PATH, files[0].Name()))
+	if strings.Contains(string(b), "BEGIN") || strings.Contains(string(b), "FOREIGN_KEY_CHECKS") || !strings.Contains(string(b), "CREATE TABLE posts") || !strings.Contains(string(b), "\ttxMigration\n") {
+		t.Fatalf("unexpected migration:\n%s", b)
+	}
+	if sqlDriverName("postgresql") != "postgres" || !isTransactionalDDL(sqlDriverName("postgresql")) {
+		t.Fatal("postgresql isn't the postgres driver")
+	}
+}
+
+func TestCheckForSchemaUpdateTable(t *testing.T) {
+	db := openSqliteFixture(t)
+	defer db.Close()
+	heldMigrationLock = acquireMigrationLock(db, "sqlite3")
+	defer func() {
+		heldMigrationLock.release()
+		heldMigrationLock = nil
+	}()
+	for i := 0; i < 2; i++ {
+		checkForSchemaUpdateTable(db, "sqlite3")
+	}
+	if name, _ := getLatestMigration(db, "upgrade"); name != "" {
+		t.Errorf("unexpected latest migration of a new table: %s", name)
+	}
+	// the row the runner inserts after a migration
+	if _, err := db.Exec("INSERT INTO migrations (name, created_at, statements, status) VALUES (?, ?, ?, ?)", "CreatePosts_20260102_150405", "2026-01-02 15:04:05", "CREATE TABLE posts2 (id int)", "update"); err != nil {
+		t.Fatal(err)
+	}
+	if name, created := getLatestMigration(db, "rollback"); name != "CreatePosts_20260102_150405" || created == 0 {
+		t.Errorf("unexpected latest migration: %s %d", name, created)
+	}
+}
@@ -0,0 +1,126 @@
+// Copyright 2013 bee authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"strings"
+)
+
+// appcodeNaming holds the naming conventions of bee generate appcode, read from the appcode
+// section of bee.json and the command line
+type appcodeNaming struct {
+	StripPrefix  []string          `json:"strip_prefix"`  // table prefixes left out of the names, e.g. tbl_
+	Singular     bool              `json:"singular"`      // singular model names, e.g. order_items => OrderItem
+	PluralRoutes bool              `json:"plural_routes"` // plural route namespaces, e.g. /order-items
+	Names        map[string]string `json:"names"`         // model names of tables, overriding the conventions
+}
+
+// irregularPlurals maps the irregular singular words to their plural
+var irregularPlurals = map[string]string{
+	"person": "people",
+	"man":    "men",
+	"woman":  "women",
+	"child":  "children",
+	"tooth":  "teeth",
+	"foot":   "feet",
+	"mouse":  "mice",
+	"goose":  "geese",
+}
+
+// modelName returns the name of the model, controller and other Go identifiers of a table
+func modelName(table string) string {
+	if name, ok := conf.Appcode.Names[table]; ok {
+		return name
+	}
+	name := stripTablePrefix(table)
+	if conf.Appcode.Singular {
+		name = singularize(name)
+	}
+	return camelCase(name)
+}
+
+// routeName returns the route namespace of a table, e.g. order_items => order-items
+func routeName(table string) string {
+	name := stripTablePrefix(table)
+	if custom, ok := conf.Appcode.Names[table]; ok {
+		name = snakeString(custom)
+	}
+	if conf.Appcode.PluralRoutes {
+		name = pluralize(name)
+	}
+	return strings.Replace(name, "_", "-", -1)
+}
+
+// sourceFileName returns the name of the model and controller files of a table, without extension
+func sourceFileName(table string) string {
+	name := modelName(table)
+	if name == camelCase(table) {
+		return getFileName(table)
+	}
+	return getFileName(snakeString(name))
+}
+
+// stripTablePrefix removes the first matching prefix of the naming conventions from a table name
+func stripTablePrefix(table string) string {
+	for _, prefix := range conf.Appcode.StripPrefix {
+		if strings.HasPrefix(table, prefix) && len(table) > len(prefix) {
+			return table[len(prefix):]
+		}
+	}
+	return table
+}
+
+// singularize returns the singular of the last word of a _ delimited name, e.g. order_items => order_item
+func singularize(name string) string {
+	i := strings.LastIndex(name, "_") + 1
+	word, lower := name[i:], strings.ToLower(name[i:])
+	for singular, plural := range irregularPlurals {
+		if lower == plural {
+			return name[:i] + singular
+		}
+	}
+	switch {
+	case strings.HasSuffix(lower, "ies") && len(lower) > 3:
+		word = word[:len(word)-3] + "y"
+	case strings.HasSuffix(lower, "sses"), strings.HasSuffix(lower, "xes"), strings.HasSuffix(lower, "zes"),
+		strings.HasSuffix(lower, "ches"), strings.HasSuffix(lower, "shes"):
+		word = word[:len(word)-2]
+	case strings.HasSuffix(lower, "s") && !strings.HasSuffix(lower, "ss") &&
+		!strings.HasSuffix(lower, "us") && !strings.HasSuffix(lower, "is"):
+		word = word[:len(word)-1]
+	}
+	return name[:i] + word
+}
+
+// pluralize returns the plural of the last word of a _ delimited name, e.g. order_item => order_items.
+// Plural names are left as they are.
+func pluralize(name string) string {
+	name = singularize(name)
+	i := strings.LastIndex(name, "_") + 1
+	word, lower := name[i:], strings.ToLower(name[i:])
+	if plural, ok := irregularPlurals[lower]; ok {
+		return name[:i] + plural
+	}
+	switch {
+	case strings.HasSuffix(lower, "y") && len(lower) > 1 && !strings.ContainsRune("aeiou", rune(lower[len(lower)-2])):
+		word = word[:len(word)-1] + "ies"
+	case strings.HasSuffix(lower, "s"), strings.HasSuffix(lower, "x"), strings.HasSuffix(lower, "z"),
+		strings.HasSuffix(lower, "ch"), strings.HasSuffix(lower, "sh"):
+		word += "es"
+	default:
+		word += "s"
+	}
+	return name[:i] + word
+}
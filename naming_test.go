@@ -0,0 +1,35 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestNaming(t *testing.T) {
+	defer func(naming appcodeNaming) { conf.Appcode = naming }(conf.Appcode)
+
+	conf.Appcode = appcodeNaming{}
+	if modelName("tbl_order_items") != "TblOrderItems" || routeName("tbl_order_items") != "tbl-order-items" ||
+		sourceFileName("tbl_order_items") != "tbl_order_items" {
+		t.Errorf("default names changed: %s %s %s", modelName("tbl_order_items"), routeName("tbl_order_items"), sourceFileName("tbl_order_items"))
+	}
+
+	conf.Appcode = appcodeNaming{
+		StripPrefix:  []string{"tbl_"},
+		Singular:     true,
+		PluralRoutes: true,
+		Names:        map[string]string{"tbl_people_addr": "Address"},
+	}
+	for table, expected := range map[string][3]string{
+		"tbl_order_items": {"OrderItem", "order-items", "order_item"},
+		"tbl_categories":  {"Category", "categories", "category"},
+		"tbl_person":      {"Person", "people", "person"},
+		"tbl_boxes":       {"Box", "boxes", "box"},
+		"tbl_status":      {"Status", "statuses", "status"},
+		"tbl_people_addr": {"Address", "addresses", "address"},
+	} {
+		got := [3]string{modelName(table), routeName(table), sourceFileName(table)}
+		if got != expected {
+			t.Errorf("%s: got %v, expected %v", table, got, expected)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+// Copyright 2013 bee authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"bytes"
+	"embed"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path"
+	"text/template"
+)
+
+// TEMPLATES_DIR is the directory of a project overriding the generator templates
+const TEMPLATES_DIR = ".bee/templates"
+
+// defaultTemplates are the generator templates built into bee
+//
+//go:embed templates
+var defaultTemplates embed.FS
+
+// templateSource returns the source of a generator template, from TEMPLATES_DIR when the
+// project overrides it
+func templateSource(name string) string {
+	if content, err := ioutil.ReadFile(path.Join(TEMPLATES_DIR, name)); err == nil {
+		return string(content)
+	}
+	content, err := defaultTemplates.ReadFile(path.Join("templates", name))
+	if err != nil {
+		ColorLog("[ERRO] Unknown template: %s\n", name)
+		os.Exit(2)
+	}
+	return string(content)
+}
+
+// renderTemplate fills in a generator template with data
+func renderTemplate(name string, data map[string]interface{}) string {
+	tpl, err := template.New(name).Option("missingkey=error").Parse(templateSource(name))
+	if err != nil {
+		ColorLog("[ERRO] Could not parse template %s: %s\n", name, err)
+		os.Exit(2)
+	}
+	var buf bytes.Buffer
+	if err := tpl.Execute(&buf, data); err != nil {
+		ColorLog("[ERRO] Could not render template %s: %s\n", name, err)
+		os.Exit(2)
+	}
+	return buf.String()
+}
+
+// ejectTemplates copies the default generator templates to TEMPLATES_DIR, keeping the
+// templates already there
+func ejectTemplates(curpath string) {
+	err := fs.WalkDir(defaultTemplates, "templates", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		fpath := path.Join(curpath, TEMPLATES_DIR, name[len("templates/"):])
+		if isExist(fpath) {
+			ColorLog("[WARN] %s is exist, skip it\n", fpath)
+			return nil
+		}
+		if err := os.MkdirAll(path.Dir(fpath), 0755); err != nil {
+			return err
+		}
+		content, err := defaultTemplates.ReadFile(name)
+		if err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(fpath, content, 0644); err != nil {
+			return err
+		}
+		ColorLog("[INFO] template => %s\n", fpath)
+		return nil
+	})
+	if err != nil {
+		ColorLog("[ERRO] Could not eject templates: %s\n", err)
+		os.Exit(2)
+	}
+}
@@ -0,0 +1,21 @@
+appname = {{.Appname}}
+httpaddr = "127.0.0.1"
+httpport = 8080
+runmode = "dev"
+autorender = false
+copyrequestbody = true
+EnableDocs = true
+pagesize = 10
+maxpagesize = 100
+maxbulksize = 1000
+{{- if eq .Auth "jwt"}}
+tokensecret = "{{.Secret}}"
+accesstokenttl = 900
+{{- end}}
+{{- if .Auth}}
+refreshtokenttl = 2592000
+{{- end}}
+sqlconn = "{{.Conn}}"
+{{- if .PasswordEnv}}
+sqlpasswordenv = "{{.PasswordEnv}}"
+{{- end}}
@@ -0,0 +1,123 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"{{.PkgPath}}/helpers"
+	"{{.PkgPath}}/models"
+
+	"github.com/aamsur/beego"
+)
+
+// operations of the authentication of the API
+type AuthController struct {
+	beego.Controller
+}
+
+func (c *AuthController) URLMapping() {
+	c.Mapping("Login", c.Login)
+	c.Mapping("Refresh", c.Refresh)
+}
+
+// @Title Login
+{{- if eq .Auth "jwt"}}
+// @Description exchange a username and password for an access token and a refresh token
+{{- else}}
+// @Description exchange a username and password for an API key
+{{- end}}
+// @Param	body		body 	models.Credentials	true		"username and password"
+// @Success 200 {object} models.Tokens
+// @Failure 400 body is not valid json
+// @Failure 401 invalid credentials
+// @router /login [post]
+func (c *AuthController) Login() {
+	var v models.Credentials
+	if err := json.Unmarshal(c.Ctx.Input.RequestBody, &v); err != nil {
+		helpers.ServeProblem(c.Ctx, helpers.NewProblem(http.StatusBadRequest, err.Error()))
+		return
+	}
+	u, err := models.Authenticate(v.Username, v.Password)
+	if err != nil {
+		c.serveError(err)
+		return
+	}
+	c.serveTokens(u)
+}
+
+// @Title Refresh
+{{- if eq .Auth "jwt"}}
+// @Description exchange a refresh token, used once, for new tokens
+// @Param	body		body 	models.Tokens	true		"the refresh_token"
+// @Success 200 {object} models.Tokens
+// @Failure 400 body is not valid json
+// @Failure 401 invalid or expired refresh token
+// @router /refresh [post]
+func (c *AuthController) Refresh() {
+	var v models.Tokens
+	if err := json.Unmarshal(c.Ctx.Input.RequestBody, &v); err != nil {
+		helpers.ServeProblem(c.Ctx, helpers.NewProblem(http.StatusBadRequest, err.Error()))
+		return
+	}
+	token := v.RefreshToken
+	u, err := models.GetAuthTokenUser(token, models.RefreshToken)
+{{- else}}
+// @Description exchange the API key of the X-API-Key header for a new one, the old key is revoked
+// @Success 200 {object} models.Tokens
+// @Failure 401 invalid or expired API key
+// @router /refresh [post]
+func (c *AuthController) Refresh() {
+	token := c.Ctx.Input.Header("X-API-Key")
+	u, err := models.GetAuthTokenUser(token, models.ApiKey)
+{{- end}}
+	if err != nil {
+		c.serveError(err)
+		return
+	}
+	if err := models.DeleteAuthToken(token); err != nil {
+		c.serveError(err)
+		return
+	}
+	c.serveTokens(u)
+}
+
+// serveTokens answers with new tokens of a user
+func (c *AuthController) serveTokens(u *models.AuthUser) {
+{{- if eq .Auth "jwt"}}
+	access, err := helpers.NewAccessToken(u.Principal())
+	if err != nil {
+		c.serveError(err)
+		return
+	}
+	refresh, err := models.AddAuthToken(u, models.RefreshToken, helpers.RefreshTokenTTL)
+	if err != nil {
+		c.serveError(err)
+		return
+	}
+	c.Data["json"] = helpers.NewResponse().Ok(models.Tokens{
+		AccessToken:  access,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(helpers.AccessTokenTTL.Seconds()),
+		RefreshToken: refresh,
+	})
+{{- else}}
+	key, err := models.AddAuthToken(u, models.ApiKey, helpers.RefreshTokenTTL)
+	if err != nil {
+		c.serveError(err)
+		return
+	}
+	c.Data["json"] = helpers.NewResponse().Ok(models.Tokens{
+		ApiKey:    key,
+		ExpiresIn: int64(helpers.RefreshTokenTTL.Seconds()),
+	})
+{{- end}}
+	c.ServeJson()
+}
+
+func (c *AuthController) serveError(err error) {
+	if err == helpers.ErrInvalidCredentials {
+		helpers.ServeProblem(c.Ctx, helpers.NewProblem(http.StatusUnauthorized, err.Error()))
+		return
+	}
+	helpers.ServeProblem(c.Ctx, helpers.ErrorProblem(err))
+}
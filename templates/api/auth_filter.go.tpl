@@ -0,0 +1,72 @@
+package filters
+
+import (
+	"net/http"
+{{- if eq .Auth "jwt"}}
+	"strings"
+{{- end}}
+
+	"{{.PkgPath}}/helpers"
+{{- if ne .Auth "jwt"}}
+	"{{.PkgPath}}/models"
+{{- end}}
+
+	"github.com/aamsur/beego/context"
+)
+
+// PublicPaths are the routes served without credentials
+var PublicPaths = map[string]bool{
+	"/v1/auth/login":   true,
+	"/v1/auth/refresh": true,
+}
+
+{{- if eq .Auth "jwt"}}
+
+// Auth authenticates the requests by the access token of their Authorization: Bearer header,
+// the others get 401
+func Auth(ctx *context.Context) {
+	if ctx.Request.Method == "OPTIONS" || PublicPaths[ctx.Request.URL.Path] {
+		return
+	}
+	header := ctx.Input.Header("Authorization")
+	if !strings.HasPrefix(header, "Bearer ") {
+		unauthorized(ctx, "missing bearer token")
+		return
+	}
+	p, err := helpers.ParseAccessToken(strings.TrimPrefix(header, "Bearer "))
+	if err != nil {
+		unauthorized(ctx, "invalid or expired token")
+		return
+	}
+	helpers.SetPrincipal(ctx, p)
+}
+
+func unauthorized(ctx *context.Context, detail string) {
+	ctx.Output.Header("WWW-Authenticate", `Bearer realm="api"`)
+	helpers.ServeProblem(ctx, helpers.NewProblem(http.StatusUnauthorized, detail))
+}
+{{- else}}
+
+// Auth authenticates the requests by the API key of their X-API-Key header, the others get 401
+func Auth(ctx *context.Context) {
+	if ctx.Request.Method == "OPTIONS" || PublicPaths[ctx.Request.URL.Path] {
+		return
+	}
+	key := ctx.Input.Header("X-API-Key")
+	if key == "" {
+		helpers.ServeProblem(ctx, helpers.NewProblem(http.StatusUnauthorized, "missing API key"))
+		return
+	}
+	u, err := models.GetAuthTokenUser(key, models.ApiKey)
+	if err == helpers.ErrInvalidCredentials {
+		helpers.ServeProblem(ctx, helpers.NewProblem(http.StatusUnauthorized, "invalid or expired API key"))
+		return
+	}
+	if err != nil {
+		helpers.ServeProblem(ctx, helpers.ErrorProblem(err))
+		return
+	}
+	p := u.Principal()
+	helpers.SetPrincipal(ctx, &p)
+}
+{{- end}}
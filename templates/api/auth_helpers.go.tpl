@@ -0,0 +1,151 @@
+package helpers
+
+import (
+{{- if eq .Auth "jwt"}}
+	"crypto/hmac"
+{{- end}}
+	"crypto/rand"
+	"crypto/sha256"
+{{- if eq .Auth "jwt"}}
+	"encoding/base64"
+{{- end}}
+	"encoding/hex"
+{{- if eq .Auth "jwt"}}
+	"encoding/json"
+{{- end}}
+	"errors"
+{{- if eq .Auth "jwt"}}
+	"strings"
+{{- end}}
+	"time"
+
+	"github.com/aamsur/beego/context"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// TokenSecret signs the access tokens, AccessTokenTTL and RefreshTokenTTL are the lifetimes of
+// the access tokens and of the refresh tokens or API keys. main sets them from tokensecret,
+// accesstokenttl and refreshtokenttl in app.conf.
+var (
+	TokenSecret     []byte
+	AccessTokenTTL  = 15 * time.Minute
+	RefreshTokenTTL = 30 * 24 * time.Hour
+)
+
+// ErrInvalidCredentials is the error of a wrong password, or of an unknown or expired token
+var ErrInvalidCredentials = errors.New("invalid credentials")
+
+// Principal is the authenticated user of a request
+type Principal struct {
+	UserId   int    `json:"sub"`
+	Username string `json:"name"`
+	Role     string `json:"role"`
+}
+
+// SetPrincipal stores the authenticated user of a request, called by the auth filter
+func SetPrincipal(ctx *context.Context, p *Principal) {
+	ctx.Input.SetData("principal", p)
+}
+
+// CurrentPrincipal returns the authenticated user of a request, nil on public routes
+func CurrentPrincipal(ctx *context.Context) *Principal {
+	p, _ := ctx.Input.GetData("principal").(*Principal)
+	return p
+}
+
+// SetOwnerOnly restricts a request to the rows owned by its user, called by the authorization filter
+func SetOwnerOnly(ctx *context.Context) {
+	ctx.Input.SetData("owner_only", true)
+}
+
+// OwnerOnly returns the id of the user of a request restricted to the rows it owns
+func OwnerOnly(ctx *context.Context) (userId int, ok bool) {
+	p := CurrentPrincipal(ctx)
+	if only, _ := ctx.Input.GetData("owner_only").(bool); !only || p == nil {
+		return 0, false
+	}
+	return p.UserId, true
+}
+
+// IsOwner reports whether a request may access a row owned by a user
+func IsOwner(ctx *context.Context, ownerId int) bool {
+	userId, ok := OwnerOnly(ctx)
+	return !ok || userId == ownerId
+}
+
+// HashPassword returns the bcrypt hash of a password, stored instead of the password
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	return string(hash), err
+}
+
+// CheckPassword reports whether a password matches its hash
+func CheckPassword(hash, password string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
+
+// NewToken returns a random token, given once to the client, and its hash stored in auth_tokens
+func NewToken() (token, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err = rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token = hex.EncodeToString(b)
+	return token, HashToken(token), nil
+}
+
+// HashToken returns the hash of a token, under which it is stored
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+{{- if eq .Auth "jwt"}}
+
+// claims is the payload of an access token
+type claims struct {
+	Principal
+	IssuedAt int64 `json:"iat"`
+	Expires  int64 `json:"exp"`
+}
+
+// jwtHeader is the encoded header of the access tokens, signed with HMAC SHA-256
+var jwtHeader = base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+
+// NewAccessToken returns a JSON web token of a user, valid for AccessTokenTTL
+func NewAccessToken(p Principal) (string, error) {
+	now := time.Now()
+	payload, err := json.Marshal(claims{Principal: p, IssuedAt: now.Unix(), Expires: now.Add(AccessTokenTTL).Unix()})
+	if err != nil {
+		return "", err
+	}
+	unsigned := jwtHeader + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return unsigned + "." + signToken(unsigned), nil
+}
+
+// ParseAccessToken checks the signature and the expiry of an access token and returns its user
+func ParseAccessToken(token string) (*Principal, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 || parts[0] != jwtHeader {
+		return nil, ErrInvalidCredentials
+	}
+	if !hmac.Equal([]byte(parts[2]), []byte(signToken(parts[0]+"."+parts[1]))) {
+		return nil, ErrInvalidCredentials
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, ErrInvalidCredentials
+	}
+	var c claims
+	if err := json.Unmarshal(payload, &c); err != nil || time.Now().Unix() >= c.Expires {
+		return nil, ErrInvalidCredentials
+	}
+	return &c.Principal, nil
+}
+
+// signToken returns the encoded signature of the header and payload of a token
+func signToken(unsigned string) string {
+	mac := hmac.New(sha256.New, TokenSecret)
+	mac.Write([]byte(unsigned))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+{{- end}}
@@ -0,0 +1,127 @@
+package models
+
+import (
+	"time"
+
+	"{{.PkgPath}}/helpers"
+
+	"github.com/aamsur/beego/orm"
+)
+
+// the kinds of the tokens of auth_tokens
+const (
+	RefreshToken = "refresh"
+	ApiKey       = "apikey"
+)
+
+// AuthUser is a user of the API, its password is stored as a bcrypt hash
+type AuthUser struct {
+	Id           int       `orm:"column(id);auto" json:"id"`
+	Username     string    `orm:"column(username);size(128);unique" json:"username"`
+	PasswordHash string    `orm:"column(password_hash);size(255)" json:"-"`
+	Role         string    `orm:"column(role);size(64)" json:"role"`
+	Created      time.Time `orm:"column(created);type(datetime);auto_now_add" json:"created"`
+}
+
+func (t *AuthUser) TableName() string {
+	return "auth_users"
+}
+
+// AuthToken is a refresh token or an API key of a user, stored as a hash
+type AuthToken struct {
+	Id        int       `orm:"column(id);auto" json:"id"`
+	User      *AuthUser `orm:"column(user_id);rel(fk);on_delete(cascade)" json:"user_id"`
+	Kind      string    `orm:"column(kind);size(16)" json:"kind"`
+	TokenHash string    `orm:"column(token_hash);size(64);unique" json:"-"`
+	Expires   time.Time `orm:"column(expires);type(datetime)" json:"expires"`
+	Created   time.Time `orm:"column(created);type(datetime);auto_now_add" json:"created"`
+}
+
+func (t *AuthToken) TableName() string {
+	return "auth_tokens"
+}
+
+func init() {
+	orm.RegisterModel(new(AuthUser), new(AuthToken))
+}
+
+// Credentials is the body of a login request
+type Credentials struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// Tokens is the answer of a login or refresh request
+type Tokens struct {
+{{- if eq .Auth "jwt"}}
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+	RefreshToken string `json:"refresh_token"`
+{{- else}}
+	ApiKey    string `json:"api_key"`
+	ExpiresIn int64  `json:"expires_in"`
+{{- end}}
+}
+
+// Principal returns the authenticated user of the requests of a user
+func (u *AuthUser) Principal() helpers.Principal {
+	return helpers.Principal{UserId: u.Id, Username: u.Username, Role: u.Role}
+}
+
+// AddAuthUser creates a user with the hash of a password
+func AddAuthUser(username, password, role string) (id int64, err error) {
+	hash, err := helpers.HashPassword(password)
+	if err != nil {
+		return 0, err
+	}
+	return orm.NewOrm().Insert(&AuthUser{Username: username, PasswordHash: hash, Role: role})
+}
+
+// Authenticate returns the user of a username and password, helpers.ErrInvalidCredentials if
+// they don't match
+func Authenticate(username, password string) (*AuthUser, error) {
+	u := AuthUser{Username: username}
+	if err := orm.NewOrm().Read(&u, "Username"); err != nil {
+		if err == orm.ErrNoRows {
+			return nil, helpers.ErrInvalidCredentials
+		}
+		return nil, err
+	}
+	if !helpers.CheckPassword(u.PasswordHash, password) {
+		return nil, helpers.ErrInvalidCredentials
+	}
+	return &u, nil
+}
+
+// AddAuthToken creates a token of a kind for a user, valid for ttl, and returns it. Only its hash
+// is stored, the token can't be read again.
+func AddAuthToken(u *AuthUser, kind string, ttl time.Duration) (string, error) {
+	token, hash, err := helpers.NewToken()
+	if err != nil {
+		return "", err
+	}
+	_, err = orm.NewOrm().Insert(&AuthToken{User: u, Kind: kind, TokenHash: hash, Expires: time.Now().Add(ttl)})
+	return token, err
+}
+
+// GetAuthTokenUser returns the user of a token of a kind, helpers.ErrInvalidCredentials if the
+// token is unknown or expired
+func GetAuthTokenUser(token, kind string) (*AuthUser, error) {
+	var t AuthToken
+	err := orm.NewOrm().QueryTable(new(AuthToken)).Filter("token_hash", helpers.HashToken(token)).
+		Filter("kind", kind).Filter("expires__gt", time.Now()).RelatedSel().One(&t)
+	if err == orm.ErrNoRows {
+		return nil, helpers.ErrInvalidCredentials
+	}
+	if err != nil {
+		return nil, err
+	}
+	return t.User, nil
+}
+
+// DeleteAuthToken revokes a token, it is used once when rotated by a refresh request
+func DeleteAuthToken(token string) error {
+	_, err := orm.NewOrm().QueryTable(new(AuthToken)).Filter("token_hash", helpers.HashToken(token)).Delete()
+	return err
+}
@@ -0,0 +1,123 @@
+package helpers
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/aamsur/beego/orm"
+)
+
+// MaxBulkSize is the most items of a bulk request, main sets it from maxbulksize in app.conf
+var MaxBulkSize = 1000
+
+// BulkResult is the outcome of an item of a bulk request: 200 or 201 when written, the status of
+// its problem otherwise, and 424 when it wasn't written because another item failed
+type BulkResult struct {
+	Index  int               `json:"index"`
+	Status int               `json:"status"`
+	Detail string            `json:"detail,omitempty"`
+	Errors map[string]string `json:"errors,omitempty"` // messages of the invalid fields
+}
+
+// Bulk collects the results of the items of a bulk request. Without partial=true the request is
+// all or nothing: no item is written when one is invalid or fails.
+type Bulk struct {
+	Partial bool
+	Results []BulkResult
+	failed  bool
+}
+
+// NewBulk returns the bulk of a request of n items
+func NewBulk(qs url.Values, n int) (b *Bulk, err error) {
+	if n == 0 || n > MaxBulkSize {
+		return nil, fmt.Errorf("a bulk request takes 1 to %d items", MaxBulkSize)
+	}
+	b = &Bulk{Results: make([]BulkResult, n)}
+	if v := qs.Get("partial"); v != "" {
+		if b.Partial, err = strconv.ParseBool(v); err != nil {
+			return nil, fmt.Errorf("partial must be a boolean")
+		}
+	}
+	for i := range b.Results {
+		b.Results[i] = BulkResult{Index: i, Status: http.StatusFailedDependency}
+	}
+	return b, nil
+}
+
+// Fail sets the problem of an item
+func (b *Bulk) Fail(i int, p *Problem) {
+	b.Results[i] = BulkResult{Index: i, Status: p.Status, Detail: p.Detail, Errors: p.Errors}
+	b.failed = true
+}
+
+// Failed reports whether an item failed
+func (b *Bulk) Failed(i int) bool {
+	return b.Results[i].Status != http.StatusFailedDependency && b.Results[i].Status >= 400
+}
+
+// Proceed reports whether the valid items are written: always when partial, else only when every
+// item is valid
+func (b *Bulk) Proceed() bool {
+	return b.Partial || !b.failed
+}
+
+// Done sets the results of the items written, index are the items of errs. When an item fails
+// and the request is all or nothing, the others are left 424.
+func (b *Bulk) Done(index []int, errs []error, status int) {
+	rolledBack := false
+	for k, i := range index {
+		if errs[k] != nil {
+			b.Fail(i, ErrorProblem(errs[k]))
+			rolledBack = !b.Partial
+		}
+	}
+	for k, i := range index {
+		if errs[k] == nil && !rolledBack {
+			b.Results[i] = BulkResult{Index: i, Status: status}
+		}
+	}
+}
+
+// Response returns the status and the response of a bulk request, with the results of its items:
+// status when every item was written, 207 when a partial request failed on some, and the status
+// of the first failure when an all or nothing request was rolled back
+func (b *Bulk) Response(status int) (int, *Response) {
+	r := NewResponse()
+	switch {
+	case !b.failed:
+		return status, r.Ok(b.Results)
+	case b.Partial:
+		return http.StatusMultiStatus, r.Ok(b.Results)
+	}
+	for _, result := range b.Results {
+		if result.Status != http.StatusFailedDependency {
+			status = result.Status
+			break
+		}
+	}
+	r.Data = b.Results
+	return status, r
+}
+
+// RunBulk runs op on n records with an orm, in a transaction unless partial. Without partial the
+// first error stops and rolls back the transaction. errs holds the error of each record.
+func RunBulk(o orm.Ormer, n int, partial bool, op func(i int) error) (errs []error, err error) {
+	errs = make([]error, n)
+	if !partial {
+		if err = o.Begin(); err != nil {
+			return nil, err
+		}
+	}
+	for i := 0; i < n; i++ {
+		if errs[i] = op(i); errs[i] != nil && !partial {
+			o.Rollback()
+			return errs, nil
+		}
+	}
+	if !partial {
+		err = o.Commit()
+	}
+	return errs, err
+}
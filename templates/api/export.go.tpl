@@ -0,0 +1,445 @@
+package helpers
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aamsur/beego"
+	"github.com/aamsur/beego/context"
+	"github.com/aamsur/beego/orm"
+)
+
+// ExportChunk is the number of records an export reads at a time
+var ExportChunk int64 = 500
+
+// exportTypes are the content types of the export formats
+var exportTypes = map[string]string{
+	"csv":  "text/csv",
+	"xlsx": "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+}
+
+// ExportFormat returns the format asked by the format parameter or the Accept header of a request,
+// csv or xlsx, "" for JSON
+func ExportFormat(ctx *context.Context) (string, error) {
+	if format := ctx.Request.URL.Query().Get("format"); format != "" {
+		if format == "json" {
+			return "", nil
+		}
+		if _, ok := exportTypes[format]; !ok {
+			return "", fmt.Errorf("format must be json, csv or xlsx")
+		}
+		return format, nil
+	}
+	accept := ctx.Input.Header("Accept")
+	for format, typ := range exportTypes {
+		if strings.Contains(accept, typ) {
+			return format, nil
+		}
+	}
+	return "", nil
+}
+
+// Export writes records to the response of a request as the rows of a CSV or XLSX file. The
+// response is buffered, so that an export failing on its first records still answers a problem.
+type Export struct {
+	out    *exportWriter
+	buf    *bufio.Writer
+	csv    *csv.Writer
+	xlsx   *zip.Writer
+	sheet  io.Writer
+	fields []int
+}
+
+// NewExport returns the export of a request in a format, served as the file name.format
+func NewExport(ctx *context.Context, format, name string) *Export {
+	out := &exportWriter{ctx: ctx, contentType: exportTypes[format], filename: name + "." + format}
+	e := &Export{out: out, buf: bufio.NewWriterSize(out, 64<<10)}
+	if format == "csv" {
+		e.csv = csv.NewWriter(e.buf)
+	} else {
+		e.xlsx = zip.NewWriter(e.buf)
+	}
+	return e
+}
+
+// Header writes the header row, the columns of a model or the JSON names of its fields. Relations
+// are left out.
+func (e *Export) Header(model interface{}, columns []string) error {
+	t := reflect.Indirect(reflect.ValueOf(model)).Type()
+	index := fieldIndex(t)
+	if len(columns) == 0 {
+		for i := 0; i < t.NumField(); i++ {
+			if name := fieldName(t.Field(i)); name != "" {
+				columns = append(columns, name)
+			}
+		}
+	}
+	e.fields = nil
+	for _, column := range columns {
+		i, ok := index[column]
+		if !ok {
+			return fmt.Errorf("unknown column %s", column)
+		}
+		e.fields = append(e.fields, i)
+	}
+	values := make([]interface{}, len(columns))
+	for i, column := range columns {
+		values[i] = column
+	}
+	return e.writeRow(values)
+}
+
+// Record writes a record, a pointer to a model, as a row
+func (e *Export) Record(v interface{}) error {
+	val := reflect.Indirect(reflect.ValueOf(v))
+	values := make([]interface{}, len(e.fields))
+	for i, field := range e.fields {
+		values[i] = cellValue(val.Field(field))
+	}
+	return e.writeRow(values)
+}
+
+// Close writes the end of the file to the response
+func (e *Export) Close() error {
+	if e.csv != nil {
+		e.csv.Flush()
+		if err := e.csv.Error(); err != nil {
+			return err
+		}
+	} else if err := e.closeXlsx(); err != nil {
+		return err
+	}
+	return e.buf.Flush()
+}
+
+// Fail answers the problem of an error when nothing was sent yet, otherwise the file is cut and
+// the error logged
+func (e *Export) Fail(err error) {
+	if !e.out.started {
+		ServeProblem(e.out.ctx, ErrorProblem(err))
+		return
+	}
+	beego.Error("export of", e.out.filename, "failed:", err)
+}
+
+// ServeExport answers a request for a CSV or XLSX file with the records of a list, named after
+// name. It returns false when the request asks for JSON.
+func ServeExport(ctx *context.Context, name string, list []interface{}) bool {
+	format, err := ExportFormat(ctx)
+	if err != nil {
+		ServeProblem(ctx, NewProblem(http.StatusBadRequest, err.Error()))
+		return true
+	}
+	if format == "" {
+		return false
+	}
+	e := NewExport(ctx, format, name)
+	if len(list) == 0 {
+		err = e.Close()
+	} else if err = e.Header(list[0], nil); err == nil {
+		for _, v := range list {
+			if err = e.Record(v); err != nil {
+				break
+			}
+		}
+		if err == nil {
+			err = e.Close()
+		}
+	}
+	if err != nil {
+		e.Fail(err)
+	}
+	return true
+}
+
+// Chunks reads the records of a query ExportChunk at a time, in the order of the query and the
+// key of the model, until read returns fewer records
+func (q *Query) Chunks(qs orm.QuerySeter, read func(qs orm.QuerySeter) (int64, error)) error {
+	order := append([]string{}, q.Sort...)
+	if q.fields.Key != "" {
+		order = append(order, q.fields.Key)
+	}
+	qs = qs.OrderBy(order...)
+	for offset := int64(0); ; offset += ExportChunk {
+		n, err := read(qs.Limit(ExportChunk, offset))
+		if err != nil || n < ExportChunk {
+			return err
+		}
+	}
+}
+
+func (e *Export) writeRow(values []interface{}) error {
+	if e.csv != nil {
+		row := make([]string, len(values))
+		for i, v := range values {
+			if v != nil {
+				row[i] = fmt.Sprint(v)
+			}
+		}
+		return e.csv.Write(row)
+	}
+	if err := e.openSheet(); err != nil {
+		return err
+	}
+	var row bytes.Buffer
+	row.WriteString("<row>")
+	for _, v := range values {
+		switch v := v.(type) {
+		case nil:
+			row.WriteString("<c/>")
+		case bool:
+			if v {
+				row.WriteString(`<c t="b"><v>1</v></c>`)
+			} else {
+				row.WriteString(`<c t="b"><v>0</v></c>`)
+			}
+		case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+			fmt.Fprintf(&row, "<c><v>%v</v></c>", v)
+		default:
+			row.WriteString(`<c t="inlineStr"><is><t>`)
+			xml.EscapeText(&row, []byte(fmt.Sprint(v)))
+			row.WriteString("</t></is></c>")
+		}
+	}
+	row.WriteString("</row>")
+	_, err := e.sheet.Write(row.Bytes())
+	return err
+}
+
+// xlsxParts are the files of a workbook besides its sheet
+var xlsxParts = [][2]string{
+	{"[Content_Types].xml", `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="xml" ContentType="application/xml"/><Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/><Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/></Types>`},
+	{"_rels/.rels", `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/></Relationships>`},
+	{"xl/workbook.xml", `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="Sheet1" sheetId="1" r:id="rId1"/></sheets></workbook>`},
+	{"xl/_rels/workbook.xml.rels", `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/></Relationships>`},
+}
+
+// openSheet starts the sheet of a workbook, it holds the rows
+func (e *Export) openSheet() error {
+	if e.sheet != nil {
+		return nil
+	}
+	sheet, err := e.xlsx.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return err
+	}
+	e.sheet = sheet
+	_, err = io.WriteString(sheet, xml.Header+`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+	return err
+}
+
+func (e *Export) closeXlsx() error {
+	if err := e.openSheet(); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(e.sheet, "</sheetData></worksheet>"); err != nil {
+		return err
+	}
+	for _, part := range xlsxParts {
+		w, err := e.xlsx.Create(part[0])
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(w, xml.Header+part[1]); err != nil {
+			return err
+		}
+	}
+	return e.xlsx.Close()
+}
+
+// exportWriter sets the headers of a file download on its first write to the response
+type exportWriter struct {
+	ctx         *context.Context
+	contentType string
+	filename    string
+	started     bool
+}
+
+func (w *exportWriter) Write(p []byte) (int, error) {
+	if !w.started {
+		w.started = true
+		w.ctx.Output.Header("Content-Type", w.contentType)
+		w.ctx.Output.Header("Content-Disposition", `attachment; filename="`+w.filename+`"`)
+	}
+	return w.ctx.ResponseWriter.Write(p)
+}
+
+// cellValue returns the value of a field in a file: times are RFC 3339, NULL is nil and related
+// models are their id
+func cellValue(f reflect.Value) interface{} {
+	switch v := f.Interface().(type) {
+	case time.Time:
+		if v.IsZero() {
+			return nil
+		}
+		return v.Format(time.RFC3339)
+	case driver.Valuer:
+		value, err := v.Value()
+		if err != nil || value == nil {
+			return nil
+		}
+		return cellValue(reflect.ValueOf(value))
+	}
+	if f.Kind() == reflect.Ptr {
+		if f.IsNil() {
+			return nil
+		}
+		if f.Elem().Kind() == reflect.Struct && f.Elem().FieldByName("Id").IsValid() {
+			return f.Elem().FieldByName("Id").Interface()
+		}
+		return cellValue(f.Elem())
+	}
+	return f.Interface()
+}
+
+// ImportCSV reads the records of a CSV body into a pointer to a slice of models. The header row
+// names the columns, the JSON names of the fields; empty cells are NULL. The rows that can't be
+// read are failed in the returned bulk, their records are left empty.
+func ImportCSV(body []byte, qs url.Values, list interface{}) (*Bulk, error) {
+	rows, err := csv.NewReader(bytes.NewReader(body)).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("the header row is missing")
+	}
+	bulk, err := NewBulk(qs, len(rows)-1)
+	if err != nil {
+		return nil, err
+	}
+	l := reflect.ValueOf(list).Elem()
+	index := fieldIndex(l.Type().Elem())
+	fields := make([]int, len(rows[0]))
+	for i, column := range rows[0] {
+		var ok bool
+		if fields[i], ok = index[strings.TrimSpace(column)]; !ok {
+			return nil, fmt.Errorf("unknown column %s", column)
+		}
+	}
+	l.Set(reflect.MakeSlice(l.Type(), len(rows)-1, len(rows)-1))
+	for i, row := range rows[1:] {
+		errData := map[string]string{}
+		for j, cell := range row {
+			if err := setCell(l.Index(i).Field(fields[j]), cell); err != nil {
+				errData[rows[0][j]] = err.Error()
+			}
+		}
+		if len(errData) > 0 {
+			bulk.Fail(i, ValidationProblem(errData))
+			l.Index(i).Set(reflect.Zero(l.Type().Elem()))
+		}
+	}
+	return bulk, nil
+}
+
+// setCell sets a field to the text of a cell, converted to its type
+func setCell(f reflect.Value, s string) error {
+	if s == "" {
+		return nil
+	}
+	switch f.Interface().(type) {
+	case time.Time:
+		t, err := parseTime(s)
+		if err == nil {
+			f.Set(reflect.ValueOf(t))
+		}
+		return err
+	case sql.NullTime:
+		t, err := parseTime(s)
+		if err == nil {
+			f.Set(reflect.ValueOf(sql.NullTime{Time: t, Valid: true}))
+		}
+		return err
+	}
+	if scanner, ok := f.Addr().Interface().(sql.Scanner); ok {
+		return scanner.Scan(s)
+	}
+	switch f.Kind() {
+	case reflect.Ptr:
+		v := reflect.New(f.Type().Elem())
+		target := v.Elem()
+		if target.Kind() == reflect.Struct && target.FieldByName("Id").IsValid() {
+			// a related model, the cell is its id
+			target = target.FieldByName("Id")
+		}
+		if err := setCell(target, s); err != nil {
+			return err
+		}
+		f.Set(v)
+	case reflect.String:
+		f.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return fmt.Errorf("must be a boolean")
+		}
+		f.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, f.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("must be an integer")
+		}
+		f.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, f.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("must be a positive integer")
+		}
+		f.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(s, f.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("must be a number")
+		}
+		f.SetFloat(n)
+	default:
+		return fmt.Errorf("can't be imported")
+	}
+	return nil
+}
+
+// parseTime parses the times of the files, RFC 3339 or 2006-01-02
+func parseTime(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		return t, fmt.Errorf("must be a RFC 3339 time or a 2006-01-02 date")
+	}
+	return t, nil
+}
+
+// fieldIndex returns the fields of a model by their column, the JSON name of the field
+func fieldIndex(t reflect.Type) map[string]int {
+	index := map[string]int{}
+	for i := 0; i < t.NumField(); i++ {
+		if name := fieldName(t.Field(i)); name != "" {
+			index[name] = i
+		}
+	}
+	return index
+}
+
+// fieldName returns the column of a field of a model, "" for relations and hidden fields
+func fieldName(f reflect.StructField) string {
+	name := strings.Split(f.Tag.Get("json"), ",")[0]
+	orm := f.Tag.Get("orm")
+	if name == "" || name == "-" || (orm != "" && !strings.Contains(orm, "column(")) {
+		return ""
+	}
+	return name
+}
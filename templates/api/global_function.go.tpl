@@ -0,0 +1,445 @@
+package helpers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aamsur/beego/orm"
+	"github.com/aamsur/beego/validation"
+)
+
+// Get input keys
+func GetInputKeys(input []byte) []string {
+	// convert input into map
+	var objmap map[string]*json.RawMessage // ambil input jadikan map
+	json.Unmarshal(input, &objmap)
+
+	// get the keys
+	// ambil keys ganti jadi slice
+	keys := make([]string, 0, len(objmap))
+	for k := range objmap {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// function validator, it returns the error messages of the invalid fields to put in Response.Fail
+// when the input keys are given, only the fields set from them are checked, as in partial updates
+func Validator(model interface{}, keys ...string) (bool, map[string]string) {
+
+	errorData := make(map[string]string)
+	valid := validation.Validation{}
+
+	valid.Valid(model)
+	for _, err := range valid.Errors {
+		field := strings.Split(err.Key, ".")
+		if len(keys) > 0 && !isInputField(model, field[0], keys) {
+			continue
+		}
+		errorData[field[0]] = err.Message
+	}
+	return len(errorData) == 0, errorData
+}
+
+// isInputField reports whether a field of a model is set from one of the input keys, by its json name
+func isInputField(model interface{}, field string, keys []string) bool {
+	t := reflect.TypeOf(model)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	f, ok := t.FieldByName(field)
+	if !ok {
+		return true
+	}
+	name := strings.Split(f.Tag.Get("json"), ",")[0]
+	for _, k := range keys {
+		if k == name {
+			return true
+		}
+	}
+	return false
+}
+
+// QueryFields is the whitelist of the fields of a model that GetAll requests filter, sort and
+// join by. The generated whitelist can be extended in an init function, e.g. with the fields of
+// joined models: PostsQuery.Filter["author_id__name"] = "string"
+type QueryFields struct {
+	Filter   map[string]string // fields and the type of their values: string, int, uint, float, bool or time
+	Sort     []string
+	Nullable []string // sort fields that can be NULL, which a cursor can't hold: their pages use offsets
+	Join     []string
+	Key      string // the key of the cursors of keyset pages, none for offset pages only
+}
+
+// Query is a GetAll request parsed by ParseQuery
+type Query struct {
+	Cond        *orm.Condition
+	Fields      []string
+	GroupBy     []string
+	Sort        []string // orm order expressions, e.g. -created
+	Join        []string
+	Offset      int64
+	Limit       int64
+	Count       bool // count the matching records, expensive on big tables
+	WithDeleted bool // read the soft-deleted records too
+	cursor      *cursor
+	values      url.Values
+	fields      QueryFields
+}
+
+// queryOperators are the operators of the filter grammar
+var queryOperators = map[string]bool{
+	"exact": true, "iexact": true, "contains": true, "icontains": true,
+	"startswith": true, "istartswith": true, "endswith": true, "iendswith": true,
+	"gt": true, "gte": true, "lt": true, "lte": true, "in": true, "between": true,
+	"isnull": true, "null": true, "notnull": true,
+}
+
+// ParseQuery parses the query string of a GetAll request, only the fields of the whitelist are
+// accepted. The query parameter is a list of conditions:
+//
+//	query = group *("|" group)           the groups are combined with AND
+//	group = term *("," term)
+//	term  = [And. | Or. | Ex. | OrNot.] field [.operator] [":" value]
+//	value = text | "(" text *("," text) ")"
+//
+// e.g. title.icontains:bee,Or.score.gt:4.5|id.in:(1,2,3). The lists are the values of in and
+// between, fields of joined models are written author_id.name and a \ escapes the next character
+// of a value, e.g. name:Smith\, John. Values are converted to the type of their field, times are
+// RFC 3339 or 2006-01-02.
+func ParseQuery(qs url.Values, fields QueryFields) (q *Query, err error) {
+	q = &Query{Cond: orm.NewCondition()}
+	if q.Fields, err = fields.columns(qs.Get("fields")); err != nil {
+		return nil, err
+	}
+	if q.GroupBy, err = fields.columns(qs.Get("groupby")); err != nil {
+		return nil, err
+	}
+
+	var sortby, order []string
+	if v := qs.Get("sortby"); v != "" {
+		for _, name := range strings.Split(strings.Replace(v, ".", "__", -1), ",") {
+			if !contains(fields.Sort, name) {
+				return nil, fmt.Errorf("can't sort by %s", name)
+			}
+			sortby = append(sortby, name)
+		}
+	}
+	if v := qs.Get("order"); v != "" {
+		for _, o := range strings.Split(v, ",") {
+			if o != "asc" && o != "desc" {
+				return nil, fmt.Errorf("order must be asc or desc")
+			}
+			order = append(order, o)
+		}
+	}
+	q.Sort = SetSorting(sortby, order)
+
+	q.Join = ParseJoin(qs.Get("join"))
+	if err = fields.CheckJoin(q.Join); err != nil {
+		return nil, err
+	}
+
+	if v := qs.Get("query"); v != "" {
+		for _, group := range splitQuery(v, '|') {
+			cond := orm.NewCondition()
+			for _, term := range splitQuery(group, ',') {
+				if cond, err = fields.addCondition(cond, term); err != nil {
+					return nil, err
+				}
+			}
+			q.Cond = q.Cond.AndCond(cond)
+		}
+	}
+	if q.WithDeleted, err = WithDeleted(qs); err != nil {
+		return nil, err
+	}
+	if err = q.parsePage(qs, fields); err != nil {
+		return nil, err
+	}
+	return q, nil
+}
+
+// WithDeleted parses the with_deleted parameter of a request, the soft-deleted records are only
+// read when it is true
+func WithDeleted(qs url.Values) (bool, error) {
+	v := qs.Get("with_deleted")
+	if v == "" {
+		return false, nil
+	}
+	withDeleted, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, fmt.Errorf("with_deleted must be a boolean")
+	}
+	return withDeleted, nil
+}
+
+// CheckJoin returns an error when a relation isn't in the whitelist
+func (f QueryFields) CheckJoin(join []string) error {
+	for _, name := range join {
+		if !contains(f.Join, name) {
+			return fmt.Errorf("can't join %s", name)
+		}
+	}
+	return nil
+}
+
+// columns parses a list of fields of the model itself
+func (f QueryFields) columns(s string) (columns []string, err error) {
+	if s == "" {
+		return nil, nil
+	}
+	for _, name := range strings.Split(s, ",") {
+		if _, ok := f.Filter[name]; !ok || strings.Contains(name, "__") {
+			return nil, fmt.Errorf("unknown field %s", name)
+		}
+		columns = append(columns, name)
+	}
+	return columns, nil
+}
+
+// addCondition parses a term of the query parameter and adds it to a condition
+func (f QueryFields) addCondition(cond *orm.Condition, term string) (*orm.Condition, error) {
+	key, value := term, "true"
+	if i := strings.Index(term, ":"); i >= 0 {
+		key, value = term[:i], term[i+1:]
+	}
+	prefix := ""
+	for _, p := range []string{"And.", "OrNot.", "Or.", "Ex."} {
+		if strings.HasPrefix(key, p) {
+			prefix, key = p, key[len(p):]
+			break
+		}
+	}
+	path := strings.Split(key, ".")
+	op := ""
+	if len(path) > 1 && queryOperators[path[len(path)-1]] {
+		op, path = path[len(path)-1], path[:len(path)-1]
+	}
+	field := strings.Join(path, "__")
+	typ, ok := f.Filter[field]
+	if !ok {
+		return nil, fmt.Errorf("can't filter by %s", field)
+	}
+
+	var args []interface{}
+	expr := field
+	switch op {
+	case "null", "notnull", "isnull":
+		isNull, err := strconv.ParseBool(unescapeQuery(value))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s is not a boolean", field, value)
+		}
+		expr += "__isnull"
+		args = append(args, isNull == (op != "notnull"))
+	case "in", "between":
+		if !strings.HasPrefix(value, "(") || !strings.HasSuffix(value, ")") {
+			return nil, fmt.Errorf("%s: the values of %s are a list, e.g. (1,2)", field, op)
+		}
+		for _, s := range splitQuery(value[1:len(value)-1], ',') {
+			v, err := queryValue(typ, unescapeQuery(s))
+			if err != nil {
+				return nil, fmt.Errorf("%s: %s", field, err)
+			}
+			args = append(args, v)
+		}
+		if op == "between" && len(args) != 2 {
+			return nil, fmt.Errorf("%s: between takes 2 values", field)
+		}
+		expr += "__" + op
+	default:
+		v, err := queryValue(typ, unescapeQuery(value))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", field, err)
+		}
+		args = append(args, v)
+		if op != "" {
+			expr += "__" + op
+		}
+	}
+
+	switch prefix {
+	case "Or.":
+		return cond.Or(expr, args...), nil
+	case "Ex.":
+		return cond.AndNot(expr, args...), nil
+	case "OrNot.":
+		return cond.OrNot(expr, args...), nil
+	}
+	return cond.And(expr, args...), nil
+}
+
+// queryValue converts a value of the query parameter to the type of its field
+func queryValue(typ, s string) (v interface{}, err error) {
+	switch typ {
+	case "int":
+		v, err = strconv.ParseInt(s, 10, 64)
+	case "uint":
+		v, err = strconv.ParseUint(s, 10, 64)
+	case "float":
+		v, err = strconv.ParseFloat(s, 64)
+	case "bool":
+		v, err = strconv.ParseBool(s)
+	case "time":
+		if v, err = time.Parse(time.RFC3339, s); err != nil {
+			v, err = time.Parse("2006-01-02", s)
+		}
+	default:
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s is not a valid %s", s, typ)
+	}
+	return v, nil
+}
+
+// splitQuery splits s at the unescaped sep outside parentheses, the escapes are kept
+func splitQuery(s string, sep byte) (parts []string) {
+	depth, start := 0, 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '(':
+			depth++
+		case ')':
+			if depth > 0 {
+				depth--
+			}
+		case sep:
+			if depth == 0 {
+				parts = append(parts, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// unescapeQuery removes the escapes of a value
+func unescapeQuery(s string) string {
+	var b []byte
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			i++
+		}
+		b = append(b, s[i])
+	}
+	return string(b)
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseJoin splits the join parameter into relation names, e.g. author.profile,tags => [author__profile tags]
+func ParseJoin(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.Replace(s, ".", "__", -1), ",")
+}
+
+// SplitJoin separates the many-to-many and reverse relations of a model, loaded one by one,
+// from the foreign keys joined in the query
+func SplitJoin(join []string, relations map[string]string) (related []string, loads []string) {
+	for _, name := range join {
+		if field, ok := relations[name]; ok {
+			loads = append(loads, field)
+		} else {
+			related = append(related, name)
+		}
+	}
+	return
+}
+
+func QueryJoin(joins []string) (field interface{}) {
+	if len(joins) > 0 {
+		return joins
+	}
+
+	return nil
+}
+
+// Set sorting for orm
+// its combine between sortby field and order case
+func SetSorting(sortby []string, order []string) (sortFields []string) {
+	if len(sortby) != 0 {
+		if len(sortby) == len(order) {
+			for i, v := range sortby {
+				orderby := ""
+				if order[i] == "desc" {
+					orderby = "-" + v
+				} else {
+					orderby = v
+				}
+				sortFields = append(sortFields, orderby)
+			}
+		} else if len(sortby) != len(order) && len(order) == 1 {
+			for _, v := range sortby {
+				orderby := ""
+				if order[0] == "desc" {
+					orderby = "-" + v
+				} else {
+					orderby = v
+				}
+
+				sortFields = append(sortFields, orderby)
+			}
+		}
+	}
+
+	return
+}
+
+// snake string, XxYy to xx_yy
+func SnakeString(s string) string {
+	data := make([]byte, 0, len(s)*2)
+	j := false
+	num := len(s)
+	for i := 0; i < num; i++ {
+		d := s[i]
+		if i > 0 && d >= 'A' && d <= 'Z' && j {
+			data = append(data, '_')
+		}
+		if d != '_' {
+			j = true
+		}
+		data = append(data, d)
+	}
+	return strings.ToLower(string(data[:len(data)]))
+}
+
+func CamelString(s string) string {
+	data := make([]byte, 0, len(s))
+	j := false
+	k := false
+	num := len(s) - 1
+	for i := 0; i <= num; i++ {
+		d := s[i]
+		if k == false && d >= 'A' && d <= 'Z' {
+			k = true
+		}
+		if d >= 'a' && d <= 'z' && (j || k == false) {
+			d = d - 32
+			j = false
+			k = true
+		}
+		if k && d == '_' && num > i && s[i+1] >= 'a' && s[i+1] <= 'z' {
+			j = true
+			continue
+		}
+		data = append(data, d)
+	}
+	return string(data[:len(data)])
+}
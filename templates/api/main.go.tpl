@@ -0,0 +1,90 @@
+package main
+
+import (
+{{- if .PasswordEnv}}
+{{- if eq .DriverName "postgres"}}
+	"net/url"
+{{- end}}
+	"os"
+{{- if ne .DriverName "postgres"}}
+	"strings"
+{{- end}}
+{{- end}}
+	"time"
+
+	_ "{{.Appname}}/docs"
+{{- if .Auth}}
+	"{{.Appname}}/filters"
+{{- end}}
+	"{{.Appname}}/helpers"
+	_ "{{.Appname}}/routers"
+
+	"github.com/aamsur/beego"
+	"github.com/aamsur/beego/orm"
+	"github.com/aamsur/beego/plugins/cors"
+	{{.DriverPkg}}
+)
+
+func init() {
+{{- if .PasswordEnv}}
+	sqlconn := beego.AppConfig.String("sqlconn")
+	// the password stays out of app.conf, it is read from the environment variable of sqlpasswordenv
+{{- if eq .DriverName "postgres"}}
+	u, err := url.Parse(sqlconn)
+	if err != nil {
+		panic(err)
+	}
+	u.User = url.UserPassword(u.User.Username(), os.Getenv(beego.AppConfig.String("sqlpasswordenv")))
+	sqlconn = u.String()
+{{- else}}
+	at := strings.LastIndex(sqlconn[:strings.LastIndex(sqlconn, "/")+1], "@")
+	sqlconn = sqlconn[:at] + ":" + os.Getenv(beego.AppConfig.String("sqlpasswordenv")) + sqlconn[at:]
+{{- end}}
+	orm.RegisterDataBase("default", "{{.DriverName}}", sqlconn)
+{{- else}}
+	orm.RegisterDataBase("default", "{{.DriverName}}", beego.AppConfig.String("sqlconn"))
+{{- end}}
+	orm.DefaultTimeLoc = time.Local
+
+	helpers.DefaultPageSize = beego.AppConfig.DefaultInt64("pagesize", helpers.DefaultPageSize)
+	helpers.MaxPageSize = beego.AppConfig.DefaultInt64("maxpagesize", helpers.MaxPageSize)
+	helpers.MaxBulkSize = beego.AppConfig.DefaultInt("maxbulksize", helpers.MaxBulkSize)
+{{- if .Auth}}
+{{- if eq .Auth "jwt"}}
+	helpers.TokenSecret = []byte(beego.AppConfig.String("tokensecret"))
+	helpers.AccessTokenTTL = time.Duration(beego.AppConfig.DefaultInt64("accesstokenttl", 900)) * time.Second
+{{- end}}
+	helpers.RefreshTokenTTL = time.Duration(beego.AppConfig.DefaultInt64("refreshtokenttl", 2592000)) * time.Second
+{{- end}}
+
+	beego.InsertFilter("*", beego.BeforeRouter, cors.Allow(&cors.Options{
+		AllowOrigins:     []string{"*"},
+		AllowMethods:     []string{"GET", "DELETE", "PUT", "PATCH", "POST"},
+		AllowHeaders:     []string{"Origin"{{if .Auth}}, "{{.AuthHeader}}"{{end}}},
+		ExposeHeaders:    []string{"Content-Length", "Link"},
+		AllowCredentials: true,
+	}))
+{{- if .Auth}}
+	if err := filters.LoadPolicies("conf/rbac.json"); err != nil {
+		panic(err)
+	}
+	beego.InsertFilter("/v1/*", beego.BeforeRouter, filters.Auth)
+	beego.InsertFilter("/v1/*", beego.BeforeRouter, filters.Authorize)
+{{- end}}
+}
+
+func main() {
+	if beego.RunMode == "dev" {
+		beego.DirectoryIndex = true
+		beego.StaticDir["/swagger"] = "swagger"
+		orm.Debug = true
+	}
+
+	if beego.RunMode == "debug" {
+		orm.Debug = true
+	}
+
+	orm.DefaultRelsDepth = 3
+
+	beego.Run()
+}
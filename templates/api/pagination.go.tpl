@@ -0,0 +1,283 @@
+package helpers
+
+import (
+	"database/sql/driver"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aamsur/beego/context"
+	"github.com/aamsur/beego/orm"
+)
+
+// DefaultPageSize is the limit of GetAll requests without one, MaxPageSize the largest limit
+// accepted. main sets them from pagesize and maxpagesize in app.conf.
+var (
+	DefaultPageSize int64 = 10
+	MaxPageSize     int64 = 100
+)
+
+// Page is the position of the results of a GetAll request, read by Query.Page
+type Page struct {
+	Totals *int64 // the number of matching records, when counted
+	next   url.Values
+	prev   url.Values
+}
+
+// cursor is the position of a keyset page: the sort and key values of the row next to the page
+type cursor struct {
+	Sort  string `json:"s"`
+	Value string `json:"v,omitempty"`
+	Key   string `json:"k"`
+	Prev  bool   `json:"p,omitempty"` // the page is before the row
+	value interface{}
+	key   interface{}
+}
+
+// parsePage parses the limit, offset, count and cursor parameters of a GetAll request
+func (q *Query) parsePage(qs url.Values, fields QueryFields) (err error) {
+	q.Limit = DefaultPageSize
+	if v := qs.Get("limit"); v != "" {
+		if q.Limit, err = strconv.ParseInt(v, 10, 64); err != nil || q.Limit < 1 {
+			return fmt.Errorf("limit must be a positive integer")
+		}
+	}
+	if q.Limit > MaxPageSize {
+		q.Limit = MaxPageSize
+	}
+	if v := qs.Get("offset"); v != "" {
+		if q.Offset, err = strconv.ParseInt(v, 10, 64); err != nil || q.Offset < 0 {
+			return fmt.Errorf("offset must be a positive integer")
+		}
+	}
+	if v := qs.Get("count"); v != "" {
+		if q.Count, err = strconv.ParseBool(v); err != nil {
+			return fmt.Errorf("count must be a boolean")
+		}
+	}
+	q.values, q.fields = qs, fields
+
+	v := qs.Get("cursor")
+	if v == "" {
+		return nil
+	}
+	if !q.keyset() {
+		return fmt.Errorf("cursor can't be used with offset, groupby, several or nullable sortby fields or fields without %s", fields.Key)
+	}
+	b, err := base64.RawURLEncoding.DecodeString(v)
+	if err != nil {
+		return fmt.Errorf("invalid cursor")
+	}
+	c := &cursor{}
+	if err = json.Unmarshal(b, c); err != nil || c.Sort != q.sortKey() {
+		return fmt.Errorf("invalid cursor")
+	}
+	if c.key, err = queryValue(fields.Filter[fields.Key], c.Key); err != nil {
+		return fmt.Errorf("invalid cursor")
+	}
+	if c.value, err = queryValue(fields.Filter[c.Sort], c.Value); err != nil {
+		return fmt.Errorf("invalid cursor")
+	}
+	q.cursor = c
+	return nil
+}
+
+// keyset reports whether the query is paged by cursors: the model has a key, the results are
+// sorted by one field at most, which is not nullable, and hold the key and sort fields
+func (q *Query) keyset() bool {
+	if q.fields.Key == "" || q.Offset > 0 || len(q.GroupBy) > 0 || len(q.Sort) > 1 || contains(q.fields.Nullable, q.sortKey()) {
+		return false
+	}
+	return len(q.Fields) == 0 || contains(q.Fields, q.fields.Key) && contains(q.Fields, q.sortKey())
+}
+
+// sortKey is the field sorting a keyset page, the key itself when no sortby is given
+func (q *Query) sortKey() string {
+	if len(q.Sort) == 0 {
+		return q.fields.Key
+	}
+	return strings.TrimPrefix(q.Sort[0], "-")
+}
+
+// Paginate applies the order and the limit of the page of the query to a query set, whose
+// condition is Where. It reads one row more than the limit, which tells Page whether there is a
+// next page.
+func (q *Query) Paginate(qs orm.QuerySeter) orm.QuerySeter {
+	if !q.keyset() {
+		return qs.OrderBy(q.Sort...).Limit(q.Limit+1, q.Offset)
+	}
+	sort, key := q.sortKey(), q.fields.Key
+	if q.descending() {
+		sort, key = "-"+sort, "-"+key
+	}
+	order := []string{sort}
+	if q.sortKey() != q.fields.Key {
+		order = append(order, key)
+	}
+	return qs.OrderBy(order...).Limit(q.Limit + 1)
+}
+
+// Where returns the condition of the page: q.Cond, and the rows after the cursor on a keyset
+// page. The predicates of the caller belong in q.Cond, SetCond replaces the filters of a query set.
+func (q *Query) Where() *orm.Condition {
+	c := q.cursor
+	if c == nil || !q.keyset() {
+		return q.Cond
+	}
+	op := "__gt"
+	if q.descending() {
+		op = "__lt"
+	}
+	after := orm.NewCondition().And(q.fields.Key+op, c.key)
+	if c.Sort != q.fields.Key {
+		after = orm.NewCondition().And(c.Sort+op, c.value).OrCond(
+			orm.NewCondition().And(c.Sort, c.value).AndCond(after))
+	}
+	return q.Cond.AndCond(after)
+}
+
+// descending reports whether a keyset page reads the rows in descending order, the rows are read
+// from the cursor backwards for the previous page and reversed by Page
+func (q *Query) descending() bool {
+	desc := strings.HasPrefix(q.firstSort(), "-")
+	if q.cursor != nil && q.cursor.Prev {
+		desc = !desc
+	}
+	return desc
+}
+
+// firstSort is the order expression of the sort key
+func (q *Query) firstSort() string {
+	if len(q.Sort) == 0 {
+		return ""
+	}
+	return q.Sort[0]
+}
+
+// Page trims the extra row read with Paginate from a pointer to the slice of results, and
+// returns the position of the next and previous pages
+func (q *Query) Page(list interface{}) *Page {
+	l := reflect.ValueOf(list).Elem()
+	more := int64(l.Len()) > q.Limit
+	if more {
+		l.Set(l.Slice(0, int(q.Limit)))
+	}
+	page := &Page{}
+
+	if !q.keyset() {
+		if more {
+			page.next = q.pageValues("offset", strconv.FormatInt(q.Offset+q.Limit, 10))
+		}
+		if q.Offset > 0 {
+			prev := q.Offset - q.Limit
+			if prev < 0 {
+				prev = 0
+			}
+			page.prev = q.pageValues("offset", strconv.FormatInt(prev, 10))
+		}
+		return page
+	}
+
+	backward := q.cursor != nil && q.cursor.Prev
+	n := l.Len()
+	if backward {
+		swap := reflect.Swapper(l.Interface())
+		for i := 0; i < n/2; i++ {
+			swap(i, n-1-i)
+		}
+	}
+	if n == 0 {
+		return page
+	}
+	if backward || more {
+		page.next = q.pageValues("cursor", q.rowCursor(l.Index(n-1), false))
+	}
+	if backward && more || !backward && q.cursor != nil {
+		page.prev = q.pageValues("cursor", q.rowCursor(l.Index(0), true))
+	}
+	return page
+}
+
+// pageValues returns the query string of the request with another offset or cursor
+func (q *Query) pageValues(name, value string) url.Values {
+	v := url.Values{}
+	for k, s := range q.values {
+		if k != "offset" && k != "cursor" {
+			v[k] = s
+		}
+	}
+	v.Set(name, value)
+	return v
+}
+
+// rowCursor encodes the position of a row
+func (q *Query) rowCursor(row reflect.Value, prev bool) string {
+	c := cursor{Sort: q.sortKey(), Key: fieldString(row, q.fields.Key), Prev: prev}
+	if c.Sort != q.fields.Key {
+		c.Value = fieldString(row, c.Sort)
+	}
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// fieldString formats the value of a field of a row as a value of the query grammar, the key of
+// a related model stands for it
+func fieldString(row reflect.Value, column string) string {
+	v := row.FieldByName(CamelString(column))
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+	if t, ok := v.Interface().(time.Time); ok {
+		return t.Format(time.RFC3339Nano)
+	}
+	if valuer, ok := v.Interface().(driver.Valuer); ok {
+		value, _ := valuer.Value()
+		if t, ok := value.(time.Time); ok {
+			return t.Format(time.RFC3339Nano)
+		}
+		if value == nil {
+			return ""
+		}
+		return fmt.Sprint(value)
+	}
+	if v.Kind() == reflect.Struct {
+		return fieldString(v, "id")
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+// WithPage sets the totals and the links of the next and previous pages of a GetAll response,
+// the links are also written in the Link header
+func (r *Response) WithPage(ctx *context.Context, page *Page) *Response {
+	if page.Totals != nil {
+		r.WithTotals(*page.Totals)
+	}
+	var header []string
+	for _, rel := range []string{"next", "prev"} {
+		values := page.next
+		if rel == "prev" {
+			values = page.prev
+		}
+		if values == nil {
+			continue
+		}
+		if r.Links == nil {
+			r.Links = make(map[string]string)
+		}
+		u := ctx.Request.URL.Path + "?" + values.Encode()
+		r.Links[rel] = u
+		header = append(header, fmt.Sprintf(`<%s>; rel="%s"`, u, rel))
+	}
+	if len(header) > 0 {
+		ctx.Output.Header("Link", strings.Join(header, ", "))
+	}
+	return r
+}
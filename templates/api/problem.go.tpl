@@ -0,0 +1,79 @@
+package helpers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/aamsur/beego"
+	"github.com/aamsur/beego/context"
+	"github.com/aamsur/beego/orm"
+)
+
+// Problem is an RFC 7807 problem document, the response of a failed request
+type Problem struct {
+	Type     string            `json:"type"`
+	Title    string            `json:"title"`
+	Status   int               `json:"status"`
+	Detail   string            `json:"detail,omitempty"`
+	Instance string            `json:"instance,omitempty"`
+	Errors   map[string]string `json:"errors,omitempty"` // messages of the invalid fields
+}
+
+// constraintErrors map the messages of the database drivers for constraint violations to the
+// details of the 409 problems, the messages themselves quote the values and the schema
+var constraintErrors = []struct{ match, detail string }{
+	{"Error 1062", "a record with the same unique fields exists"}, // mysql
+	{"Error 1451", "the record is referenced by other records"},
+	{"Error 1452", "the record references a record that doesn't exist"},
+	{"violates unique constraint", "a record with the same unique fields exists"}, // postgres
+	{"violates foreign key constraint", "the record references or is referenced by other records"},
+	{"violates check constraint", "the record violates a check constraint"},
+	{"violates exclusion constraint", "the record conflicts with another record"},
+	{"UNIQUE constraint failed", "a record with the same unique fields exists"}, // sqlite
+	{"FOREIGN KEY constraint failed", "the record references or is referenced by other records"},
+	{"CHECK constraint failed", "the record violates a check constraint"},
+}
+
+// NewProblem returns the problem of an HTTP status
+func NewProblem(status int, detail string) *Problem {
+	return &Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+	}
+}
+
+// ValidationProblem returns the 422 problem of the invalid fields given by Validator
+func ValidationProblem(errorData map[string]string) *Problem {
+	p := NewProblem(http.StatusUnprocessableEntity, "the request has invalid fields")
+	p.Errors = errorData
+	return p
+}
+
+// ErrorProblem maps an error of the orm or the database to a problem: 404 when no record is
+// found, 409 for constraint violations and 500 otherwise. The error of a 500 is logged, not
+// returned to the client.
+func ErrorProblem(err error) *Problem {
+	if err == orm.ErrNoRows {
+		return NewProblem(http.StatusNotFound, "no record found")
+	}
+	msg := err.Error()
+	for _, constraint := range constraintErrors {
+		if strings.Contains(msg, constraint.match) {
+			return NewProblem(http.StatusConflict, constraint.detail)
+		}
+	}
+	beego.Error("request failed:", msg)
+	return NewProblem(http.StatusInternalServerError, "internal server error")
+}
+
+// ServeProblem writes a problem as the response of a request
+func ServeProblem(ctx *context.Context, p *Problem) {
+	p.Instance = ctx.Request.URL.Path
+	body, _ := json.Marshal(p)
+	ctx.Output.Header("Content-Type", "application/problem+json; charset=utf-8")
+	ctx.Output.SetStatus(p.Status)
+	ctx.Output.Body(body)
+}
@@ -0,0 +1,78 @@
+package filters
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"{{.PkgPath}}/helpers"
+
+	"github.com/aamsur/beego/context"
+)
+
+// Policies are the actions granted to each role on each resource, read from conf/rbac.json, e.g.
+// {"user": {"posts": ["read", "create", "update:own", "delete:own"]}}. Resources are the route
+// namespaces, * stands for every resource or action, and the :own actions are only granted on
+// the rows owned by the user.
+var Policies map[string]map[string][]string
+
+// actions are the actions of the request methods
+var actions = map[string]string{
+	"GET":    "read",
+	"HEAD":   "read",
+	"POST":   "create",
+	"PUT":    "update",
+	"PATCH":  "update",
+	"DELETE": "delete",
+}
+
+// LoadPolicies reads the policies of a file
+func LoadPolicies(file string) error {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, &Policies)
+}
+
+// Authorize rejects with 403 the requests whose action on a resource isn't granted to the role
+// of their user. When only the :own action is granted, the request is restricted to the rows of
+// the user, see helpers.OwnerOnly.
+func Authorize(ctx *context.Context) {
+	if ctx.Request.Method == "OPTIONS" || PublicPaths[ctx.Request.URL.Path] {
+		return
+	}
+	p := helpers.CurrentPrincipal(ctx)
+	if p == nil {
+		helpers.ServeProblem(ctx, helpers.NewProblem(http.StatusUnauthorized, "missing credentials"))
+		return
+	}
+	resource := strings.SplitN(strings.TrimPrefix(ctx.Request.URL.Path, "/v1/"), "/", 2)[0]
+	all, own := granted(p.Role, resource, actions[ctx.Request.Method])
+	switch {
+	case all:
+	case own:
+		helpers.SetOwnerOnly(ctx)
+	default:
+		helpers.ServeProblem(ctx, helpers.NewProblem(http.StatusForbidden, "the role "+p.Role+" may not do this"))
+	}
+}
+
+// granted reports whether a role may do an action on every row of a resource, or on its own rows only
+func granted(role, resource, action string) (all, own bool) {
+	if action == "" {
+		return false, false
+	}
+	for _, r := range []string{resource, "*"} {
+		for _, a := range Policies[role][r] {
+			switch a {
+			case "*", action:
+				return true, false
+			case action + ":own":
+				own = true
+			}
+		}
+	}
+	return false, own
+}
@@ -0,0 +1,54 @@
+package helpers
+
+import (
+	"net/http"
+	"net/url"
+)
+
+// ReportParam is a query parameter of a report, Type is the type of its value as in
+// QueryFields: string, int, uint, float, bool or time
+type ReportParam struct {
+	Name     string
+	Type     string
+	Required bool
+}
+
+// ReportParams reads the parameters of a report from the query string of a request, a missing
+// optional parameter is nil, i.e. NULL in the query of the report. The problem lists the missing
+// and invalid parameters.
+func ReportParams(qs url.Values, params []ReportParam) (values map[string]interface{}, p *Problem) {
+	values = make(map[string]interface{}, len(params))
+	errs := make(map[string]string)
+	for _, param := range params {
+		s := qs.Get(param.Name)
+		if s == "" {
+			if param.Required {
+				errs[param.Name] = "is required"
+			}
+			values[param.Name] = nil
+			continue
+		}
+		v, err := queryValue(param.Type, s)
+		if err != nil {
+			errs[param.Name] = err.Error()
+			continue
+		}
+		values[param.Name] = v
+	}
+	if len(errs) > 0 {
+		p = NewProblem(http.StatusBadRequest, "the request has invalid parameters")
+		p.Errors = errs
+		return nil, p
+	}
+	return values, nil
+}
+
+// ReportArgs returns the arguments of the placeholders of a report query, each placeholder is
+// named by its parameter
+func ReportArgs(values map[string]interface{}, placeholders []string) []interface{} {
+	args := make([]interface{}, len(placeholders))
+	for i, name := range placeholders {
+		args[i] = values[name]
+	}
+	return args
+}
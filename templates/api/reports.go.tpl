@@ -0,0 +1,21 @@
+package controllers
+
+import (
+	"github.com/aamsur/beego"
+)
+
+// oprations for ReportsController, the endpoints of the reports created by bee generate report
+type ReportsController struct {
+	beego.Controller
+}
+
+// reportMethods are the endpoints of the reports by method name, the file of each report adds
+// its own
+var reportMethods = map[string]func(*ReportsController){}
+
+func (c *ReportsController) URLMapping() {
+	for name, method := range reportMethods {
+		method := method
+		c.Mapping(name, func() { method(c) })
+	}
+}
@@ -0,0 +1,77 @@
+package helpers
+
+import (
+	"strings"
+)
+
+// Response is the envelope of every API response. Controllers build a new one for each request
+// with NewResponse, it must not be shared between requests.
+type Response struct {
+	Success bool                   `json:"success"`
+	Id      int64                  `json:"id,omitempty"`
+	Data    interface{}            `json:"data,omitempty"`
+	Totals  *int64                 `json:"totals,omitempty"`
+	Links   map[string]string      `json:"links,omitempty"`
+	Error   interface{}            `json:"error,omitempty"`
+	Meta    map[string]interface{} `json:"meta,omitempty"`
+}
+
+// NewResponse returns an empty response for a request
+func NewResponse() *Response {
+	return &Response{}
+}
+
+// Ok marks the response successful, with the data read by the request if any
+func (r *Response) Ok(data ...interface{}) *Response {
+	r.Success = true
+	r.Error = nil
+	if len(data) > 0 {
+		r.Data = data[0]
+	}
+	return r
+}
+
+// Created marks the response successful, with the id of the record created by the request
+func (r *Response) Created(id int64) *Response {
+	r.Id = id
+	return r.Ok()
+}
+
+// WithTotals sets the number of records matching the request, beyond the ones in Data
+func (r *Response) WithTotals(totals int64) *Response {
+	r.Totals = &totals
+	return r
+}
+
+// WithMeta adds a value to the meta data of the response
+func (r *Response) WithMeta(key string, value interface{}) *Response {
+	if r.Meta == nil {
+		r.Meta = make(map[string]interface{})
+	}
+	r.Meta[key] = value
+	return r
+}
+
+// Fail marks the response failed with an error message of the orm, or the errors of the fields
+// given by Validator
+func (r *Response) Fail(errorData interface{}) *Response {
+	r.Success = false
+	r.Data = nil
+	switch e := errorData.(type) {
+	case string:
+		r.Error = map[string]string{
+			"orm": ClearErrorPrefix(e), // from orm
+		}
+	default:
+		r.Error = errorData // from validator
+	}
+	return r
+}
+
+// Beego "No row" SQL error has "<QuerySetter>" prefix which is really annoying
+// remove it with this function
+func ClearErrorPrefix(s string) string {
+	strToRemove := "<QuerySeter> "
+	s = strings.TrimPrefix(s, strToRemove)
+	return s
+}
@@ -0,0 +1,197 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	{{.StrconvPkg}}
+
+	"{{.PkgPath}}/models"
+	"{{.PkgPath}}/helpers"
+	"github.com/aamsur/beego"
+)
+
+// oprations for {{.CtrlName}}
+type {{.CtrlName}}Controller struct {
+	beego.Controller
+}
+
+func (c *{{.CtrlName}}Controller) URLMapping() {
+	c.Mapping("Post", c.Post)
+	c.Mapping("GetOne", c.GetOne)
+	c.Mapping("GetAll", c.GetAll)
+	c.Mapping("Put", c.Put)
+	c.Mapping("Delete", c.Delete)
+}
+
+// @Title Post
+// @Description create {{.CtrlName}}
+// @Param	body		body 	models.{{.CtrlName}}	true		"body for {{.CtrlName}} content"
+// @Success 201 {object} models.{{.CtrlName}}
+// @Failure 400 body is not valid json
+// @Failure 409 constraint violation
+// @Failure 422 invalid fields
+{{.AuthDoc}}// @router / [post]
+func (c *{{.CtrlName}}Controller) Post() {
+	var v models.{{.CtrlName}}
+	if err := json.Unmarshal(c.Ctx.Input.RequestBody, &v); err != nil {
+		helpers.ServeProblem(c.Ctx, helpers.NewProblem(http.StatusBadRequest, err.Error()))
+		return
+	}
+{{- if or .CreatedBy .UpdatedBy}}
+	v.AuditCreate(helpers.CurrentPrincipal(c.Ctx))
+{{- end}}
+
+	// validate the model
+	if ok, errData := helpers.Validator(&v); !ok {
+		helpers.ServeProblem(c.Ctx, helpers.ValidationProblem(errData))
+		return
+	}
+	if _, err := models.Add{{.CtrlName}}(&v); err != nil {
+		helpers.ServeProblem(c.Ctx, helpers.ErrorProblem(err))
+		return
+	}
+
+	c.Ctx.Output.SetStatus(http.StatusCreated)
+	c.Data["json"] = helpers.NewResponse().Ok()
+	c.ServeJson()
+}
+
+// @Title Get
+// @Description get {{.CtrlName}} by key
+{{.KeyDocParams}}
+{{- if .SoftDelete}}// @Param	with_deleted	query	string	false	"Read the record even if soft-deleted. Must be a boolean"
+{{end}}// @Success 200 {object} models.{{.CtrlName}}
+// @Failure 404 not found
+{{.AuthDoc}}// @router {{.KeyRoute}} [get]
+func (c *{{.CtrlName}}Controller) GetOne() {
+	{{.KeyParse}}
+{{- if .SoftDelete}}
+	withDeleted, err := helpers.WithDeleted(c.Input())
+	if err != nil {
+		helpers.ServeProblem(c.Ctx, helpers.NewProblem(http.StatusBadRequest, err.Error()))
+		return
+	}
+	get := models.Get{{.CtrlName}}ByPk
+	if withDeleted {
+		get = models.Get{{.CtrlName}}ByPkWithDeleted
+	}
+	v, err := get({{.KeyArgs}})
+{{- else}}
+	v, err := models.Get{{.CtrlName}}ByPk({{.KeyArgs}})
+{{- end}}
+	if err != nil {
+		helpers.ServeProblem(c.Ctx, helpers.ErrorProblem(err))
+		return
+	}
+	c.Data["json"] = helpers.NewResponse().Ok(v)
+	c.ServeJson()
+}
+
+// @Title Get All
+// @Description get {{.CtrlName}}
+// @Param	query	query	string	false	"Filter. e.g. title.icontains:bee,Or.score.gt:4.5|id.in:(1,2,3) ..."
+// @Param	fields	query	string	false	"Fields returned. e.g. col1,col2 ..."
+// @Param	groupby	query	string	false	"Group-by fields. e.g. col1,col2 ..."
+// @Param	sortby	query	string	false	"Sorted-by fields. e.g. col1,col2 ..."
+// @Param	order	query	string	false	"Order corresponding to each sortby field, if single value, apply to all sortby fields. e.g. desc,asc ..."
+// @Param	limit	query	string	false	"Limit the size of result set, at most maxpagesize. Must be an integer"
+// @Param	offset	query	string	false	"Start position of result set. Must be an integer"
+// @Param	count	query	string	false	"Count the matching records in totals. Must be a boolean"
+// @Param	join	query	string	false	"Relations loaded with the results. e.g. rel1,rel2 ..."
+{{- if .SoftDelete}}
+// @Param	with_deleted	query	string	false	"Read the soft-deleted records too. Must be a boolean"
+{{- end}}
+// @Param	format	query	string	false	"json, csv or xlsx, also chosen by the Accept header. Files hold every matching record"
+// @Success 200 {object} models.{{.CtrlName}}
+// @Failure 400 invalid query
+// @Failure 500
+{{.AuthDoc}}// @router / [get]
+func (c *{{.CtrlName}}Controller) GetAll() {
+	q, err := helpers.ParseQuery(c.Input(), models.{{.ModelName}}Query)
+	if err != nil {
+		helpers.ServeProblem(c.Ctx, helpers.NewProblem(http.StatusBadRequest, err.Error()))
+		return
+	}
+
+	// files are streamed from the database rather than loaded
+	format, err := helpers.ExportFormat(c.Ctx)
+	if err != nil {
+		helpers.ServeProblem(c.Ctx, helpers.NewProblem(http.StatusBadRequest, err.Error()))
+		return
+	}
+	if format != "" {
+		export := helpers.NewExport(c.Ctx, format, "{{.TableName}}")
+		if err := models.Export{{.CtrlName}}(q, export); err != nil {
+			export.Fail(err)
+		}
+		return
+	}
+
+	// Get all with query string
+	l, err, page := models.GetAll{{.CtrlName}}(q)
+	if err != nil {
+		helpers.ServeProblem(c.Ctx, helpers.ErrorProblem(err))
+		return
+	}
+	if l == nil {
+		// no row found
+		l = []interface{}{}
+	}
+	c.Data["json"] = helpers.NewResponse().Ok(l).WithPage(c.Ctx, page)
+	c.ServeJson()
+}
+
+// @Title Update
+// @Description update the {{.CtrlName}}
+{{.KeyDocParams}}// @Param	body	body 	models.{{.CtrlName}}	true		"body for {{.CtrlName}} content"
+// @Success 200 {object} models.{{.CtrlName}}
+// @Failure 400 body is not valid json
+// @Failure 404 not found
+// @Failure 409 constraint violation
+// @Failure 422 invalid fields
+{{.AuthDoc}}// @router {{.KeyRoute}} [put]
+func (c *{{.CtrlName}}Controller) Put() {
+	{{.KeyParse}}
+	var v models.{{.CtrlName}}
+
+	// bind input into model struct, the key always comes from the path
+	if err := json.Unmarshal(c.Ctx.Input.RequestBody, &v); err != nil {
+		helpers.ServeProblem(c.Ctx, helpers.NewProblem(http.StatusBadRequest, err.Error()))
+		return
+	}
+	{{.KeyAssign}}
+
+	// get input keys
+	keys := helpers.GetInputKeys(c.Ctx.Input.RequestBody)
+{{- if or .CreatedBy .UpdatedBy}}
+	keys = v.AuditUpdate(helpers.CurrentPrincipal(c.Ctx), keys)
+{{- end}}
+
+	// validate the fields being updated
+	if ok, errData := helpers.Validator(&v, keys...); !ok {
+		helpers.ServeProblem(c.Ctx, helpers.ValidationProblem(errData))
+		return
+	}
+	if err := models.Update{{.CtrlName}}ByPk(&v, keys); err != nil {
+		helpers.ServeProblem(c.Ctx, helpers.ErrorProblem(err))
+		return
+	}
+	c.Data["json"] = helpers.NewResponse().Ok()
+	c.ServeJson()
+}
+
+// @Title Delete
+// @Description delete the {{.CtrlName}}
+{{.KeyDocParams}}// @Success 200 {string} delete success!
+// @Failure 404 not found
+// @Failure 409 the record is still referenced
+{{.AuthDoc}}// @router {{.KeyRoute}} [delete]
+func (c *{{.CtrlName}}Controller) Delete() {
+	{{.KeyParse}}
+	if err := models.Delete{{.CtrlName}}ByPk({{.KeyArgs}}); err != nil {
+		helpers.ServeProblem(c.Ctx, helpers.ErrorProblem(err))
+		return
+	}
+	c.Data["json"] = helpers.NewResponse().Ok()
+	c.ServeJson()
+}
@@ -0,0 +1,200 @@
+package models
+
+import (
+	"reflect"
+	{{.TimePkg}}
+
+	"github.com/aamsur/beego/orm"
+	"{{.PkgPath}}/helpers"
+)
+
+{{.ModelStruct}}
+
+func (t *{{.ModelName}}) TableName() string {
+	return "{{.TableName}}"
+}
+{{- if or .CreatedBy .UpdatedBy}}
+
+// AuditCreate sets the audit columns of a record created by the user of a request
+func (t *{{.ModelName}}) AuditCreate(p *helpers.Principal) {
+	if p == nil {
+		return
+	}
+{{.CreatedBy}}{{.UpdatedBy}}}
+
+// AuditUpdate sets the audit columns of a record updated by the user of a request, and returns
+// the keys of the update: the audit columns of the body are left out, updated_by is added
+func (t *{{.ModelName}}) AuditUpdate(p *helpers.Principal, keys []string) []string {
+	var audited []string
+	for _, k := range keys {
+		if k != "created_by" && k != "updated_by" {
+			audited = append(audited, k)
+		}
+	}
+{{- if .UpdatedBy}}
+	if p != nil {
+{{.UpdatedBy}}		audited = append(audited, "{{.UpdatedByColumn}}")
+	}
+{{- end}}
+	return audited
+}
+{{- end}}
+
+func init() {
+	orm.RegisterModel(new({{.ModelName}}))
+}
+
+// {{.ModelName}}Query is the whitelist of the fields GetAll{{.ModelName}} filters, sorts and joins by
+var {{.ModelName}}Query = helpers.QueryFields{
+	Filter: map[string]string{ {{.QueryFilter}} },
+	Sort:   []string{ {{.QuerySort}} },
+	Join:   []string{ {{.QueryJoin}} },
+}
+
+// Add{{.ModelName}} insert a new {{.ModelName}} into database
+func Add{{.ModelName}}(m *{{.ModelName}}) (id int64, err error) {
+	o := orm.NewOrm()
+	id, err = o.Insert(m)
+	return
+}
+
+// Get{{.ModelName}}ByPk retrieves {{.ModelName}} by {{.KeyNames}}. Returns error if
+// the key doesn't exist{{if .SoftDelete}} or the record is soft-deleted{{end}}
+func Get{{.ModelName}}ByPk({{.KeyParams}}) (v *{{.ModelName}}, err error) {
+	var m {{.ModelName}}
+	o := orm.NewOrm()
+
+	if err = o.QueryTable(new({{.ModelName}})){{.KeyFilters}}{{.LiveFilter}}.RelatedSel().One(&m); err == nil {
+		return &m, nil
+	}
+
+	return nil, err
+}
+{{- if .SoftDelete}}
+
+// Get{{.ModelName}}ByPkWithDeleted retrieves {{.ModelName}} by {{.KeyNames}}, soft-deleted or not
+func Get{{.ModelName}}ByPkWithDeleted({{.KeyParams}}) (v *{{.ModelName}}, err error) {
+	var m {{.ModelName}}
+	o := orm.NewOrm()
+
+	if err = o.QueryTable(new({{.ModelName}})){{.KeyFilters}}.RelatedSel().One(&m); err == nil {
+		return &m, nil
+	}
+
+	return nil, err
+}
+{{- end}}
+
+// GetAll{{.ModelName}} retrieves all {{.ModelName}} matches certain condition. Returns empty list if
+// no records exist
+func GetAll{{.ModelName}}(q *helpers.Query) (ml []interface{}, err error, page *helpers.Page) {
+
+	o := orm.NewOrm()
+{{- if .SoftDelete}}
+	if !q.WithDeleted {
+		q.Cond = q.Cond{{.LiveCond}}
+	}
+{{- end}}
+	qs := o.QueryTable(new({{.ModelName}})).SetCond(q.Cond).RelatedSel(helpers.QueryJoin(q.Join)).GroupBy(q.GroupBy...)
+
+	// count the current query only when asked, it is expensive on big tables
+	var totals *int64
+	if q.Count {
+		cnt, err := qs.Count()
+		if err != nil {
+			return nil, err, nil
+		}
+		totals = &cnt
+	}
+
+	var l []{{.ModelName}}
+	if _, err := q.Paginate(qs).All(&l, q.Fields...); err == nil {
+		page = q.Page(&l)
+		page.Totals = totals
+		if len(q.Fields) == 0 {
+			for _, v := range l {
+				ml = append(ml, v)
+			}
+		} else {
+			// trim unused fields
+			for _, v := range l {
+				m := make(map[string]interface{})
+				val := reflect.ValueOf(v)
+				for _, fname := range q.Fields {
+					m[fname] = val.FieldByName(helpers.CamelString(fname)).Interface()
+				}
+				ml = append(ml, m)
+			}
+		}
+
+		return ml, nil, page
+	}
+	return nil, err, nil
+}
+
+// Export{{.ModelName}} writes the {{.ModelName}} matching a query to an export, reading
+// helpers.ExportChunk records at a time. The query isn't paged.
+func Export{{.ModelName}}(q *helpers.Query, export *helpers.Export) error {
+	o := orm.NewOrm()
+{{- if .SoftDelete}}
+	if !q.WithDeleted {
+		q.Cond = q.Cond{{.LiveCond}}
+	}
+{{- end}}
+	qs := o.QueryTable(new({{.ModelName}})).SetCond(q.Cond)
+	if err := export.Header(new({{.ModelName}}), q.Fields); err != nil {
+		return err
+	}
+	err := q.Chunks(qs, func(qs orm.QuerySeter) (int64, error) {
+		var l []{{.ModelName}}
+		n, err := qs.All(&l, q.Fields...)
+		for i := 0; i < len(l) && err == nil; i++ {
+			err = export.Record(&l[i])
+		}
+		return n, err
+	})
+	if err != nil {
+		return err
+	}
+	return export.Close()
+}
+
+// Update{{.ModelName}}ByPk updates {{.ModelName}} by {{.KeyNames}} and returns error if
+// the record to be updated doesn't exist
+func Update{{.ModelName}}ByPk(m *{{.ModelName}}, keys []string) (err error) {
+	o := orm.NewOrm()
+	qs := o.QueryTable(new({{.ModelName}})){{.KeyFiltersM}}{{.LiveFilter}}
+	// ascertain the key exists in the database
+	if !qs.Exist() {
+		return orm.ErrNoRows
+	}
+	// update only the keys provided, the primary key itself is not updated
+	pk := map[string]bool{ {{.KeyColumnSet}} }
+	params := orm.Params{}
+	val := reflect.ValueOf(m).Elem()
+	for _, k := range keys {
+		if f := val.FieldByName(helpers.CamelString(k)); f.IsValid() && !pk[k] {
+			params[k] = f.Interface()
+		}
+	}
+	if len(params) > 0 {
+		_, err = qs.Update(params)
+	}
+	return
+}
+
+// Delete{{.ModelName}}ByPk deletes {{.ModelName}} by {{.KeyNames}} and returns error if
+// the record to be deleted doesn't exist
+{{- if .SoftDelete}}. The record is kept, marked by {{.DeletedColumn}}.{{end}}
+func Delete{{.ModelName}}ByPk({{.KeyParams}}) (err error) {
+	o := orm.NewOrm()
+{{- if .SoftDelete}}
+	num, err := o.QueryTable(new({{.ModelName}})){{.KeyFilters}}{{.LiveFilter}}.Update(orm.Params{"{{.DeletedColumn}}": {{.DeletedValue}}})
+{{- else}}
+	num, err := o.QueryTable(new({{.ModelName}})){{.KeyFilters}}.Delete()
+{{- end}}
+	if err == nil && num == 0 {
+		err = orm.ErrNoRows
+	}
+	return
+}
@@ -0,0 +1,507 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"{{.PkgPath}}/models"
+	"{{.PkgPath}}/helpers"
+	"github.com/aamsur/beego"
+)
+
+// oprations for {{.CtrlName}}
+type {{.CtrlName}}Controller struct {
+	beego.Controller
+}
+
+func (c *{{.CtrlName}}Controller) URLMapping() {
+	c.Mapping("Post", c.Post)
+	c.Mapping("GetOne", c.GetOne)
+	c.Mapping("GetAll", c.GetAll)
+	c.Mapping("Put", c.Put)
+	c.Mapping("Delete", c.Delete)
+	c.Mapping("PostBulk", c.PostBulk)
+	c.Mapping("PutBulk", c.PutBulk)
+	c.Mapping("DeleteBulk", c.DeleteBulk)
+	c.Mapping("Import", c.Import)
+}
+
+// @Title Post
+// @Description create {{.CtrlName}}
+// @Param	body		body 	models.{{.CtrlName}}	true		"body for {{.CtrlName}} content"
+// @Success 201 {int} models.{{.CtrlName}}.Id
+// @Failure 400 body is not valid json
+// @Failure 409 constraint violation
+// @Failure 422 invalid fields
+{{.AuthDoc}}// @router / [post]
+func (c *{{.CtrlName}}Controller) Post() {
+	var v models.{{.CtrlName}}
+	if err := json.Unmarshal(c.Ctx.Input.RequestBody, &v); err != nil {
+		helpers.ServeProblem(c.Ctx, helpers.NewProblem(http.StatusBadRequest, err.Error()))
+		return
+	}
+{{- if .OwnerField}}
+	// the rows created by a user allowed to write its own rows only are its own
+	if userId, ok := helpers.OwnerOnly(c.Ctx); ok {
+		v.SetRowOwner(userId)
+	}
+{{- end}}
+{{- if or .CreatedBy .UpdatedBy}}
+	v.AuditCreate(helpers.CurrentPrincipal(c.Ctx))
+{{- end}}
+
+	// validate the model
+	if ok, errData := helpers.Validator(&v); !ok {
+		helpers.ServeProblem(c.Ctx, helpers.ValidationProblem(errData))
+		return
+	}
+	id, err := models.Add{{.CtrlName}}(&v)
+	if err != nil {
+		helpers.ServeProblem(c.Ctx, helpers.ErrorProblem(err))
+		return
+	}
+
+	c.Ctx.Output.SetStatus(http.StatusCreated)
+	c.Data["json"] = helpers.NewResponse().Created(id)
+	c.ServeJson()
+}
+
+// @Title Get
+// @Description get {{.CtrlName}} by id
+// @Param	id		path 	string	true		"The key for staticblock"
+// @Param	join	query	string	false	"Relations loaded with the record. e.g. rel1,rel2 ..."
+{{- if .SoftDelete}}
+// @Param	with_deleted	query	string	false	"Read the record even if soft-deleted. Must be a boolean"
+{{- end}}
+// @Success 200 {object} models.{{.CtrlName}}
+// @Failure 400 :id is not int or join is invalid
+// @Failure 404 not found
+{{.AuthDoc}}// @router /:id [get]
+func (c *{{.CtrlName}}Controller) GetOne() {
+	id, err := strconv.Atoi(c.Ctx.Input.Params[":id"])
+	if err != nil {
+		helpers.ServeProblem(c.Ctx, helpers.NewProblem(http.StatusBadRequest, "id must be an integer"))
+		return
+	}
+	join := helpers.ParseJoin(c.GetString("join"))
+	if err := models.{{.ModelName}}Query.CheckJoin(join); err != nil {
+		helpers.ServeProblem(c.Ctx, helpers.NewProblem(http.StatusBadRequest, err.Error()))
+		return
+	}
+{{- if .SoftDelete}}
+	withDeleted, err := helpers.WithDeleted(c.Input())
+	if err != nil {
+		helpers.ServeProblem(c.Ctx, helpers.NewProblem(http.StatusBadRequest, err.Error()))
+		return
+	}
+	get := models.Get{{.CtrlName}}ById
+	if withDeleted {
+		get = models.Get{{.CtrlName}}ByIdWithDeleted
+	}
+	v, err := get(id, join...)
+{{- else}}
+	v, err := models.Get{{.CtrlName}}ById(id, join...)
+{{- end}}
+	if err != nil {
+		helpers.ServeProblem(c.Ctx, helpers.ErrorProblem(err))
+		return
+	}
+{{- if .OwnerField}}
+	if !helpers.IsOwner(c.Ctx, v.RowOwner()) {
+		helpers.ServeProblem(c.Ctx, helpers.NewProblem(http.StatusForbidden, "the record belongs to another user"))
+		return
+	}
+{{- end}}
+	c.Data["json"] = helpers.NewResponse().Ok(v)
+	c.ServeJson()
+}
+
+// @Title Get All
+// @Description get {{.CtrlName}}
+// @Param	query	query	string	false	"Filter. e.g. title.icontains:bee,Or.score.gt:4.5|id.in:(1,2,3) ..."
+// @Param	fields	query	string	false	"Fields returned. e.g. col1,col2 ..."
+// @Param	groupby	query	string	false	"Group-by fields. e.g. col1,col2 ..."
+// @Param	sortby	query	string	false	"Sorted-by fields. e.g. col1,col2 ..."
+// @Param	order	query	string	false	"Order corresponding to each sortby field, if single value, apply to all sortby fields. e.g. desc,asc ..."
+// @Param	limit	query	string	false	"Limit the size of result set, at most maxpagesize. Must be an integer"
+// @Param	offset	query	string	false	"Start position of result set. Must be an integer"
+// @Param	cursor	query	string	false	"Position of a keyset page, from the next and prev links"
+// @Param	count	query	string	false	"Count the matching records in totals. Must be a boolean"
+// @Param	join	query	string	false	"Relations loaded with the results. e.g. rel1,rel2 ..."
+{{- if .SoftDelete}}
+// @Param	with_deleted	query	string	false	"Read the soft-deleted records too. Must be a boolean"
+{{- end}}
+// @Param	format	query	string	false	"json, csv or xlsx, also chosen by the Accept header. Files hold every matching record"
+// @Success 200 {object} models.{{.CtrlName}}
+// @Failure 400 invalid query
+// @Failure 500
+{{.AuthDoc}}// @router / [get]
+func (c *{{.CtrlName}}Controller) GetAll() {
+	q, err := helpers.ParseQuery(c.Input(), models.{{.ModelName}}Query)
+	if err != nil {
+		helpers.ServeProblem(c.Ctx, helpers.NewProblem(http.StatusBadRequest, err.Error()))
+		return
+	}
+{{- if .OwnerField}}
+	if userId, ok := helpers.OwnerOnly(c.Ctx); ok {
+		q.Cond = q.Cond.And("{{.OwnerColumn}}", userId)
+	}
+{{- end}}
+
+	// files are streamed from the database rather than loaded
+	format, err := helpers.ExportFormat(c.Ctx)
+	if err != nil {
+		helpers.ServeProblem(c.Ctx, helpers.NewProblem(http.StatusBadRequest, err.Error()))
+		return
+	}
+	if format != "" {
+		export := helpers.NewExport(c.Ctx, format, "{{.TableName}}")
+		if err := models.Export{{.CtrlName}}(q, export); err != nil {
+			export.Fail(err)
+		}
+		return
+	}
+
+	// Get all with query string
+	l, err, page := models.GetAll{{.CtrlName}}(q)
+	if err != nil {
+		helpers.ServeProblem(c.Ctx, helpers.ErrorProblem(err))
+		return
+	}
+	if l == nil {
+		// no row found
+		l = []interface{}{}
+	}
+	c.Data["json"] = helpers.NewResponse().Ok(l).WithPage(c.Ctx, page)
+	c.ServeJson()
+}
+
+// @Title Update
+// @Description update the {{.CtrlName}}
+// @Param	id		path 	string				true		"The id you want to update"
+// @Param	body	body 	models.{{.CtrlName}}	true		"body for {{.CtrlName}} content"
+// @Success 200 {object} models.{{.CtrlName}}
+// @Failure 400 :id is not int or body is not valid json
+// @Failure 404 not found
+// @Failure 409 constraint violation
+// @Failure 422 invalid fields
+{{.AuthDoc}}// @router /:id [put]
+func (c *{{.CtrlName}}Controller) Put() {
+	id, err := strconv.Atoi(c.Ctx.Input.Params[":id"])
+	if err != nil {
+		helpers.ServeProblem(c.Ctx, helpers.NewProblem(http.StatusBadRequest, "id must be an integer"))
+		return
+	}
+{{- if .OwnerField}}
+	if !c.owns(id) {
+		return
+	}
+{{- end}}
+
+	// bind input into model struct, the id always comes from the path
+	var v models.{{.CtrlName}}
+	if err := json.Unmarshal(c.Ctx.Input.RequestBody, &v); err != nil {
+		helpers.ServeProblem(c.Ctx, helpers.NewProblem(http.StatusBadRequest, err.Error()))
+		return
+	}
+	v.Id = id
+{{- if .OwnerField}}
+	// a user writing its own rows only can't give them away
+	if userId, ok := helpers.OwnerOnly(c.Ctx); ok {
+		v.SetRowOwner(userId)
+	}
+{{- end}}
+
+	// get input keys
+	keys := helpers.GetInputKeys(c.Ctx.Input.RequestBody)
+{{- if or .CreatedBy .UpdatedBy}}
+	keys = v.AuditUpdate(helpers.CurrentPrincipal(c.Ctx), keys)
+{{- end}}
+
+	// validate the fields being updated
+	if ok, errData := helpers.Validator(&v, keys...); !ok {
+		helpers.ServeProblem(c.Ctx, helpers.ValidationProblem(errData))
+		return
+	}
+	if err := models.Update{{.CtrlName}}ById(&v, keys); err != nil {
+		helpers.ServeProblem(c.Ctx, helpers.ErrorProblem(err))
+		return
+	}
+	c.Data["json"] = helpers.NewResponse().Ok()
+	c.ServeJson()
+}
+
+// @Title Delete
+// @Description delete the {{.CtrlName}}
+// @Param	id		path 	string	true		"The id you want to delete"
+// @Success 200 {string} delete success!
+// @Failure 400 :id is not int
+// @Failure 404 not found
+// @Failure 409 the record is still referenced
+{{.AuthDoc}}// @router /:id [delete]
+func (c *{{.CtrlName}}Controller) Delete() {
+	id, err := strconv.Atoi(c.Ctx.Input.Params[":id"])
+	if err != nil {
+		helpers.ServeProblem(c.Ctx, helpers.NewProblem(http.StatusBadRequest, "id must be an integer"))
+		return
+	}
+{{- if .OwnerField}}
+	if !c.owns(id) {
+		return
+	}
+{{- end}}
+	if err := models.Delete{{.CtrlName}}(id); err != nil {
+		helpers.ServeProblem(c.Ctx, helpers.ErrorProblem(err))
+		return
+	}
+	c.Data["json"] = helpers.NewResponse().Ok()
+	c.ServeJson()
+}
+
+// @Title Post Bulk
+// @Description create a list of {{.CtrlName}}, in one transaction unless partial
+// @Param	body		body 	models.{{.CtrlName}}	true		"list of {{.CtrlName}} content"
+// @Param	partial	query	string	false	"Write the valid items even if others fail. Must be a boolean"
+// @Success 201 {object} helpers.BulkResult
+// @Failure 207 partial request, some items failed
+// @Failure 400 body is not a valid json list
+// @Failure 409 constraint violation, nothing written
+// @Failure 422 invalid items, nothing written
+{{.AuthDoc}}// @router /bulk [post]
+func (c *{{.CtrlName}}Controller) PostBulk() {
+	var l []models.{{.CtrlName}}
+	if err := json.Unmarshal(c.Ctx.Input.RequestBody, &l); err != nil {
+		helpers.ServeProblem(c.Ctx, helpers.NewProblem(http.StatusBadRequest, err.Error()))
+		return
+	}
+	bulk, err := helpers.NewBulk(c.Input(), len(l))
+	if err != nil {
+		helpers.ServeProblem(c.Ctx, helpers.NewProblem(http.StatusBadRequest, err.Error()))
+		return
+	}
+	c.addBulk(l, bulk)
+}
+
+// @Title Import
+// @Description create {{.CtrlName}} from the rows of a CSV body, in one transaction unless partial
+// @Param	body	body 	string	true		"CSV with a header row naming the fields of {{.CtrlName}}, empty cells are null"
+// @Param	partial	query	string	false	"Write the valid rows even if others fail. Must be a boolean"
+// @Success 201 {object} helpers.BulkResult
+// @Failure 207 partial request, some rows failed
+// @Failure 400 body is not a valid CSV or has unknown columns
+// @Failure 409 constraint violation, nothing written
+// @Failure 422 invalid rows, nothing written
+{{.AuthDoc}}// @router /import [post]
+func (c *{{.CtrlName}}Controller) Import() {
+	var l []models.{{.CtrlName}}
+	bulk, err := helpers.ImportCSV(c.Ctx.Input.RequestBody, c.Input(), &l)
+	if err != nil {
+		helpers.ServeProblem(c.Ctx, helpers.NewProblem(http.StatusBadRequest, err.Error()))
+		return
+	}
+	c.addBulk(l, bulk)
+}
+
+// addBulk validates and creates the records of a bulk request, then serves the results
+func (c *{{.CtrlName}}Controller) addBulk(l []models.{{.CtrlName}}, bulk *helpers.Bulk) {
+	// validate each item, only the valid ones are written
+	var valid []models.{{.CtrlName}}
+	var index []int
+	for i := range l {
+		if bulk.Failed(i) {
+			continue
+		}
+		v := &l[i]
+{{- if .OwnerField}}
+		if userId, ok := helpers.OwnerOnly(c.Ctx); ok {
+			v.SetRowOwner(userId)
+		}
+{{- end}}
+{{- if or .CreatedBy .UpdatedBy}}
+		v.AuditCreate(helpers.CurrentPrincipal(c.Ctx))
+{{- end}}
+		if ok, errData := helpers.Validator(v); !ok {
+			bulk.Fail(i, helpers.ValidationProblem(errData))
+			continue
+		}
+		valid = append(valid, *v)
+		index = append(index, i)
+	}
+	if bulk.Proceed() {
+		errs, err := models.AddMulti{{.CtrlName}}(valid, bulk.Partial)
+		if err != nil {
+			helpers.ServeProblem(c.Ctx, helpers.ErrorProblem(err))
+			return
+		}
+		bulk.Done(index, errs, http.StatusCreated)
+	}
+
+	status, r := bulk.Response(http.StatusCreated)
+	c.Ctx.Output.SetStatus(status)
+	c.Data["json"] = r
+	c.ServeJson()
+}
+
+// @Title Update Bulk
+// @Description update a list of {{.CtrlName}} by their id, in one transaction unless partial
+// @Param	body	body 	models.{{.CtrlName}}	true		"list of {{.CtrlName}} content with their id"
+// @Param	partial	query	string	false	"Write the valid items even if others fail. Must be a boolean"
+// @Success 200 {object} helpers.BulkResult
+// @Failure 207 partial request, some items failed
+// @Failure 400 body is not a valid json list
+// @Failure 404 an item is not found, nothing written
+// @Failure 409 constraint violation, nothing written
+// @Failure 422 invalid items, nothing written
+{{.AuthDoc}}// @router /bulk [put]
+func (c *{{.CtrlName}}Controller) PutBulk() {
+	var items []json.RawMessage
+	if err := json.Unmarshal(c.Ctx.Input.RequestBody, &items); err != nil {
+		helpers.ServeProblem(c.Ctx, helpers.NewProblem(http.StatusBadRequest, err.Error()))
+		return
+	}
+	bulk, err := helpers.NewBulk(c.Input(), len(items))
+	if err != nil {
+		helpers.ServeProblem(c.Ctx, helpers.NewProblem(http.StatusBadRequest, err.Error()))
+		return
+	}
+
+	// bind and validate each item, only the fields it holds are updated
+	var l []models.{{.CtrlName}}
+	var keys [][]string
+	var index []int
+	for i, item := range items {
+		var v models.{{.CtrlName}}
+		if err := json.Unmarshal(item, &v); err != nil {
+			bulk.Fail(i, helpers.NewProblem(http.StatusBadRequest, err.Error()))
+			continue
+		}
+		if v.Id == 0 {
+			bulk.Fail(i, helpers.NewProblem(http.StatusBadRequest, "id is required"))
+			continue
+		}
+{{- if .OwnerField}}
+		if p := c.ownerProblem(v.Id); p != nil {
+			bulk.Fail(i, p)
+			continue
+		}
+		if userId, ok := helpers.OwnerOnly(c.Ctx); ok {
+			v.SetRowOwner(userId)
+		}
+{{- end}}
+		k := helpers.GetInputKeys(item)
+{{- if or .CreatedBy .UpdatedBy}}
+		k = v.AuditUpdate(helpers.CurrentPrincipal(c.Ctx), k)
+{{- end}}
+		if ok, errData := helpers.Validator(&v, k...); !ok {
+			bulk.Fail(i, helpers.ValidationProblem(errData))
+			continue
+		}
+		l = append(l, v)
+		keys = append(keys, k)
+		index = append(index, i)
+	}
+	if bulk.Proceed() {
+		errs, err := models.UpdateMulti{{.CtrlName}}ById(l, keys, bulk.Partial)
+		if err != nil {
+			helpers.ServeProblem(c.Ctx, helpers.ErrorProblem(err))
+			return
+		}
+		bulk.Done(index, errs, http.StatusOK)
+	}
+
+	status, r := bulk.Response(http.StatusOK)
+	c.Ctx.Output.SetStatus(status)
+	c.Data["json"] = r
+	c.ServeJson()
+}
+
+// @Title Delete Bulk
+// @Description delete a list of {{.CtrlName}} by their id, in one transaction unless partial
+// @Param	body	body 	[]int	true		"list of ids"
+// @Param	partial	query	string	false	"Delete the other items even if some fail. Must be a boolean"
+// @Success 200 {object} helpers.BulkResult
+// @Failure 207 partial request, some items failed
+// @Failure 400 body is not a valid json list of ids
+// @Failure 404 an item is not found, nothing deleted
+// @Failure 409 a record is still referenced, nothing deleted
+{{.AuthDoc}}// @router /bulk [delete]
+func (c *{{.CtrlName}}Controller) DeleteBulk() {
+	var ids []int
+	if err := json.Unmarshal(c.Ctx.Input.RequestBody, &ids); err != nil {
+		helpers.ServeProblem(c.Ctx, helpers.NewProblem(http.StatusBadRequest, err.Error()))
+		return
+	}
+	bulk, err := helpers.NewBulk(c.Input(), len(ids))
+	if err != nil {
+		helpers.ServeProblem(c.Ctx, helpers.NewProblem(http.StatusBadRequest, err.Error()))
+		return
+	}
+{{- if .OwnerField}}
+
+	// only the records of the user are deleted by a user allowed to delete its own rows only
+	var owned []int
+	var index []int
+	for i, id := range ids {
+		if p := c.ownerProblem(id); p != nil {
+			bulk.Fail(i, p)
+			continue
+		}
+		owned = append(owned, id)
+		index = append(index, i)
+	}
+	if bulk.Proceed() {
+		errs, err := models.DeleteMulti{{.CtrlName}}(owned, bulk.Partial)
+		if err != nil {
+			helpers.ServeProblem(c.Ctx, helpers.ErrorProblem(err))
+			return
+		}
+		bulk.Done(index, errs, http.StatusOK)
+	}
+{{- else}}
+	errs, err := models.DeleteMulti{{.CtrlName}}(ids, bulk.Partial)
+	if err != nil {
+		helpers.ServeProblem(c.Ctx, helpers.ErrorProblem(err))
+		return
+	}
+	index := make([]int, len(ids))
+	for i := range index {
+		index[i] = i
+	}
+	bulk.Done(index, errs, http.StatusOK)
+{{- end}}
+
+	status, r := bulk.Response(http.StatusOK)
+	c.Ctx.Output.SetStatus(status)
+	c.Data["json"] = r
+	c.ServeJson()
+}
+{{- if .OwnerField}}
+
+// owns reports whether the user of the request may write the record of an id, it serves the
+// problem otherwise
+func (c *{{.CtrlName}}Controller) owns(id int) bool {
+	if p := c.ownerProblem(id); p != nil {
+		helpers.ServeProblem(c.Ctx, p)
+		return false
+	}
+	return true
+}
+
+// ownerProblem returns the problem of writing the record of an id when the user of the request
+// may write its own rows only and doesn't own it, nil otherwise
+func (c *{{.CtrlName}}Controller) ownerProblem(id int) *helpers.Problem {
+	userId, ok := helpers.OwnerOnly(c.Ctx)
+	if !ok {
+		return nil
+	}
+	v, err := models.Get{{.CtrlName}}ById(id)
+	if err != nil {
+		return helpers.ErrorProblem(err)
+	}
+	if v.RowOwner() != userId {
+		return helpers.NewProblem(http.StatusForbidden, "the record belongs to another user")
+	}
+	return nil
+}
+{{- end}}
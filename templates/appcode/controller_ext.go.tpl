@@ -0,0 +1,4 @@
+package controllers
+
+// Code added to {{.CtrlName}}Controller goes here. With -split, bee generate appcode regenerates
+// {{.FileName}}_gen.go and never touches this file.
@@ -0,0 +1,278 @@
+package models
+
+import (
+	"reflect"
+	{{.TimePkg}}
+
+	"github.com/aamsur/beego/orm"
+	"{{.PkgPath}}/helpers"
+)
+
+{{.ModelStruct}}
+
+func (t *{{.ModelName}}) TableName() string {
+	return "{{.TableName}}"
+}
+{{- if .OwnerField}}
+
+// RowOwner returns the id of the user owning the record
+func (t *{{.ModelName}}) RowOwner() int {
+	if t.{{.OwnerField}} == nil {
+		return 0
+	}
+{{- if eq .OwnerKeyType "int"}}
+	return t.{{.OwnerField}}.{{.OwnerKey}}
+{{- else}}
+	return int(t.{{.OwnerField}}.{{.OwnerKey}})
+{{- end}}
+}
+
+// SetRowOwner makes the user of an id the owner of the record
+func (t *{{.ModelName}}) SetRowOwner(userId int) {
+{{- if eq .OwnerKeyType "int"}}
+	t.{{.OwnerField}} = &{{.OwnerModel}}{ {{- .OwnerKey}}: userId}
+{{- else}}
+	t.{{.OwnerField}} = &{{.OwnerModel}}{ {{- .OwnerKey}}: {{.OwnerKeyType}}(userId)}
+{{- end}}
+}
+{{- end}}
+{{- if or .CreatedBy .UpdatedBy}}
+
+// AuditCreate sets the audit columns of a record created by the user of a request
+func (t *{{.ModelName}}) AuditCreate(p *helpers.Principal) {
+	if p == nil {
+		return
+	}
+{{.CreatedBy}}{{.UpdatedBy}}}
+
+// AuditUpdate sets the audit columns of a record updated by the user of a request, and returns
+// the keys of the update: the audit columns of the body are left out, updated_by is added
+func (t *{{.ModelName}}) AuditUpdate(p *helpers.Principal, keys []string) []string {
+	var audited []string
+	for _, k := range keys {
+		if k != "created_by" && k != "updated_by" {
+			audited = append(audited, k)
+		}
+	}
+{{- if .UpdatedBy}}
+	if p != nil {
+{{.UpdatedBy}}		audited = append(audited, "{{.UpdatedByColumn}}")
+	}
+{{- end}}
+	return audited
+}
+{{- end}}
+
+func init() {
+	orm.RegisterModel(new({{.ModelName}}))
+}
+
+// {{.ModelName}}Relations maps the many-to-many and reverse relations of {{.ModelName}} to their
+// fields, they are loaded when named in join
+var {{.ModelName}}Relations = map[string]string{ {{.Relations}} }
+
+// {{.ModelName}}Query is the whitelist of the fields GetAll{{.ModelName}} filters, sorts and joins by
+var {{.ModelName}}Query = helpers.QueryFields{
+	Filter:   map[string]string{ {{.QueryFilter}} },
+	Sort:     []string{ {{.QuerySort}} },
+	Nullable: []string{ {{.QueryNullable}} },
+	Join:     []string{ {{.QueryJoin}} },
+	Key:      "id",
+}
+
+// Add{{.ModelName}} insert a new {{.ModelName}} into database and returns
+// last inserted Id on success.
+func Add{{.ModelName}}(m *{{.ModelName}}) (id int64, err error) {
+	o := orm.NewOrm()
+	id, err = o.Insert(m)
+	return
+}
+
+// AddMulti{{.ModelName}} inserts records with InsertMulti in a transaction, none is inserted when
+// one fails. When partial, they are then inserted one by one and errs holds the error of each.
+func AddMulti{{.ModelName}}(ml []{{.ModelName}}, partial bool) (errs []error, err error) {
+	o := orm.NewOrm()
+	if len(ml) == 0 {
+		return nil, nil
+	}
+	if err = o.Begin(); err != nil {
+		return nil, err
+	}
+	if _, err = o.InsertMulti(100, ml); err == nil {
+		return make([]error, len(ml)), o.Commit()
+	}
+	o.Rollback()
+	if !partial {
+		return nil, err
+	}
+	return helpers.RunBulk(o, len(ml), true, func(i int) error {
+		_, err := o.Insert(&ml[i])
+		return err
+	})
+}
+
+// Get{{.ModelName}}ById retrieves {{.ModelName}} by Id. Returns error if
+// Id doesn't exist{{if .SoftDelete}} or the record is soft-deleted{{end}}
+func Get{{.ModelName}}ById(id int, join ...string) (v *{{.ModelName}}, err error) {
+	return get{{.ModelName}}(orm.NewOrm().QueryTable(new({{.ModelName}})).Filter("id", id){{.LiveFilter}}, join)
+}
+{{- if .SoftDelete}}
+
+// Get{{.ModelName}}ByIdWithDeleted retrieves {{.ModelName}} by Id, soft-deleted or not
+func Get{{.ModelName}}ByIdWithDeleted(id int, join ...string) (v *{{.ModelName}}, err error) {
+	return get{{.ModelName}}(orm.NewOrm().QueryTable(new({{.ModelName}})).Filter("id", id), join)
+}
+{{- end}}
+
+// get{{.ModelName}} retrieves the {{.ModelName}} of a query with the relations of join
+func get{{.ModelName}}(qs orm.QuerySeter, join []string) (v *{{.ModelName}}, err error) {
+	var m {{.ModelName}}
+	o := orm.NewOrm()
+	_, loads := helpers.SplitJoin(join, {{.ModelName}}Relations)
+
+	if err = qs.RelatedSel().One(&m); err == nil {
+		for _, name := range loads {
+			if _, err = o.LoadRelated(&m, name); err != nil {
+				return nil, err
+			}
+		}
+		return &m, nil
+	}
+
+	return nil, err
+}
+
+// GetAll{{.ModelName}} retrieves all {{.ModelName}} matches certain condition. Returns empty list if
+// no records exist
+func GetAll{{.ModelName}}(q *helpers.Query) (ml []interface{}, err error, page *helpers.Page) {
+
+	o := orm.NewOrm()
+	related, loads := helpers.SplitJoin(q.Join, {{.ModelName}}Relations)
+{{- if .SoftDelete}}
+	if !q.WithDeleted {
+		q.Cond = q.Cond{{.LiveCond}}
+	}
+{{- end}}
+	qs := o.QueryTable(new({{.ModelName}})).SetCond(q.Cond).RelatedSel(helpers.QueryJoin(related)).GroupBy(q.GroupBy...)
+
+	// count the current query only when asked, it is expensive on big tables
+	var totals *int64
+	if q.Count {
+		cnt, err := qs.Count()
+		if err != nil {
+			return nil, err, nil
+		}
+		totals = &cnt
+	}
+
+	var l []{{.ModelName}}
+	if _, err := q.Paginate(qs.SetCond(q.Where())).All(&l, q.Fields...); err == nil {
+		page = q.Page(&l)
+		page.Totals = totals
+		for i := range l {
+			for _, name := range loads {
+				if _, err := o.LoadRelated(&l[i], name); err != nil {
+					return nil, err, nil
+				}
+			}
+		}
+		if len(q.Fields) == 0 {
+			for _, v := range l {
+				ml = append(ml, v)
+			}
+		} else {
+			// trim unused fields
+			for _, v := range l {
+				m := make(map[string]interface{})
+				val := reflect.ValueOf(v)
+				for _, fname := range q.Fields {
+					m[fname] = val.FieldByName(helpers.CamelString(fname)).Interface()
+				}
+				ml = append(ml, m)
+			}
+		}
+
+		return ml, nil, page
+	}
+	return nil, err, nil
+}
+
+// Export{{.ModelName}} writes the {{.ModelName}} matching a query to an export, reading
+// helpers.ExportChunk records at a time. The query isn't paged.
+func Export{{.ModelName}}(q *helpers.Query, export *helpers.Export) error {
+	o := orm.NewOrm()
+{{- if .SoftDelete}}
+	if !q.WithDeleted {
+		q.Cond = q.Cond{{.LiveCond}}
+	}
+{{- end}}
+	qs := o.QueryTable(new({{.ModelName}})).SetCond(q.Cond)
+	if err := export.Header(new({{.ModelName}}), q.Fields); err != nil {
+		return err
+	}
+	err := q.Chunks(qs, func(qs orm.QuerySeter) (int64, error) {
+		var l []{{.ModelName}}
+		n, err := qs.All(&l, q.Fields...)
+		for i := 0; i < len(l) && err == nil; i++ {
+			err = export.Record(&l[i])
+		}
+		return n, err
+	})
+	if err != nil {
+		return err
+	}
+	return export.Close()
+}
+
+// Update{{.ModelName}} updates {{.ModelName}} by Id and returns error if
+// the record to be updated doesn't exist{{if .SoftDelete}} or is soft-deleted{{end}}
+func Update{{.ModelName}}ById(m *{{.ModelName}}, keys []string) (err error) {
+	return update{{.ModelName}}ById(orm.NewOrm(), m, keys)
+}
+
+// UpdateMulti{{.ModelName}}ById updates records by Id, keys[i] being the keys of ml[i], in a
+// transaction: none is updated when one fails, unless partial. errs holds the error of each.
+func UpdateMulti{{.ModelName}}ById(ml []{{.ModelName}}, keys [][]string, partial bool) (errs []error, err error) {
+	o := orm.NewOrm()
+	return helpers.RunBulk(o, len(ml), partial, func(i int) error {
+		return update{{.ModelName}}ById(o, &ml[i], keys[i])
+	})
+}
+
+func update{{.ModelName}}ById(o orm.Ormer, m *{{.ModelName}}, keys []string) (err error) {
+	// ascertain id exists in the database
+	if !o.QueryTable(new({{.ModelName}})).Filter("id", m.Id){{.LiveFilter}}.Exist() {
+		return orm.ErrNoRows
+	}
+	// update only the keys provided
+	_, err = o.Update(m, keys...)
+	return
+}
+
+// Delete{{.ModelName}} deletes {{.ModelName}} by Id and returns error if
+// the record to be deleted doesn't exist
+{{- if .SoftDelete}}. The record is kept, marked by {{.DeletedColumn}}.{{end}}
+func Delete{{.ModelName}}(id int) (err error) {
+	return delete{{.ModelName}}(orm.NewOrm(), id)
+}
+
+// DeleteMulti{{.ModelName}} deletes records by Id in a transaction: none is deleted when one
+// fails, unless partial. errs holds the error of each.
+func DeleteMulti{{.ModelName}}(ids []int, partial bool) (errs []error, err error) {
+	o := orm.NewOrm()
+	return helpers.RunBulk(o, len(ids), partial, func(i int) error {
+		return delete{{.ModelName}}(o, ids[i])
+	})
+}
+
+func delete{{.ModelName}}(o orm.Ormer, id int) (err error) {
+{{- if .SoftDelete}}
+	num, err := o.QueryTable(new({{.ModelName}})).Filter("id", id){{.LiveFilter}}.Update(orm.Params{"{{.DeletedColumn}}": {{.DeletedValue}}})
+{{- else}}
+	num, err := o.QueryTable(new({{.ModelName}})).Filter("id", id).Delete()
+{{- end}}
+	if err == nil && num == 0 {
+		err = orm.ErrNoRows
+	}
+	return
+}
@@ -0,0 +1,4 @@
+package models
+
+// Code added to {{.ModelName}} goes here. With -split, bee generate appcode regenerates
+// {{.FileName}}_gen.go and never touches this file.
@@ -0,0 +1,6 @@
+
+		beego.NSNamespace("/{{.NameSpace}}",
+			beego.NSInclude(
+				&controllers.{{.CtrlName}}Controller{},
+			),
+		),
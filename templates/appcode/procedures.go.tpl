@@ -0,0 +1,15 @@
+package models
+
+import (
+	{{.TimePkg}}
+
+	"github.com/aamsur/beego/orm"
+)
+{{range .Procedures}}
+// {{.FuncName}} calls the {{.Name}} stored procedure and returns the rows of its result
+func {{.FuncName}}({{.Params}}) (rows []orm.Params, err error) {
+	o := orm.NewOrm()
+	_, err = o.Raw({{printf "%q" .Query}}{{.Args}}).Values(&rows)
+	return rows, err
+}
+{{end}}
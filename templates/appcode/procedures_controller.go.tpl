@@ -0,0 +1,44 @@
+package controllers
+
+import (
+	{{.Imports}}
+
+	"{{.PkgPath}}/helpers"
+	"{{.PkgPath}}/models"
+	"github.com/aamsur/beego"
+	"github.com/aamsur/beego/orm"
+)
+
+// oprations for ProceduresController, the report endpoints of the stored procedures
+type ProceduresController struct {
+	beego.Controller
+}
+
+func (c *ProceduresController) URLMapping() {
+{{- range .Procedures}}
+	c.Mapping("{{.FuncName}}", c.{{.FuncName}})
+{{- end}}
+}
+{{range .Procedures}}
+// @Title {{.FuncName}}
+// @Description call the {{.Name}} stored procedure
+{{.DocParams}}// @Success 200 {string} rows of the {{.Name}} result
+// @Failure 500
+{{$.AuthDoc}}// @router /{{.Route}} [get]
+func (c *ProceduresController) {{.FuncName}}() {
+{{- if .Parse}}
+	{{.Parse}}
+{{- end}}
+	l, err := models.{{.FuncName}}({{.ArgNames}})
+	if err != nil {
+		helpers.ServeProblem(c.Ctx, helpers.ErrorProblem(err))
+		return
+	}
+	if l == nil {
+		// no row found
+		l = []orm.Params{}
+	}
+	c.Data["json"] = helpers.NewResponse().Ok(l).WithTotals(int64(len(l)))
+	c.ServeJson()
+}
+{{end}}
@@ -0,0 +1,61 @@
+package controllers
+
+import (
+	"net/http"
+
+	"{{.PkgPath}}/helpers"
+	"{{.PkgPath}}/models"
+)
+
+func init() {
+	reportMethods["{{.FuncName}}"] = (*ReportsController).{{.FuncName}}
+}
+
+// @Title {{.FuncName}}
+// @Description {{.Description}}
+{{.DocParams}}// @Param	limit	query	string	false	"Limit the size of result set, at most maxpagesize. Must be an integer"
+// @Param	offset	query	string	false	"Start position of result set. Must be an integer"
+// @Param	count	query	string	false	"Count the rows in totals. Must be a boolean"
+// @Param	format	query	string	false	"json, csv or xlsx, also chosen by the Accept header. Files hold every row"
+// @Success 200 {object} models.{{.ModelName}}
+// @Failure 400 missing or invalid parameters
+// @Failure 500
+{{.AuthDoc}}// @router /{{.Route}} [get]
+func (c *ReportsController) {{.FuncName}}() {
+	params, p := helpers.ReportParams(c.Input(), models.{{.ModelName}}Params)
+	if p != nil {
+		helpers.ServeProblem(c.Ctx, p)
+		return
+	}
+	q, err := helpers.ParseQuery(c.Input(), helpers.QueryFields{})
+	if err != nil {
+		helpers.ServeProblem(c.Ctx, helpers.NewProblem(http.StatusBadRequest, err.Error()))
+		return
+	}
+
+	// files are streamed from the database rather than loaded
+	format, err := helpers.ExportFormat(c.Ctx)
+	if err != nil {
+		helpers.ServeProblem(c.Ctx, helpers.NewProblem(http.StatusBadRequest, err.Error()))
+		return
+	}
+	if format != "" {
+		export := helpers.NewExport(c.Ctx, format, "{{.FileName}}")
+		if err := models.Export{{.ModelName}}(params, export); err != nil {
+			export.Fail(err)
+		}
+		return
+	}
+
+	l, err, page := models.GetAll{{.ModelName}}(q, params)
+	if err != nil {
+		helpers.ServeProblem(c.Ctx, helpers.ErrorProblem(err))
+		return
+	}
+	if l == nil {
+		// no row found
+		l = []interface{}{}
+	}
+	c.Data["json"] = helpers.NewResponse().Ok(l).WithPage(c.Ctx, page)
+	c.ServeJson()
+}
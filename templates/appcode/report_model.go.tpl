@@ -0,0 +1,79 @@
+package models
+
+import (
+	{{.TimePkg}}
+
+	"github.com/aamsur/beego/orm"
+	"{{.PkgPath}}/helpers"
+)
+
+// {{.ModelName}} is a row of the {{.Name}} report
+{{.ModelStruct}}
+
+// {{.ModelName}}Params are the query parameters of the {{.Name}} report
+var {{.ModelName}}Params = []helpers.ReportParam{
+{{- range .Params}}
+	{Name: {{printf "%q" .Name}}, Type: {{printf "%q" .Type}}, Required: {{.Required}}},
+{{- end}}
+}
+
+// {{.VarName}}Sql is the query of the {{.Name}} report, from {{.SqlFile}}
+const {{.VarName}}Sql = {{.Query}}
+
+// {{.VarName}}Args are the parameters of the placeholders of {{.VarName}}Sql, in order
+var {{.VarName}}Args = []string{ {{- .Args -}} }
+
+// GetAll{{.ModelName}} runs the {{.Name}} report and returns the rows of the page of q, counted
+// when asked
+func GetAll{{.ModelName}}(q *helpers.Query, params map[string]interface{}) (ml []interface{}, err error, page *helpers.Page) {
+	o := orm.NewOrm()
+	args := helpers.ReportArgs(params, {{.VarName}}Args)
+
+	// count the rows only when asked, reports are often expensive
+	var totals *int64
+	if q.Count {
+		var cnt int64
+		if err = o.Raw("SELECT COUNT(*) FROM (\n"+{{.VarName}}Sql+"\n) r", args...).QueryRow(&cnt); err != nil {
+			return nil, err, nil
+		}
+		totals = &cnt
+	}
+
+	// one more row tells whether there is a next page
+	var m []{{.ModelName}}
+	if _, err = o.Raw({{.VarName}}Sql+"\nLIMIT ? OFFSET ?", append(args, q.Limit+1, q.Offset)...).QueryRows(&m); err != nil {
+		return nil, err, nil
+	}
+	page = q.Page(&m)
+	page.Totals = totals
+	for _, v := range m {
+		ml = append(ml, v)
+	}
+	return ml, nil, page
+}
+
+// Export{{.ModelName}} writes the rows of the {{.Name}} report to an export, reading
+// helpers.ExportChunk rows at a time
+func Export{{.ModelName}}(params map[string]interface{}, export *helpers.Export) error {
+	o := orm.NewOrm()
+	args := helpers.ReportArgs(params, {{.VarName}}Args)
+
+	if err := export.Header(new({{.ModelName}}), nil); err != nil {
+		return err
+	}
+	for offset := int64(0); ; offset += helpers.ExportChunk {
+		var m []{{.ModelName}}
+		n, err := o.Raw({{.VarName}}Sql+"\nLIMIT ? OFFSET ?", append(args, helpers.ExportChunk, offset)...).QueryRows(&m)
+		if err != nil {
+			return err
+		}
+		for i := range m {
+			if err := export.Record(&m[i]); err != nil {
+				return err
+			}
+		}
+		if n < helpers.ExportChunk {
+			return export.Close()
+		}
+	}
+}
@@ -0,0 +1,19 @@
+// @APIVersion 1.0.0
+// @Title Application API
+// @Description application api
+// @License Apache 2.0
+// @LicenseUrl http://www.apache.org/licenses/LICENSE-2.0.html
+{{.AuthDefinition}}package routers
+
+import (
+	"{{.PkgPath}}/controllers"
+
+	"github.com/aamsur/beego"
+)
+
+func init() {
+	ns := beego.NewNamespace("/v1",
+		{{.NameSpaces}}
+	)
+	beego.AddNamespace(ns)
+}
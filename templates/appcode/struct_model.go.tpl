@@ -0,0 +1,34 @@
+package models
+import (
+	{{.TimePkg}}
+
+	"github.com/aamsur/beego/orm"
+)
+
+{{.ModelStruct}}
+
+// GetAll{{.ModelName}} retrieves all {{.ModelName}}.
+// Returns empty list if no records exist
+func GetAll{{.ModelName}}() (ml []interface{}, err error, totals int64) {
+
+	qb, _ := orm.NewQueryBuilder("mysql")
+
+	qb.Select("*")
+	qb.From("{{.TableName}}")
+{{- if .SoftDelete}}
+	qb.Where("{{.LiveWhere}}")
+{{- end}}
+
+	o := orm.NewOrm()
+	sql := qb.String()
+
+	var m []{{.ModelName}}
+	if _, err := o.Raw(sql).QueryRows(&m); err == nil {
+		for _, v := range m {
+			ml = append(ml, v)
+		}
+	}
+
+
+	return ml, err, totals
+}
@@ -0,0 +1,62 @@
+package controllers
+
+import (
+	"net/http"
+
+	"{{.PkgPath}}/models"
+	"{{.PkgPath}}/helpers"
+	"github.com/aamsur/beego"
+)
+
+// oprations for {{.CtrlName}}, read from the {{.TableName}} view
+type {{.CtrlName}}Controller struct {
+	beego.Controller
+}
+
+func (c *{{.CtrlName}}Controller) URLMapping() {
+	c.Mapping("GetAll", c.GetAll)
+}
+
+// @Title Get All
+// @Description get {{.CtrlName}}
+// @Param	limit	query	string	false	"Limit the size of result set, at most maxpagesize. Must be an integer"
+// @Param	offset	query	string	false	"Start position of result set. Must be an integer"
+// @Param	format	query	string	false	"json, csv or xlsx, also chosen by the Accept header. Files hold every row"
+// @Success 200 {object} models.{{.CtrlName}}
+// @Failure 400 invalid limit or offset
+// @Failure 500
+{{.AuthDoc}}// @router / [get]
+func (c *{{.CtrlName}}Controller) GetAll() {
+	q, err := helpers.ParseQuery(c.Input(), helpers.QueryFields{})
+	if err != nil {
+		helpers.ServeProblem(c.Ctx, helpers.NewProblem(http.StatusBadRequest, err.Error()))
+		return
+	}
+
+	// files are streamed from the database rather than loaded
+	format, err := helpers.ExportFormat(c.Ctx)
+	if err != nil {
+		helpers.ServeProblem(c.Ctx, helpers.NewProblem(http.StatusBadRequest, err.Error()))
+		return
+	}
+	if format != "" {
+		export := helpers.NewExport(c.Ctx, format, "{{.TableName}}")
+		if err := models.Export{{.CtrlName}}(export); err != nil {
+			export.Fail(err)
+		}
+		return
+	}
+
+	// Get all with query string
+	l, err, totals := models.GetAll{{.CtrlName}}(q.Offset, q.Limit)
+	if err != nil {
+		helpers.ServeProblem(c.Ctx, helpers.ErrorProblem(err))
+		return
+	}
+	if l == nil {
+		// no row found
+		l = []interface{}{}
+	}
+	c.Data["json"] = helpers.NewResponse().Ok(l).WithTotals(totals)
+	c.ServeJson()
+}
@@ -0,0 +1,62 @@
+package models
+
+import (
+	{{.TimePkg}}
+
+	"github.com/aamsur/beego/orm"
+	"{{.PkgPath}}/helpers"
+)
+
+{{.ModelStruct}}
+
+// GetAll{{.ModelName}} retrieves the rows of the {{.TableName}} view, all of them when limit is 0.
+// Views are read only, they have no key to get, update or delete a row by.
+func GetAll{{.ModelName}}(offset int64, limit int64) (ml []interface{}, err error, totals int64) {
+	o := orm.NewOrm()
+	sql := {{printf "%q" .ViewQuery}}
+
+	// count the rows of the view
+	if err = o.Raw("SELECT COUNT(*) FROM (" + sql + ") v").QueryRow(&totals); err != nil {
+		return nil, err, totals
+	}
+
+	var m []{{.ModelName}}
+	if limit > 0 {
+		_, err = o.Raw(sql+" LIMIT ? OFFSET ?", limit, offset).QueryRows(&m)
+	} else {
+		_, err = o.Raw(sql).QueryRows(&m)
+	}
+	if err != nil {
+		return nil, err, totals
+	}
+	for _, v := range m {
+		ml = append(ml, v)
+	}
+	return ml, nil, totals
+}
+
+// Export{{.ModelName}} writes the rows of the {{.TableName}} view to an export, reading
+// helpers.ExportChunk rows at a time
+func Export{{.ModelName}}(export *helpers.Export) error {
+	o := orm.NewOrm()
+	sql := {{printf "%q" .ViewQuery}}
+
+	if err := export.Header(new({{.ModelName}}), nil); err != nil {
+		return err
+	}
+	for offset := int64(0); ; offset += helpers.ExportChunk {
+		var m []{{.ModelName}}
+		n, err := o.Raw(sql+" LIMIT ? OFFSET ?", helpers.ExportChunk, offset).QueryRows(&m)
+		if err != nil {
+			return err
+		}
+		for i := range m {
+			if err := export.Record(&m[i]); err != nil {
+				return err
+			}
+		}
+		if n < helpers.ExportChunk {
+			return export.Close()
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package {{.PackageName}}
+
+import (
+	"github.com/aamsur/beego"
+)
+
+// oprations for {{.ControllerName}}
+type {{.ControllerName}}Controller struct {
+	beego.Controller
+}
+
+func (c *{{.ControllerName}}Controller) URLMapping() {
+	c.Mapping("Post", c.Post)
+	c.Mapping("GetOne", c.GetOne)
+	c.Mapping("GetAll", c.GetAll)
+	c.Mapping("Put", c.Put)
+	c.Mapping("Delete", c.Delete)
+}
+
+// @Title Post
+// @Description create {{.ControllerName}}
+// @Param	body		body 	models.{{.ControllerName}}	true		"body for {{.ControllerName}} content"
+// @Success 200 {int} models.{{.ControllerName}}.Id
+// @Failure 403 body is empty
+// @router / [post]
+func (c *{{.ControllerName}}Controller) Post() {
+
+}
+
+// @Title Get
+// @Description get {{.ControllerName}} by id
+// @Param	id		path 	string	true		"The key for staticblock"
+// @Success 200 {object} models.{{.ControllerName}}
+// @Failure 403 :id is empty
+// @router /:id [get]
+func (c *{{.ControllerName}}Controller) GetOne() {
+
+}
+
+// @Title Get All
+// @Description get {{.ControllerName}}
+// @Param	query	query	string	false	"Filter. e.g. col1:v1,col2:v2 ..."
+// @Param	fields	query	string	false	"Fields returned. e.g. col1,col2 ..."
+// @Param	sortby	query	string	false	"Sorted-by fields. e.g. col1,col2 ..."
+// @Param	order	query	string	false	"Order corresponding to each sortby field, if single value, apply to all sortby fields. e.g. desc,asc ..."
+// @Param	limit	query	string	false	"Limit the size of result set. Must be an integer"
+// @Param	offset	query	string	false	"Start position of result set. Must be an integer"
+// @Success 200 {object} models.{{.ControllerName}}
+// @Failure 403
+// @router / [get]
+func (c *{{.ControllerName}}Controller) GetAll() {
+
+}
+
+// @Title Update
+// @Description update the {{.ControllerName}}
+// @Param	id		path 	string	true		"The id you want to update"
+// @Param	body		body 	models.{{.ControllerName}}	true		"body for {{.ControllerName}} content"
+// @Success 200 {object} models.{{.ControllerName}}
+// @Failure 403 :id is not int
+// @router /:id [put]
+func (c *{{.ControllerName}}Controller) Put() {
+	
+}
+
+// @Title Delete
+// @Description delete the {{.ControllerName}}
+// @Param	id		path 	string	true		"The id you want to delete"
+// @Success 200 {string} delete success!
+// @Failure 403 id is empty
+// @router /:id [delete]
+func (c *{{.ControllerName}}Controller) Delete() {
+	
+}
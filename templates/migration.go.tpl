@@ -0,0 +1,33 @@
+package main
+
+import (
+	"github.com/aamsur/beego/migration"
+)
+
+// DO NOT MODIFY
+type {{.StructName}} struct {
+	txMigration
+}
+
+// DO NOT MODIFY
+func init() {
+	m := &{{.StructName}}{}
+	m.Created = "{{.CurrTime}}"
+	migration.Register("{{.StructName}}", m)
+}
+
+// Run the migrations
+func (m *{{.StructName}}) Up() {
+{{- if .Prelude}}
+	{{.Prelude}}
+{{- end}}
+	{{.UpSQL}}
+}
+
+// Reverse the migrations
+func (m *{{.StructName}}) Down() {
+{{- if .Prelude}}
+	{{.Prelude}}
+{{- end}}
+	{{.DownSQL}}
+}
@@ -0,0 +1,16 @@
+package main
+
+import (
+	"github.com/aamsur/beego/orm"
+)
+
+// DO NOT MODIFY
+func init() {
+	// environments this seed is applied for, empty means all
+	registerSeed("{{.SeedName}}", []string{}, {{.FuncName}})
+}
+
+// Load the seed data, it runs inside a transaction
+func {{.FuncName}}(o orm.Ormer) error {
+	return nil
+}
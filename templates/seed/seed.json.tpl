@@ -0,0 +1,6 @@
+{
+	"envs": [],
+	"table": "{{.TableName}}",
+	"rows": [
+	]
+}
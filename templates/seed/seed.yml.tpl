@@ -0,0 +1,6 @@
+# environments this seed is applied for, empty means all
+envs: []
+table: {{.TableName}}
+rows:
+#  - id: 1
+#    name: example
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+)
+
+func TestRenderTemplate(t *testing.T) {
+	wd, _ := os.Getwd()
+	dir := t.TempDir()
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	data := map[string]interface{}{"PackageName": "controllers", "ControllerName": "Post"}
+	if got := renderTemplate("controller.go.tpl", data); got[:len("package controllers")] != "package controllers" {
+		t.Errorf("unexpected default template output:\n%s", got)
+	}
+
+	ejectTemplates(dir)
+	fpath := path.Join(dir, TEMPLATES_DIR, "controller.go.tpl")
+	if !isExist(fpath) {
+		t.Fatalf("%s was not ejected", fpath)
+	}
+	if err := ioutil.WriteFile(fpath, []byte("package {{.PackageName}} // {{.ControllerName}}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got := renderTemplate("controller.go.tpl", data); got != "package controllers // Post\n" {
+		t.Errorf("the project template is not used: %q", got)
+	}
+}
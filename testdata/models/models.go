@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+type User struct {
+	Id      int
+	Name    string `orm:"size(64)"`
+	Email   string `orm:"size(128);unique"`
+	Created time.Time
+}
+
+type Post struct {
+	Id     int
+	Title  string `orm:"size(255)"`
+	Body   string `orm:"type(text);null"`
+	Author *User  `orm:"rel(fk)"`
+}
+
+func (p *Post) TableName() string {
+	return "posts"
+}
@@ -0,0 +1,29 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+
+	"example.com/base"
+)
+
+type Audit struct {
+	Created time.Time  `orm:"auto_now_add"`
+	Deleted *time.Time `orm:"null"`
+}
+
+type Event struct {
+	Audit
+	Id    int
+	Name  *string         `orm:"size(64);null;index"`
+	Kind  string          `orm:"size(8)"`
+	Score sql.NullInt64   `orm:"null"`
+	Rate  sql.NullFloat64 `orm:"null"`
+	Note  sql.NullString  `orm:"size(128);null"`
+}
+
+type Log struct {
+	base.Model
+	Id  int
+	Msg string `orm:"type(text)"`
+}
@@ -0,0 +1,42 @@
+CREATE TABLE user (
+	id INTEGER PRIMARY KEY,
+	name VARCHAR(64) NOT NULL,
+	email VARCHAR(128) NOT NULL UNIQUE,
+	created DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+CREATE TABLE posts (
+	id INTEGER PRIMARY KEY,
+	title VARCHAR(255) NOT NULL,
+	body TEXT,
+	score DECIMAL(5,2),
+	author_id INTEGER NOT NULL REFERENCES user(id)
+);
+CREATE INDEX idx_posts_title ON posts (title);
+CREATE TABLE post_tags (
+	post_id INTEGER NOT NULL REFERENCES posts,
+	tag VARCHAR(32) NOT NULL,
+	PRIMARY KEY (post_id, tag)
+);
+CREATE TABLE tag_votes (
+	id INTEGER PRIMARY KEY,
+	post_id INTEGER NOT NULL,
+	tag VARCHAR(32) NOT NULL,
+	FOREIGN KEY (post_id, tag) REFERENCES post_tags (post_id, tag)
+);
+CREATE TABLE tags (
+	id INTEGER PRIMARY KEY,
+	name VARCHAR(32) NOT NULL
+);
+CREATE TABLE post_tag_links (
+	post_id INTEGER NOT NULL REFERENCES posts,
+	tag_id INTEGER NOT NULL REFERENCES tags,
+	PRIMARY KEY (post_id, tag_id)
+);
+CREATE TABLE profiles (
+	id INTEGER PRIMARY KEY,
+	user_id INTEGER NOT NULL UNIQUE REFERENCES user,
+	bio TEXT
+);
+CREATE VIEW post_scores AS
+	SELECT posts.id AS post_id, user.name AS author, posts.score AS score
+	FROM posts JOIN user ON user.id = posts.author_id;